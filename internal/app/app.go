@@ -4,13 +4,26 @@ import (
 	"ShopAvito/internal/config"
 	"ShopAvito/internal/handlers"
 	"ShopAvito/internal/repository"
+	"ShopAvito/internal/scheduler"
 	"ShopAvito/internal/services"
+	"ShopAvito/internal/storage"
 	"ShopAvito/pkg/logger"
 	"ShopAvito/pkg/postgres"
 	"ShopAvito/pkg/server"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+)
+
+// Как часто планировщик проверяет истёкшие аукционы, наступившие изменения цен и поступление предзаказанных товаров, а также чистит истёкшие refresh-токены, записи об отозванных токенах, счётчики неудачных входов и незавершённые входы через OIDC
+const (
+	auctionCloseInterval      = 10 * time.Second
+	priceChangeInterval       = 30 * time.Second
+	preOrderCaptureInterval   = 30 * time.Second
+	tokenPurgeInterval        = time.Hour
+	loginAttemptPurgeInterval = time.Hour
 )
 
 func Run() {
@@ -33,16 +46,107 @@ func Run() {
 	invenRepo := repository.NewInventoryRepository(db, log)
 	userRepo := repository.NewUserRepository(db, log)
 	invenService := services.NewInventoryService(invenRepo)
-	authService := services.NewAuthService(userRepo, cfg.JwtSecret, log)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db, log)
+	revocationStore := services.NewRevocationStore(repository.NewTokenRevocationRepository(db, log))
+	keySet, err := loadKeySet(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+	passwordPolicy := services.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		Cost:          cfg.BcryptCost,
+	}
+	// Счётчики неудачных попыток входа: в памяти для одного экземпляра, в Postgres - общие для реплик
+	var loginAttemptRepo repository.LoginAttemptRepositoryInterface = repository.NewLoginAttemptRepository(db, log)
+	if cfg.LoginAttemptStore == "memory" {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepository()
+	}
+	loginThrottle := services.NewLoginThrottle(loginAttemptRepo, services.LoginThrottlePolicy{
+		MaxAttempts:   cfg.LoginMaxAttempts,
+		MaxIPAttempts: cfg.LoginIPMaxAttempts,
+		BaseDelay:     cfg.LoginBackoffBase,
+		MaxLockout:    cfg.LoginLockoutMax,
+	})
+	twoFactorService := services.NewTwoFactorService(repository.NewTwoFactorRepository(db, log), services.TwoFactorPolicy{
+		Issuer:            cfg.TOTPIssuer,
+		RequireForAdmins:  cfg.TwoFactorRequiredForAdmins,
+		TransferThreshold: cfg.TwoFactorTransferThreshold,
+	}, log)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, revocationStore, keySet, passwordPolicy, loginThrottle, twoFactorService, log)
+	// Вход через провайдера OpenID Connect включается заданием OIDC_ISSUER
+	var oidcService *services.OIDCService
+	if cfg.OIDCIssuer != "" {
+		oidcService = services.NewOIDCService(services.OIDCConfig{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		}, repository.NewOIDCRepository(db, log), authService, nil, log)
+	}
+	apiTokenService := services.NewAPITokenService(repository.NewAPITokenRepository(db, log), revocationStore, log)
+	sessionService := services.NewSessionService(repository.NewSessionRepository(db, log), log)
 	userService := services.NewUserService(userRepo, log)
+	passwordService := services.NewPasswordService(userRepo, repository.NewPasswordResetRepository(db, log), revocationStore, passwordPolicy, log)
+	catalogRepo := repository.NewCatalogRepository(db, log)
+	discountRepo := repository.NewDiscountRepository(db, log)
+	wishlistRepo := repository.NewWishlistRepository(db, log)
+	notificationRepo := repository.NewNotificationRepository(db, log)
+	wishlistService := services.NewWishlistService(wishlistRepo, notificationRepo, catalogRepo, discountRepo, userService, log)
 	transactionRepo := repository.NewTransactionRepository(db, log)
-	transactionService := services.NewTransactionService(transactionRepo, userRepo, log)
+	transactionService := services.NewTransactionService(transactionRepo, userRepo, wishlistService, log)
+	catalogService := services.NewCatalogService(catalogRepo, discountRepo, wishlistService, log)
+	discountService := services.NewDiscountService(discountRepo, wishlistService, log)
 	purchaseRepo := repository.NewPurchaseRepository(db, log)
-	purchaseService := services.NewPurchaseService(purchaseRepo, userRepo, invenRepo, log)
+	purchaseService := services.NewPurchaseService(purchaseRepo, userRepo, invenRepo, discountRepo, log)
+	orderRepo := repository.NewOrderRepository(db, log)
+	orderService := services.NewOrderService(orderRepo, purchaseRepo, log)
+	auctionRepo := repository.NewAuctionRepository(db, log)
+	auctionService := services.NewAuctionService(auctionRepo, log)
+	reviewRepo := repository.NewReviewRepository(db, log)
+	reviewService := services.NewReviewService(reviewRepo, invenRepo, catalogRepo, log)
+	preOrderRepo := repository.NewPreOrderRepository(db, log)
+	preOrderService := services.NewPreOrderService(preOrderRepo, catalogRepo, discountRepo, log)
+
+	// Хранилище изображений товаров и аватаров
+	var blobStore storage.BlobStore
+	switch cfg.BlobStore {
+	case "s3":
+		blobStore = storage.NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, nil)
+	default:
+		localStore, err := storage.NewLocalStore(cfg.ImagesDir)
+		if err != nil {
+			log.Fatal("Failed to initialize image storage: ", err)
+		}
+		blobStore = localStore
+	}
+	imageService := services.NewImageService(catalogRepo, blobStore, log)
+	profileService := services.NewProfileService(repository.NewProfileRepository(db, log), blobStore, log)
+	accountService := services.NewAccountService(repository.NewAccountRepository(db, log), userRepo, revocationStore, blobStore, log)
+
+	// Фоновые задачи
+	sched := scheduler.NewScheduler(log)
+	sched.Add("close-auctions", auctionCloseInterval, auctionService.CloseDueAuctions)
+	sched.Add("apply-price-changes", priceChangeInterval, catalogService.ApplyDuePriceChanges)
+	sched.Add("capture-pre-orders", preOrderCaptureInterval, preOrderService.CaptureDuePreOrders)
+	sched.Add("purge-expired-tokens", tokenPurgeInterval, authService.PurgeExpiredTokens)
+	sched.Add("purge-password-reset-tokens", tokenPurgeInterval, passwordService.PurgeExpiredResetTokens)
+	sched.Add("purge-login-attempts", loginAttemptPurgeInterval, authService.PurgeLoginAttempts)
+	if oidcService != nil {
+		sched.Add("purge-oidc-login-states", tokenPurgeInterval, oidcService.PurgeExpiredStates)
+	}
+	sched.Start()
 
 	serv := new(server.Server)
 
-	routes := handlers.RegisterRoutes(userService, transactionService, purchaseService, authService, invenService, log)
+	routes := handlers.RegisterRoutes(userService, transactionService, purchaseService, authService, invenService, auctionService, orderService, catalogService, discountService, wishlistService, reviewService, imageService, preOrderService, passwordService, twoFactorService, oidcService, apiTokenService, sessionService, accountService, profileService, cfg.AuthAutoRegister, log)
+	if err := routes.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -62,7 +166,35 @@ func Run() {
 		log.Errorf("Ошибка при завершении работы сервера")
 	}
 
+	sched.Stop()
+
 	db.Close()
 	log.Info("Сервер успешно выключен!")
 
 }
+
+// Ключи подписи JWT из конфигурации. Без JWT_KEYS используется HS256 с JWT_SECRET.
+func loadKeySet(cfg *config.Config) (*services.KeySet, error) {
+	if len(cfg.JwtKeys) == 0 {
+		return services.NewKeySet(services.NewHMACKey("", cfg.JwtSecret), cfg.JwtKeyGracePeriod)
+	}
+
+	var active *services.SigningKey
+	var others []services.SigningKey
+	for _, keyConfig := range cfg.JwtKeys {
+		key, err := services.LoadSigningKey(keyConfig.ID, keyConfig.Path)
+		if err != nil {
+			return nil, err
+		}
+		if keyConfig.ID == cfg.JwtActiveKeyID {
+			active = &key
+			continue
+		}
+		key.RetiredAt = keyConfig.RetiredAt
+		others = append(others, key)
+	}
+	if active == nil {
+		return nil, fmt.Errorf("active key %q is not listed in JWT_KEYS", cfg.JwtActiveKeyID)
+	}
+	return services.NewKeySet(*active, cfg.JwtKeyGracePeriod, others...)
+}
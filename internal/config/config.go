@@ -2,9 +2,14 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -15,6 +20,63 @@ type Config struct {
 	DBName     string
 	SSLMode    string
 	JwtSecret  string
+
+	// Асимметричные ключи подписи JWT. Если не заданы, токены подписываются JwtSecret (HS256).
+	JwtKeys           []JWTKey
+	JwtActiveKeyID    string
+	JwtKeyGracePeriod time.Duration
+
+	// Автоматическая регистрация неизвестных пользователей в POST /api/auth
+	AuthAutoRegister bool
+
+	// Требования к паролю при регистрации
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	// Стоимость bcrypt для новых хэшей; хэши с другой стоимостью пересчитываются при входе
+	BcryptCost int
+
+	// Двухфакторная аутентификация: имя сервиса в приложении-аутентификаторе, обязательность для
+	// администраторов и порог перевода, выше которого нужен код (0 - без порога)
+	TOTPIssuer                 string
+	TwoFactorRequiredForAdmins bool
+	TwoFactorTransferThreshold int
+
+	// Вход через провайдера OpenID Connect; без OIDCIssuer отключён
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+
+	// Адреса и подсети обратных прокси, которым доверяется X-Forwarded-For. Без них IP клиента -
+	// адрес TCP-соединения: иначе клиент подставлял бы любой адрес и обходил блокировку входа по IP.
+	TrustedProxies []string
+
+	// Защита входа от перебора: хранилище счётчиков memory или postgres, пороги и задержки
+	LoginAttemptStore  string
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginBackoffBase   time.Duration
+	LoginLockoutMax    time.Duration
+
+	// Хранилище изображений: local (каталог на диске) или s3
+	BlobStore   string
+	ImagesDir   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// JWTKey - ключ подписи из JWT_KEYS: kid, путь к PEM-файлу и момент вывода из использования
+type JWTKey struct {
+	ID        string
+	Path      string
+	RetiredAt *time.Time
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,12 +93,132 @@ func LoadConfig() (*Config, error) {
 		DBName:     os.Getenv("DB_NAME"),
 		SSLMode:    os.Getenv("DB_SSLMODE"),
 		JwtSecret:  os.Getenv("JWT_SECRET"),
+
+		BlobStore:   getEnv("BLOB_STORE", "local"),
+		ImagesDir:   getEnv("IMAGES_DIR", "./data/images"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+	}
+
+	keys, err := parseJWTKeys(os.Getenv("JWT_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.JwtKeys = keys
+	cfg.JwtActiveKeyID = os.Getenv("JWT_ACTIVE_KEY_ID")
+	if len(keys) > 0 && cfg.JwtActiveKeyID == "" {
+		cfg.JwtActiveKeyID = keys[0].ID
+	}
+	if cfg.AuthAutoRegister, err = strconv.ParseBool(getEnv("AUTH_AUTO_REGISTER", "true")); err != nil {
+		return nil, fmt.Errorf("invalid AUTH_AUTO_REGISTER: %w", err)
+	}
+	if cfg.JwtKeyGracePeriod, err = time.ParseDuration(getEnv("JWT_KEY_GRACE_PERIOD", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEY_GRACE_PERIOD: %w", err)
+	}
+	if cfg.PasswordMinLength, err = strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8")); err != nil || cfg.PasswordMinLength < 1 {
+		return nil, errors.New("PASSWORD_MIN_LENGTH must be a positive integer")
+	}
+	if cfg.BcryptCost, err = strconv.Atoi(getEnv("BCRYPT_COST", strconv.Itoa(bcrypt.DefaultCost))); err != nil ||
+		cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	for key, value := range map[string]*int{
+		"LOGIN_MAX_ATTEMPTS":            &cfg.LoginMaxAttempts,
+		"LOGIN_IP_MAX_ATTEMPTS":         &cfg.LoginIPMaxAttempts,
+		"TWO_FACTOR_TRANSFER_THRESHOLD": &cfg.TwoFactorTransferThreshold,
+	} {
+		if *value, err = strconv.Atoi(getEnv(key, "0")); err != nil || *value < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer", key)
+		}
+	}
+	for key, value := range map[string]*time.Duration{
+		"LOGIN_BACKOFF_BASE": &cfg.LoginBackoffBase,
+		"LOGIN_LOCKOUT_MAX":  &cfg.LoginLockoutMax,
+	} {
+		if *value, err = time.ParseDuration(getEnv(key, "0s")); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	for key, value := range map[string]*bool{
+		"PASSWORD_REQUIRE_UPPER":         &cfg.PasswordRequireUpper,
+		"PASSWORD_REQUIRE_LOWER":         &cfg.PasswordRequireLower,
+		"PASSWORD_REQUIRE_DIGIT":         &cfg.PasswordRequireDigit,
+		"PASSWORD_REQUIRE_SYMBOL":        &cfg.PasswordRequireSymbol,
+		"TWO_FACTOR_REQUIRED_FOR_ADMINS": &cfg.TwoFactorRequiredForAdmins,
+	} {
+		if *value, err = strconv.ParseBool(getEnv(key, "false")); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
 	}
 
-	if cfg.DBUser == "" || cfg.DBPassword == "" || cfg.DBHost == "" || cfg.DBPort == "" || cfg.DBName == "" || cfg.SSLMode == "" || cfg.JwtSecret == "" {
+	if cfg.DBUser == "" || cfg.DBPassword == "" || cfg.DBHost == "" || cfg.DBPort == "" || cfg.DBName == "" || cfg.SSLMode == "" || (cfg.JwtSecret == "" && len(cfg.JwtKeys) == 0) {
 		log.Println("Error in the configuration data and check the config!")
 		return nil, errors.New("Error in the configuration data and check the config")
 	}
 
+	cfg.TOTPIssuer = getEnv("TOTP_ISSUER", "Avito Shop")
+	cfg.OIDCIssuer = os.Getenv("OIDC_ISSUER")
+	cfg.OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	cfg.OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	cfg.OIDCRedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	cfg.OIDCScopes = strings.Fields(getEnv("OIDC_SCOPES", "openid profile email"))
+	if cfg.OIDCIssuer != "" && (cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "") {
+		return nil, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set")
+	}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+	cfg.LoginAttemptStore = getEnv("LOGIN_ATTEMPT_STORE", "postgres")
+	if cfg.LoginAttemptStore != "memory" && cfg.LoginAttemptStore != "postgres" {
+		return nil, errors.New("LOGIN_ATTEMPT_STORE must be memory or postgres")
+	}
+
+	switch cfg.BlobStore {
+	case "local":
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 blob store")
+		}
+	default:
+		return nil, errors.New("BLOB_STORE must be local or s3")
+	}
+
 	return cfg, nil
 }
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Разбор JWT_KEYS: "kid=path[@retired_at],...", retired_at в формате RFC 3339
+func parseJWTKeys(value string) ([]JWTKey, error) {
+	var keys []JWTKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid=path", entry)
+		}
+		key := JWTKey{ID: id, Path: path}
+		if path, retired, ok := strings.Cut(path, "@"); ok {
+			retiredAt, err := time.Parse(time.RFC3339, retired)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retirement time of JWT key %s: %w", id, err)
+			}
+			key.Path, key.RetiredAt = path, &retiredAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
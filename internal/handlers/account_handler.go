@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+)
+
+type AccountHandler struct {
+	accountService services.AccountServiceInterface
+	log            *logrus.Logger
+}
+
+func NewAccountHandler(accountService services.AccountServiceInterface, log *logrus.Logger) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		log:            log,
+	}
+}
+
+// Выгрузка личных данных zip-архивом с файлом на каждый раздел (format=zip, по умолчанию) или одним JSON
+func (h *AccountHandler) ExportAccount(c *gin.Context) {
+	format := c.DefaultQuery("format", "zip")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown export format"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	export, err := h.accountService.Export(username)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.log.Errorf("Error exporting account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-export.%s", username, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	archive, err := exportArchive(export)
+	if err != nil {
+		h.log.Errorf("Error building export archive: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// Удаление своего аккаунта, требует подтверждения паролем
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	// Без пароля (пустое тело) удаляют аккаунт пользователи, входящие только через OIDC
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	err := h.accountService.Delete(username, req.Password)
+	switch {
+	case errors.Is(err, models.ErrInvalidPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrAccountHasPendingOperations):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error deleting account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+	}
+}
+
+func exportArchive(export *models.AccountExport) ([]byte, error) {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", gin.H{"exported_at": export.ExportedAt, "profile": export.Profile}},
+		{"coin_history.json", export.CoinHistory},
+		{"purchases.json", export.Purchases},
+		{"inventory.json", export.Inventory},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
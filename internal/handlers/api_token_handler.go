@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type APITokenHandler struct {
+	apiTokenService services.APITokenServiceInterface
+	log             *logrus.Logger
+}
+
+func NewAPITokenHandler(apiTokenService services.APITokenServiceInterface, log *logrus.Logger) *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenService: apiTokenService,
+		log:             log,
+	}
+}
+
+// Создание персонального API-токена. Токен показывается только в этом ответе.
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	token, err := h.apiTokenService.Create(username, req)
+	if validationFailed(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrAPITokenNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error creating API token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+	default:
+		c.JSON(http.StatusCreated, token)
+	}
+}
+
+func (h *APITokenHandler) ListTokens(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	tokens, err := h.apiTokenService.List(username)
+	if err != nil {
+		h.log.Errorf("Error listing API tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	tokenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token id"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	err = h.apiTokenService.Revoke(username, tokenID)
+	switch {
+	case errors.Is(err, models.ErrAPITokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error revoking API token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type AuctionHandler struct {
+	auctionService services.AuctionServiceInterface
+	log            *logrus.Logger
+}
+
+func NewAuctionHandler(auctionService services.AuctionServiceInterface, log *logrus.Logger) *AuctionHandler {
+	return &AuctionHandler{
+		auctionService: auctionService,
+		log:            log,
+	}
+}
+
+func (h *AuctionHandler) CreateAuction(c *gin.Context) {
+	var req models.CreateAuctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	auction, err := h.auctionService.CreateAuction(req)
+	if err != nil {
+		h.log.Errorf("Error creating auction: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, auction)
+}
+
+func (h *AuctionHandler) ListAuctions(c *gin.Context) {
+	auctions, err := h.auctionService.ListOpenAuctions()
+	if err != nil {
+		h.log.Errorf("Error fetching auctions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auctions"})
+		return
+	}
+	if auctions == nil {
+		auctions = []models.Auction{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auctions": auctions})
+}
+
+func (h *AuctionHandler) GetAuction(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction id"})
+		return
+	}
+
+	auction, err := h.auctionService.GetAuction(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	bids, err := h.auctionService.GetBids(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if bids == nil {
+		bids = []models.AuctionBid{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auction": auction, "bids": bids})
+}
+
+func (h *AuctionHandler) PlaceBid(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction id"})
+		return
+	}
+
+	var req models.PlaceBidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be positive"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	if err = h.auctionService.PlaceBid(id, username, req.Amount); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bid placed"})
+}
+
+func (h *AuctionHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAuctionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrAuctionNotActive),
+		errors.Is(err, models.ErrBidTooLow),
+		errors.Is(err, models.ErrInsufficientBalance):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.log.Errorf("Auction request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process auction request"})
+	}
+}
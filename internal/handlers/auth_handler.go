@@ -3,25 +3,34 @@ package handlers
 import (
 	"ShopAvito/internal/models"
 	"ShopAvito/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type AuthHandler struct {
-	authService services.AuthServiceInterface
-	userService services.UserServiceInterface
-	log         *logrus.Logger
+	authService  services.AuthServiceInterface
+	userService  services.UserServiceInterface
+	autoRegister bool
+	log          *logrus.Logger
 }
 
-func NewAuthHandler(authService services.AuthServiceInterface, userService services.UserServiceInterface, log *logrus.Logger) *AuthHandler {
+// autoRegister включает прежнее поведение /api/auth: неизвестный логин регистрируется автоматически
+func NewAuthHandler(authService services.AuthServiceInterface, userService services.UserServiceInterface, autoRegister bool, log *logrus.Logger) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		userService: userService,
-		log:         log,
+		authService:  authService,
+		userService:  userService,
+		autoRegister: autoRegister,
+		log:          log,
 	}
 }
 
+// Вход с автоматической регистрацией для обратной совместимости. Если автоматическая
+// регистрация выключена, работает так же, как /api/auth/login.
 func (h *AuthHandler) Authenticate(c *gin.Context) {
 	var req models.AuthRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -37,10 +46,13 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		return
 	}
 
-	var token string
-	if exists {
-		// Если пользователь существует, проверяем пароль и выдаем токен
-		token, err = h.authService.Login(req.Username, req.Password)
+	var tokens *models.AuthResponse
+	if exists || !h.autoRegister {
+		// Если пользователь существует, проверяем пароль и выдаем токены
+		tokens, err = h.authService.Login(req.Username, req.Password, clientInfo(c))
+		if validationFailed(c, err) || h.loginLocked(c, err) {
+			return
+		}
 		if err != nil {
 			h.log.Error("Error log in:", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
@@ -48,7 +60,10 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		}
 	} else {
 		// Если пользователя нет, регистрируем его
-		token, err = h.authService.Register(req.Username, req.Password)
+		tokens, err = h.authService.Register(req.Username, req.Password, clientInfo(c))
+		if validationFailed(c, err) {
+			return
+		}
 		if err != nil {
 			h.log.Error("Error register:", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
@@ -56,5 +71,191 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, models.AuthResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Регистрация нового пользователя. Занятое имя - 409, вход в существующий аккаунт не выполняется.
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.AuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tokens, err := h.authService.Register(req.Username, req.Password, clientInfo(c))
+	if validationFailed(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrUserAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+	case err != nil:
+		h.log.Error("Error register:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	default:
+		c.JSON(http.StatusCreated, tokens)
+	}
+}
+
+// Вход существующего пользователя. Неизвестный логин не регистрируется.
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.AuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tokens, err := h.authService.Login(req.Username, req.Password, clientInfo(c))
+	if validationFailed(c, err) || h.loginLocked(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrUserNotFound), errors.Is(err, models.ErrInvalidPassword):
+		// Не сообщаем, что именно не подошло, чтобы нельзя было перебирать логины
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	case err != nil:
+		h.log.Error("Error log in:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+	default:
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// Второй шаг входа с двухфакторной аутентификацией: токен из ответа на вход и код TOTP
+// или код восстановления в обмен на пару токенов
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tokens, err := h.authService.VerifyTwoFactor(req.ChallengeToken, req.Code, clientInfo(c))
+	if h.loginLocked(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrInvalidChallenge), errors.Is(err, models.ErrInvalidTwoFactorCode),
+		errors.Is(err, models.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error verifying two-factor code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+	default:
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// Обмен refresh-токена на новую пару токенов
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	switch {
+	case errors.Is(err, models.ErrInvalidRefreshToken), errors.Is(err, models.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error refreshing token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+	default:
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// Выход: refresh-токен и все выданные по нему токены отзываются.
+// JWT из заголовка Authorization, если он передан, тоже отзывается.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	err := h.authService.Logout(req.RefreshToken, accessToken)
+	switch {
+	case errors.Is(err, models.ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error logging out: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+// Отзыв администратором всех токенов пользователя, например при потере устройства
+func (h *AuthHandler) RevokeUserTokens(c *gin.Context) {
+	var req models.RevokeTokensRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.log.Errorf("error occurred while binding json: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	before, err := h.authService.RevokeUserTokens(c.Param("username"), req.Before)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error revoking tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Tokens revoked", "revoked_before": before})
+	}
+}
+
+// Снятие блокировки входа по имени и/или IP-адресу
+func (h *AuthHandler) UnlockLogin(c *gin.Context) {
+	var req models.UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Username == "" && req.IP == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.authService.UnlockLogin(req.Username, req.IP); err != nil {
+		h.log.Errorf("Error unlocking login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Login unlocked"})
+}
+
+// Открытые ключи подписи токенов для других сервисов
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
+// Ошибки проверки полей отдаются клиенту списком: {"error": "Validation failed", "fields": [...]}
+func validationFailed(c *gin.Context, err error) bool {
+	var verr *models.ValidationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": verr.Fields})
+	return true
+}
+
+// Блокировка после неудачных попыток: 429 с заголовком Retry-After в секундах
+func (h *AuthHandler) loginLocked(c *gin.Context, err error) bool {
+	var lerr *models.LoginLockedError
+	if !errors.As(err, &lerr) {
+		return false
+	}
+	retryAfter := int(math.Ceil(lerr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts", "retry_after": retryAfter})
+	return true
+}
+
+// Клиент, с которого выполняется вход: для учёта неудачных попыток и списка сеансов
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
 }
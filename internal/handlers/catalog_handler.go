@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type CatalogHandler struct {
+	catalogService services.CatalogServiceInterface
+	userService    services.UserServiceInterface
+	log            *logrus.Logger
+}
+
+func NewCatalogHandler(catalogService services.CatalogServiceInterface, userService services.UserServiceInterface, log *logrus.Logger) *CatalogHandler {
+	return &CatalogHandler{
+		catalogService: catalogService,
+		userService:    userService,
+		log:            log,
+	}
+}
+
+// Поиск по каталогу: GET /api/items?q=&category=&tag=&sort=&page=&per_page=
+func (h *CatalogHandler) ListItems(c *gin.Context) {
+	query := models.CatalogQuery{
+		Query:    c.Query("q"),
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+		Sort:     c.Query("sort"),
+	}
+	var err error
+	if page := c.Query("page"); page != "" {
+		if query.Page, err = strconv.Atoi(page); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+			return
+		}
+	}
+	if perPage := c.Query("per_page"); perPage != "" {
+		if query.PerPage, err = strconv.Atoi(perPage); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page"})
+			return
+		}
+	}
+
+	username := c.MustGet("username").(string)
+	balance, err := h.userService.GetBalance(username)
+	if err != nil {
+		h.log.Errorf("Error getting balance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog"})
+		return
+	}
+
+	page, err := h.catalogService.SearchItems(query, balance)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *CatalogHandler) ListCategories(c *gin.Context) {
+	categories, err := h.catalogService.ListCategories()
+	if err != nil {
+		h.log.Errorf("Error fetching categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+func (h *CatalogHandler) UpsertVariant(c *gin.Context) {
+	var req models.UpsertVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	variant, err := h.catalogService.UpsertVariant(c.Param("item"), req)
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, variant)
+	}
+}
+
+func (h *CatalogHandler) CreateBundle(c *gin.Context) {
+	var req models.CreateBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	bundle, err := h.catalogService.CreateBundle(req)
+	switch {
+	case errors.Is(err, models.ErrInvalidBundle), errors.Is(err, models.ErrItemNotFound), errors.Is(err, models.ErrVariantNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error creating bundle: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bundle"})
+	default:
+		c.JSON(http.StatusCreated, bundle)
+	}
+}
+
+func (h *CatalogHandler) GetPriceHistory(c *gin.Context) {
+	history, err := h.catalogService.GetPriceHistory(c.Param("item"))
+	if errors.Is(err, models.ErrItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("Error fetching price history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+	if history == nil {
+		history = []models.PriceChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prices": history})
+}
+
+func (h *CatalogHandler) SchedulePriceChange(c *gin.Context) {
+	var req models.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	change, err := h.catalogService.SchedulePriceChange(c.Param("item"), req, username)
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, change)
+	}
+}
+
+func (h *CatalogHandler) CancelPriceChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price change id"})
+		return
+	}
+
+	err = h.catalogService.CancelPriceChange(id)
+	switch {
+	case errors.Is(err, models.ErrPriceChangeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error cancelling price change: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel price change"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Price change cancelled"})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type DiscountHandler struct {
+	discountService services.DiscountServiceInterface
+	log             *logrus.Logger
+}
+
+func NewDiscountHandler(discountService services.DiscountServiceInterface, log *logrus.Logger) *DiscountHandler {
+	return &DiscountHandler{
+		discountService: discountService,
+		log:             log,
+	}
+}
+
+func (h *DiscountHandler) CreateCampaign(c *gin.Context) {
+	var req models.Campaign
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	campaign, err := h.discountService.CreateCampaign(req)
+	if err != nil {
+		h.log.Errorf("Error creating campaign: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func (h *DiscountHandler) ListCampaigns(c *gin.Context) {
+	campaigns, err := h.discountService.ListCampaigns()
+	if err != nil {
+		h.log.Errorf("Error fetching campaigns: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaigns"})
+		return
+	}
+	if campaigns == nil {
+		campaigns = []models.Campaign{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+func (h *DiscountHandler) CreatePromoCode(c *gin.Context) {
+	var req models.CreatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	promo, err := h.discountService.CreatePromoCode(req)
+	if err != nil {
+		h.log.Errorf("Error creating promo code: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promo)
+}
+
+func (h *DiscountHandler) ListPromoCodes(c *gin.Context) {
+	codes, err := h.discountService.ListPromoCodes()
+	if err != nil {
+		h.log.Errorf("Error fetching promo codes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promo codes"})
+		return
+	}
+	if codes == nil {
+		codes = []models.PromoCode{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promo_codes": codes})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"ShopAvito/internal/storage"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type ImageHandler struct {
+	imageService services.ImageServiceInterface
+	log          *logrus.Logger
+}
+
+func NewImageHandler(imageService services.ImageServiceInterface, log *logrus.Logger) *ImageHandler {
+	return &ImageHandler{
+		imageService: imageService,
+		log:          log,
+	}
+}
+
+// Загрузка изображения товара: multipart/form-data с файлом в поле image
+func (h *ImageHandler) UploadItemImage(c *gin.Context) {
+	data, ok := readUploadedImage(c, h.log)
+	if !ok {
+		return
+	}
+
+	item, err := h.imageService.UploadItemImage(c.Param("item"), data)
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error uploading image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"image_url": item.ImageURL, "thumbnail_url": item.ThumbURL})
+	}
+}
+
+// Чтение файла из поля image запроса multipart/form-data. При ошибке ответ уже отправлен, возвращается false.
+func readUploadedImage(c *gin.Context, log *logrus.Logger) ([]byte, bool) {
+	// Запас на заголовки multipart сверх размера самого файла
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImageSize+64<<10)
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+		return nil, false
+	}
+	if file.Size > services.MaxImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+		return nil, false
+	}
+	f, err := file.Open()
+	if err != nil {
+		log.Errorf("Error opening uploaded image: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image"})
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		log.Errorf("Error reading uploaded image: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image"})
+		return nil, false
+	}
+	return data, true
+}
+
+// Раздача файлов из хранилища. Ключи содержат хэш содержимого, поэтому ответ кэшируется навсегда.
+func (h *ImageHandler) ServeImage(c *gin.Context) {
+	data, contentType, err := h.imageService.GetImage(strings.TrimPrefix(c.Param("key"), "/"))
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("Error reading image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Cookie со state незавершённого входа: связывает ответ провайдера с браузером, начавшим вход
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService services.OIDCServiceInterface
+	log         *logrus.Logger
+}
+
+func NewOIDCHandler(oidcService services.OIDCServiceInterface, log *logrus.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		log:         log,
+	}
+}
+
+// Начало входа через провайдера: перенаправление на его страницу входа
+func (h *OIDCHandler) Login(c *gin.Context) {
+	loginURL, state, err := h.oidcService.LoginURL()
+	if err != nil {
+		h.log.Errorf("Error starting OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(services.OIDCStateTTL.Seconds()), oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, loginURL)
+}
+
+// Возврат от провайдера: обмен кода на наши токены. Ответ такой же, как у /api/auth/login.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		h.log.Infof("OIDC login declined by identity provider: %s", providerError)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider error: " + providerError})
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if cookieState != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidOIDCState.Error()})
+		return
+	}
+
+	resp, err := h.oidcService.Callback(state, code, clientInfo(c))
+	switch {
+	case errors.Is(err, models.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrOIDCLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrOIDCLoginFailed.Error()})
+	case err != nil:
+		h.log.Errorf("Error completing OIDC login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login"})
+	default:
+		c.JSON(http.StatusOK, resp)
+	}
+}
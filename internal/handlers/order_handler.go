@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type OrderHandler struct {
+	orderService services.OrderServiceInterface
+	log          *logrus.Logger
+}
+
+func NewOrderHandler(orderService services.OrderServiceInterface, log *logrus.Logger) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+		log:          log,
+	}
+}
+
+func (h *OrderHandler) GetUserOrders(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	orders, err := h.orderService.GetUserOrders(username)
+	if err != nil {
+		h.log.Errorf("Error fetching orders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+func (h *OrderHandler) GetUserOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	order, history, err := h.orderService.GetUserOrder(username, orderID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order, "history": history})
+}
+
+func (h *OrderHandler) CancelUserOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	if err = h.orderService.CancelUserOrder(username, orderID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled"})
+}
+
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	orders, err := h.orderService.ListOrders(c.Query("status"))
+	if err != nil {
+		h.log.Errorf("Error fetching orders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	admin := c.MustGet("username").(string)
+	if err = h.orderService.UpdateOrderStatus(orderID, req.Status, admin); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order status updated"})
+}
+
+func (h *OrderHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidOrderTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.log.Errorf("Order request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process order request"})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type PasswordHandler struct {
+	passwordService services.PasswordServiceInterface
+	log             *logrus.Logger
+}
+
+func NewPasswordHandler(passwordService services.PasswordServiceInterface, log *logrus.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+		log:             log,
+	}
+}
+
+// Смена пароля текущим пользователем. После смены все токены отзываются, нужно войти заново.
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := h.passwordService.ChangePassword(username, req.CurrentPassword, req.NewPassword)
+	if validationFailed(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrInvalidPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+	case err != nil:
+		h.log.Errorf("Error changing password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+	}
+}
+
+// Выдача администратором токена сброса пароля. Токен передаётся пользователю вне сервиса.
+func (h *PasswordHandler) IssueResetToken(c *gin.Context) {
+	admin := c.MustGet("username").(string)
+
+	token, err := h.passwordService.IssueResetToken(c.Param("username"), admin)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error issuing password reset token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue reset token"})
+	default:
+		c.JSON(http.StatusCreated, token)
+	}
+}
+
+// Установка нового пароля по токену сброса
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := h.passwordService.ResetPassword(req.Token, req.NewPassword)
+	if validationFailed(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error resetting password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+	}
+}
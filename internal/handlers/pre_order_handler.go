@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type PreOrderHandler struct {
+	preOrderService services.PreOrderServiceInterface
+	catalogService  services.CatalogServiceInterface
+	log             *logrus.Logger
+}
+
+func NewPreOrderHandler(preOrderService services.PreOrderServiceInterface, catalogService services.CatalogServiceInterface, log *logrus.Logger) *PreOrderHandler {
+	return &PreOrderHandler{
+		preOrderService: preOrderService,
+		catalogService:  catalogService,
+		log:             log,
+	}
+}
+
+// Предзаказ товара. Вариант задаётся query-параметрами так же, как при покупке:
+// /api/items/hoody/preorder?size=M
+func (h *PreOrderHandler) CreatePreOrder(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	item, err := h.catalogService.GetItem(c.Param("item"))
+	if errors.Is(err, models.ErrItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("Error fetching catalog item: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
+		return
+	}
+	variant, err := h.catalogService.ResolveVariant(item, variantAttributes(c, item))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "variant_axes": item.VariantAxes})
+		return
+	}
+
+	preOrder, err := h.preOrderService.CreatePreOrder(username, item.Name, variant)
+	switch {
+	case errors.Is(err, models.ErrItemInStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrItemNotFound), errors.Is(err, models.ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, preOrder)
+	}
+}
+
+func (h *PreOrderHandler) GetUserPreOrders(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	preOrders, err := h.preOrderService.GetUserPreOrders(username)
+	if err != nil {
+		h.log.Errorf("Error fetching pre-orders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pre-orders"})
+		return
+	}
+	if preOrders == nil {
+		preOrders = []models.PreOrder{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pre_orders": preOrders})
+}
+
+func (h *PreOrderHandler) CancelPreOrder(c *gin.Context) {
+	preOrderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pre-order id"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	err = h.preOrderService.CancelPreOrder(username, preOrderID)
+	switch {
+	case errors.Is(err, models.ErrPreOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrPreOrderNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error cancelling pre-order: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel pre-order"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Pre-order cancelled"})
+	}
+}
+
+func (h *PreOrderHandler) ReleaseItemPreOrders(c *gin.Context) {
+	released, err := h.preOrderService.ReleaseItemPreOrders(c.Param("item"))
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error releasing pre-orders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release pre-orders"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"released": released})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type ProfileHandler struct {
+	profileService services.ProfileServiceInterface
+	log            *logrus.Logger
+}
+
+func NewProfileHandler(profileService services.ProfileServiceInterface, log *logrus.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		log:            log,
+	}
+}
+
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	profile, err := h.profileService.Get(username)
+	h.respondProfile(c, profile, err)
+}
+
+// Частичное изменение профиля: отображаемое имя, отдел, должность
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	profile, err := h.profileService.Update(username, req)
+	if validationFailed(c, err) {
+		return
+	}
+	h.respondProfile(c, profile, err)
+}
+
+// Загрузка аватара: multipart/form-data с файлом в поле image
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	data, ok := readUploadedImage(c, h.log)
+	if !ok {
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	profile, err := h.profileService.UploadAvatar(username, data)
+	if errors.Is(err, models.ErrInvalidImage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondProfile(c, profile, err)
+}
+
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	err := h.profileService.DeleteAvatar(username)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error deleting avatar: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete avatar"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Avatar deleted"})
+	}
+}
+
+func (h *ProfileHandler) respondProfile(c *gin.Context, profile *models.UserProfile, err error) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error handling profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process profile"})
+	default:
+		c.JSON(http.StatusOK, profile)
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"ShopAvito/internal/models"
 	"ShopAvito/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -10,40 +12,76 @@ import (
 type PurchaseHandler struct {
 	purchaseService  services.PurchaseServiceInterface
 	inventoryService services.InventoryServiceInterface
+	catalogService   services.CatalogServiceInterface
 	log              *logrus.Logger
 }
 
-func NewPurchaseHandler(purchaseService services.PurchaseServiceInterface, inventoryService services.InventoryServiceInterface, log *logrus.Logger) *PurchaseHandler {
+func NewPurchaseHandler(purchaseService services.PurchaseServiceInterface, inventoryService services.InventoryServiceInterface, catalogService services.CatalogServiceInterface, log *logrus.Logger) *PurchaseHandler {
 	return &PurchaseHandler{
 		purchaseService:  purchaseService,
 		inventoryService: inventoryService,
+		catalogService:   catalogService,
 		log:              log,
 	}
 }
 
+// Покупка товара. Вариант задаётся query-параметрами по осям товара, промокод - параметром promo:
+// /api/buy/hoody?size=M&promo=HACKATHON
 func (h *PurchaseHandler) BuyItem(c *gin.Context) {
 	username := c.MustGet("username").(string)
-	item := c.Param("item")
+	itemName := c.Param("item")
 
-	// Цены товаров
-	prices := map[string]int{
-		"t-shirt": 80, "cup": 20, "book": 50,
-		"pen": 10, "powerbank": 200, "hoody": 300,
-		"umbrella": 200, "socks": 10, "wallet": 50, "pink-hoody": 500,
-	}
-
-	price, exists := prices[item]
-	if !exists {
+	item, err := h.catalogService.GetItem(itemName)
+	if errors.Is(err, models.ErrItemNotFound) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item"})
 		return
 	}
+	if err != nil {
+		h.log.Errorf("Error fetching catalog item: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
+		return
+	}
 
-	err := h.purchaseService.BuyItem(username, item, price)
+	variant, err := h.catalogService.ResolveVariant(item, variantAttributes(c, item))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "variant_axes": item.VariantAxes})
+		return
+	}
+
+	orderID, quote, err := h.purchaseService.BuyItem(username, item.Name, variant, c.Query("promo"))
+	switch {
+	case errors.Is(err, models.ErrOutOfStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrInsufficientBalance),
+		errors.Is(err, models.ErrPromoCodeNotFound),
+		errors.Is(err, models.ErrPromoCodeExpired),
+		errors.Is(err, models.ErrPromoCodeNotApplicable),
+		errors.Is(err, models.ErrPromoCodeAlreadyUsed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
 		h.log.Errorf("Error buying item: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Purchase successful"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Purchase successful",
+		"order_id":   orderID,
+		"list_price": quote.ListPrice,
+		"discount":   quote.Discount,
+		"price":      quote.Price,
+	})
+}
+
+// Атрибуты варианта из query-параметров по осям товара
+func variantAttributes(c *gin.Context, item *models.CatalogItem) map[string]string {
+	attributes := map[string]string{}
+	for _, axis := range item.VariantAxes {
+		if value, ok := c.GetQuery(axis); ok {
+			attributes[axis] = value
+		}
+	}
+	return attributes
 }
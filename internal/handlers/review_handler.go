@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type ReviewHandler struct {
+	reviewService services.ReviewServiceInterface
+	log           *logrus.Logger
+}
+
+func NewReviewHandler(reviewService services.ReviewServiceInterface, log *logrus.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		log:           log,
+	}
+}
+
+func (h *ReviewHandler) GetItemReviews(c *gin.Context) {
+	item, reviews, err := h.reviewService.GetItemReviews(c.Param("item"))
+	if errors.Is(err, models.ErrItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("Error fetching reviews: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	if reviews == nil {
+		reviews = []models.Review{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item":         item.Name,
+		"rating":       item.Rating,
+		"review_count": item.ReviewCount,
+		"reviews":      reviews,
+	})
+}
+
+func (h *ReviewHandler) SaveReview(c *gin.Context) {
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	review, err := h.reviewService.SaveReview(username, c.Param("item"), req)
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrItemNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, review)
+	}
+}
+
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review id"})
+		return
+	}
+	var req models.ModerateReviewRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err = h.reviewService.ModerateReview(reviewID, req.Hidden)
+	switch {
+	case errors.Is(err, models.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error moderating review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Review updated"})
+	}
+}
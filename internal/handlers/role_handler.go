@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type RoleHandler struct {
+	roleService services.RoleServiceInterface
+	log         *logrus.Logger
+}
+
+func NewRoleHandler(roleService services.RoleServiceInterface, log *logrus.Logger) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+		log:         log,
+	}
+}
+
+// Назначение роли пользователю администратором
+func (h *RoleHandler) SetUserRole(c *gin.Context) {
+	admin := c.MustGet("username").(string)
+
+	var req models.SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.Param("username")
+	err := h.roleService.SetRole(admin, username, req.Role)
+	if validationFailed(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrCannotChangeOwnRole):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error setting role: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set role"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"username": username, "role": req.Role})
+	}
+}
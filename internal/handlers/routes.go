@@ -2,29 +2,139 @@ package handlers
 
 import (
 	"ShopAvito/internal/middleware"
+	"ShopAvito/internal/models"
 	"ShopAvito/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"strings"
 )
 
-func RegisterRoutes(userService *services.UserService, transactionService *services.TransactionService, purchaseService *services.PurchaseService, authService *services.AuthService, invenService *services.InventoryService, log *logrus.Logger) *gin.Engine {
-	authHandler := NewAuthHandler(authService, userService, log)
+func RegisterRoutes(userService *services.UserService, transactionService *services.TransactionService, purchaseService *services.PurchaseService, authService *services.AuthService, invenService *services.InventoryService, auctionService *services.AuctionService, orderService *services.OrderService, catalogService *services.CatalogService, discountService *services.DiscountService, wishlistService *services.WishlistService, reviewService *services.ReviewService, imageService *services.ImageService, preOrderService *services.PreOrderService, passwordService *services.PasswordService, twoFactorService *services.TwoFactorService, oidcService *services.OIDCService, apiTokenService *services.APITokenService, sessionService *services.SessionService, accountService *services.AccountService, profileService *services.ProfileService, autoRegister bool, log *logrus.Logger) *gin.Engine {
+	authHandler := NewAuthHandler(authService, userService, autoRegister, log)
 	userHandler := NewUserHandler(userService, transactionService, invenService, log)
-	transactionHandler := NewTransactionHandler(transactionService, log)
-	purchaseHandler := NewPurchaseHandler(purchaseService, invenService, log)
+	transactionHandler := NewTransactionHandler(transactionService, twoFactorService, log)
+	purchaseHandler := NewPurchaseHandler(purchaseService, invenService, catalogService, log)
+	catalogHandler := NewCatalogHandler(catalogService, userService, log)
+	discountHandler := NewDiscountHandler(discountService, log)
+	auctionHandler := NewAuctionHandler(auctionService, log)
+	orderHandler := NewOrderHandler(orderService, log)
+	wishlistHandler := NewWishlistHandler(wishlistService, log)
+	reviewHandler := NewReviewHandler(reviewService, log)
+	imageHandler := NewImageHandler(imageService, log)
+	preOrderHandler := NewPreOrderHandler(preOrderService, catalogService, log)
+	passwordHandler := NewPasswordHandler(passwordService, log)
+	twoFactorHandler := NewTwoFactorHandler(twoFactorService, log)
+	apiTokenHandler := NewAPITokenHandler(apiTokenService, log)
+	roleHandler := NewRoleHandler(userService, log)
+	sessionHandler := NewSessionHandler(sessionService, log)
+	accountHandler := NewAccountHandler(accountService, log)
+	profileHandler := NewProfileHandler(profileService, log)
 
 	router := gin.New()
+	// По умолчанию заголовкам X-Forwarded-For не доверяем; доверенные прокси задаются TRUSTED_PROXIES
+	_ = router.SetTrustedProxies(nil)
+
+	router.GET(strings.TrimSuffix(models.ImagesPath, "/")+"/*key", imageHandler.ServeImage)
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	api := router.Group("/api")
 	{
 		api.POST("/auth", authHandler.Authenticate)
+		api.POST("/auth/register", authHandler.Register)
+		api.POST("/auth/login", authHandler.Login)
+		api.POST("/auth/refresh", authHandler.Refresh)
+		api.POST("/auth/logout", authHandler.Logout)
+		api.POST("/auth/password/reset", passwordHandler.ResetPassword)
+		api.POST("/auth/2fa/verify", authHandler.VerifyTwoFactor)
+
+		// Вход через провайдера OpenID Connect, если он настроен
+		if oidcService != nil {
+			oidcHandler := NewOIDCHandler(oidcService, log)
+			api.GET("/auth/oidc/login", oidcHandler.Login)
+			api.GET("/auth/oidc/callback", oidcHandler.Callback)
+		}
+
+		// Каждый эндпоинт требует область действия. У JWT входа они определяются ролью пользователя,
+		// у персонального API-токена заданы при создании (account:manage и admin:* токену не выдаются).
+		scope := middleware.RequireScope
 
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(authService, log))
+		protected.Use(middleware.AuthMiddleware(authService, apiTokenService, sessionService, log))
+		{
+			protected.POST("/auth/password", scope(services.ScopeAccountManage), passwordHandler.ChangePassword)
+			protected.POST("/auth/2fa/enroll", scope(services.ScopeAccountManage), twoFactorHandler.Enroll)
+			protected.POST("/auth/2fa/confirm", scope(services.ScopeAccountManage), twoFactorHandler.Confirm)
+			protected.POST("/auth/2fa/disable", scope(services.ScopeAccountManage), twoFactorHandler.Disable)
+
+			protected.GET("/tokens", scope(services.ScopeAccountManage), apiTokenHandler.ListTokens)
+			protected.POST("/tokens", scope(services.ScopeAccountManage), apiTokenHandler.CreateToken)
+			protected.DELETE("/tokens/:id", scope(services.ScopeAccountManage), apiTokenHandler.RevokeToken)
+
+			protected.GET("/sessions", scope(services.ScopeAccountManage), sessionHandler.ListSessions)
+			protected.DELETE("/sessions/:id", scope(services.ScopeAccountManage), sessionHandler.RevokeSession)
+
+			protected.GET("/me", scope(services.ScopeInfoRead), profileHandler.GetProfile)
+			protected.PATCH("/me", scope(services.ScopeAccountManage), profileHandler.UpdateProfile)
+			protected.PUT("/me/avatar", scope(services.ScopeAccountManage), profileHandler.UploadAvatar)
+			protected.DELETE("/me/avatar", scope(services.ScopeAccountManage), profileHandler.DeleteAvatar)
+			protected.GET("/me/export", scope(services.ScopeAccountManage), accountHandler.ExportAccount)
+			protected.DELETE("/me", scope(services.ScopeAccountManage), accountHandler.DeleteAccount)
+
+			protected.GET("/info", scope(services.ScopeInfoRead), userHandler.GetUserInfo)
+			protected.POST("/sendCoin", scope(services.ScopeTransferSend), transactionHandler.SendCoins)
+			protected.GET("/buy/:item", scope(services.ScopePurchaseWrite), purchaseHandler.BuyItem)
+
+			protected.GET("/items", scope(services.ScopeCatalogRead), catalogHandler.ListItems)
+			protected.GET("/categories", scope(services.ScopeCatalogRead), catalogHandler.ListCategories)
+			protected.GET("/items/:item/prices", scope(services.ScopeCatalogRead), catalogHandler.GetPriceHistory)
+			protected.GET("/items/:item/reviews", scope(services.ScopeCatalogRead), reviewHandler.GetItemReviews)
+			protected.PUT("/items/:item/reviews", scope(services.ScopeReviewsWrite), reviewHandler.SaveReview)
+
+			protected.GET("/wishlist", scope(services.ScopeInfoRead), wishlistHandler.GetWishlist)
+			protected.POST("/wishlist", scope(services.ScopeWishlistWrite), wishlistHandler.AddItem)
+			protected.DELETE("/wishlist/:item", scope(services.ScopeWishlistWrite), wishlistHandler.RemoveItem)
+			protected.GET("/notifications", scope(services.ScopeInfoRead), wishlistHandler.GetNotifications)
+			protected.POST("/notifications/read", scope(services.ScopeWishlistWrite), wishlistHandler.MarkNotificationsRead)
+
+			protected.GET("/preorders", scope(services.ScopeInfoRead), preOrderHandler.GetUserPreOrders)
+			protected.POST("/items/:item/preorder", scope(services.ScopePurchaseWrite), preOrderHandler.CreatePreOrder)
+			protected.POST("/preorders/:id/cancel", scope(services.ScopePurchaseWrite), preOrderHandler.CancelPreOrder)
+
+			protected.GET("/orders", scope(services.ScopeInfoRead), orderHandler.GetUserOrders)
+			protected.GET("/orders/:id", scope(services.ScopeInfoRead), orderHandler.GetUserOrder)
+			protected.POST("/orders/:id/cancel", scope(services.ScopePurchaseWrite), orderHandler.CancelUserOrder)
+
+			protected.GET("/auctions", scope(services.ScopeCatalogRead), auctionHandler.ListAuctions)
+			protected.GET("/auctions/:id", scope(services.ScopeCatalogRead), auctionHandler.GetAuction)
+			protected.POST("/auctions/:id/bids", scope(services.ScopePurchaseWrite), auctionHandler.PlaceBid)
+		}
+
+		// Только для сотрудников; AdminMiddleware сужает области действия до текущей роли
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(authService, apiTokenService, sessionService, log), middleware.AdminMiddleware(userService, log), middleware.AdminTwoFactorMiddleware(twoFactorService, log))
 		{
-			protected.GET("/info", userHandler.GetUserInfo)
-			protected.POST("/sendCoin", transactionHandler.SendCoins)
-			protected.GET("/buy/:item", purchaseHandler.BuyItem)
+			admin.POST("/auctions", scope(services.ScopeAdminAuctions), auctionHandler.CreateAuction)
+
+			admin.POST("/users/:username/revoke-tokens", scope(services.ScopeAdminUsers), authHandler.RevokeUserTokens)
+			admin.POST("/auth/unlock", scope(services.ScopeAdminUsers), authHandler.UnlockLogin)
+			admin.POST("/users/:username/password-reset", scope(services.ScopeAdminUsers), passwordHandler.IssueResetToken)
+			admin.PUT("/users/:username/role", scope(services.ScopeAdminUsers), roleHandler.SetUserRole)
+
+			admin.PUT("/items/:item/variants", scope(services.ScopeAdminCatalog), catalogHandler.UpsertVariant)
+			admin.POST("/items/:item/image", scope(services.ScopeAdminCatalog), imageHandler.UploadItemImage)
+			admin.POST("/items/:item/prices", scope(services.ScopeAdminCatalog), catalogHandler.SchedulePriceChange)
+			admin.DELETE("/prices/:id", scope(services.ScopeAdminCatalog), catalogHandler.CancelPriceChange)
+			admin.POST("/items/:item/preorders/release", scope(services.ScopeAdminCatalog), preOrderHandler.ReleaseItemPreOrders)
+			admin.POST("/bundles", scope(services.ScopeAdminCatalog), catalogHandler.CreateBundle)
+			admin.PUT("/reviews/:id/hidden", scope(services.ScopeAdminReviews), reviewHandler.ModerateReview)
+
+			admin.GET("/campaigns", scope(services.ScopeAdminPromotions), discountHandler.ListCampaigns)
+			admin.POST("/campaigns", scope(services.ScopeAdminPromotions), discountHandler.CreateCampaign)
+			admin.GET("/promo-codes", scope(services.ScopeAdminPromotions), discountHandler.ListPromoCodes)
+			admin.POST("/promo-codes", scope(services.ScopeAdminPromotions), discountHandler.CreatePromoCode)
+
+			admin.GET("/orders", scope(services.ScopeAdminOrders), orderHandler.ListOrders)
+			admin.POST("/orders/:id/status", scope(services.ScopeAdminOrders), orderHandler.UpdateOrderStatus)
 		}
 	}
 
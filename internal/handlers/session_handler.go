@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type SessionHandler struct {
+	sessionService services.SessionServiceInterface
+	log            *logrus.Logger
+}
+
+func NewSessionHandler(sessionService services.SessionServiceInterface, log *logrus.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		log:            log,
+	}
+}
+
+// Активные сеансы пользователя, текущий отмечен полем current
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	username := c.MustGet("username").(string)
+	sessions, err := h.sessionService.List(username, c.GetString("session"))
+	if err != nil {
+		h.log.Errorf("Error listing sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// Завершение сеанса, например на потерянном устройстве
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session id"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	err = h.sessionService.Revoke(username, sessionID)
+	switch {
+	case errors.Is(err, models.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error revoking session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"ShopAvito/internal/models"
 	"ShopAvito/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -10,12 +11,15 @@ import (
 
 type TransactionHandler struct {
 	transactionService services.TransactionServiceInterface
+	twoFactorService   services.TwoFactorServiceInterface
 	log                *logrus.Logger
 }
 
-func NewTransactionHandler(transactionService services.TransactionServiceInterface, log *logrus.Logger) *TransactionHandler {
+// twoFactorService может быть nil - тогда переводы не требуют кода двухфакторной аутентификации
+func NewTransactionHandler(transactionService services.TransactionServiceInterface, twoFactorService services.TwoFactorServiceInterface, log *logrus.Logger) *TransactionHandler {
 	return &TransactionHandler{
 		transactionService: transactionService,
+		twoFactorService:   twoFactorService,
 		log:                log,
 	}
 }
@@ -29,6 +33,20 @@ func (h *TransactionHandler) SendCoins(c *gin.Context) {
 	}
 
 	fromUser := c.MustGet("username").(string)
+	if h.twoFactorService != nil {
+		err := h.twoFactorService.AuthorizeTransfer(fromUser, req.Amount, req.TwoFactorCode)
+		switch {
+		case errors.Is(err, models.ErrTwoFactorRequired), errors.Is(err, models.ErrTwoFactorNotEnabled),
+			errors.Is(err, models.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			h.log.Errorf("error occurred while checking two-factor code: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor code"})
+			return
+		}
+	}
+
 	err := h.transactionService.TransferCoins(fromUser, req.ToUser, req.Amount)
 	if err != nil {
 		h.log.Errorf("error occurred while sending coins: %v", err)
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService services.TwoFactorServiceInterface
+	log              *logrus.Logger
+}
+
+func NewTwoFactorHandler(twoFactorService services.TwoFactorServiceInterface, log *logrus.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		log:              log,
+	}
+}
+
+// Начало подключения двухфакторной аутентификации: секрет и otpauth:// URI для QR-кода
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	enrollment, err := h.twoFactorService.Enroll(username)
+	switch {
+	case errors.Is(err, models.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error enrolling two-factor authentication: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll two-factor authentication"})
+	default:
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// Подтверждение кодом из приложения. В ответе коды восстановления, больше они не показываются.
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	var req models.TwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	codes, err := h.twoFactorService.Confirm(username, req.Code)
+	switch {
+	case errors.Is(err, models.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTwoFactorNotEnabled), errors.Is(err, models.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error confirming two-factor authentication: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// Отключение двухфакторной аутентификации по коду TOTP или коду восстановления
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	var req models.TwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := h.twoFactorService.Disable(username, req.Code)
+	switch {
+	case errors.Is(err, models.ErrTwoFactorNotEnabled), errors.Is(err, models.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error disabling two-factor authentication: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type WishlistHandler struct {
+	wishlistService services.WishlistServiceInterface
+	log             *logrus.Logger
+}
+
+func NewWishlistHandler(wishlistService services.WishlistServiceInterface, log *logrus.Logger) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: wishlistService,
+		log:             log,
+	}
+}
+
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	items, balance, err := h.wishlistService.GetWishlist(username)
+	if err != nil {
+		h.log.Errorf("Error fetching wishlist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wishlist"})
+		return
+	}
+	if items == nil {
+		items = []models.WishlistItem{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance, "items": items})
+}
+
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	var req models.AddToWishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Errorf("error occurred while binding json: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	username := c.MustGet("username").(string)
+	err := h.wishlistService.AddItem(username, req.Item)
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Item added to wishlist"})
+	}
+}
+
+func (h *WishlistHandler) RemoveItem(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	err := h.wishlistService.RemoveItem(username, c.Param("item"))
+	switch {
+	case errors.Is(err, models.ErrWishlistItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		h.log.Errorf("Error removing wishlist item: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from wishlist"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Item removed from wishlist"})
+	}
+}
+
+func (h *WishlistHandler) GetNotifications(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	notifications, err := h.wishlistService.GetNotifications(username, c.Query("unread") == "true")
+	if err != nil {
+		h.log.Errorf("Error fetching notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
+
+func (h *WishlistHandler) MarkNotificationsRead(c *gin.Context) {
+	username := c.MustGet("username").(string)
+
+	if err := h.wishlistService.MarkNotificationsRead(username); err != nil {
+		h.log.Errorf("Error marking notifications read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read"})
+}
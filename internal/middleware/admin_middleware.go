@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"ShopAvito/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// AdminMiddleware пропускает только сотрудников (модераторов и администраторов). Должен стоять после AuthMiddleware.
+// Роль читается из базы на каждый запрос, и области действия токена сужаются до областей текущей роли,
+// поэтому понижение в правах действует сразу, не дожидаясь истечения JWT.
+func AdminMiddleware(userService *services.UserService, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.GetString("username")
+
+		role, err := userService.GetRole(username)
+		if err != nil {
+			log.Errorf("Role check failed for user %s: %v", username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			c.Abort()
+			return
+		}
+		if !services.IsStaffRole(role) {
+			log.Infof("User %s with role %s is not staff", username, role)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		allowed := services.RoleScopes(role)
+		var scopes []string
+		for _, scope := range c.GetStringSlice("scopes") {
+			if hasScope(allowed, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		c.Set("scopes", scopes)
+
+		c.Next()
+	}
+}
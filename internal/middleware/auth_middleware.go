@@ -1,17 +1,21 @@
 package middleware
 
 import (
+	"ShopAvito/internal/models"
 	"ShopAvito/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"strings"
 )
 
-func AuthMiddleware(authService *services.AuthService, log *logrus.Logger) gin.HandlerFunc {
+// AuthMiddleware принимает JWT входа и персональные API-токены. В контекст кладутся имя пользователя
+// "username" и области действия токена "scopes", их проверяет RequireScope. У JWT входа в "session"
+// кладётся его сеанс; JWT завершённого сеанса не принимается. apiTokenService и sessionService могут быть nil.
+func AuthMiddleware(authService *services.AuthService, apiTokenService *services.APITokenService, sessionService *services.SessionService, log *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
-		log.Info("Authorization Header:", tokenString)
 
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
@@ -29,6 +33,28 @@ func AuthMiddleware(authService *services.AuthService, log *logrus.Logger) gin.H
 
 		tokenString = parts[1] // Оставляем только сам токен
 
+		if apiTokenService != nil && services.IsAPIToken(tokenString) {
+			token, err := apiTokenService.Authenticate(tokenString)
+			if errors.Is(err, models.ErrInvalidAPIToken) {
+				log.Info("API token validation failed")
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				log.Errorf("API token check failed: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+				c.Abort()
+				return
+			}
+
+			log.Infof("API token %d valid. Username extracted: %s", token.ID, token.Username)
+			c.Set("username", token.Username)
+			c.Set("scopes", token.Scopes)
+			c.Next()
+			return
+		}
+
 		claims, err := authService.ValidateToken(tokenString)
 		if err != nil {
 			log.Info("Token validation error:", err)
@@ -37,9 +63,47 @@ func AuthMiddleware(authService *services.AuthService, log *logrus.Logger) gin.H
 			return
 		}
 
+		// Токен мог быть отозван до истечения срока действия
+		revoked, err := authService.IsTokenRevoked(claims)
+		if err != nil {
+			log.Errorf("Token revocation check failed for user %s: %v", claims.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			log.Infof("Revoked token used by user %s", claims.Username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		if sessionService != nil {
+			ended, err := sessionService.IsEnded(claims.Session)
+			if err != nil {
+				log.Errorf("Session check failed for user %s: %v", claims.Username, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+				c.Abort()
+				return
+			}
+			if ended {
+				log.Infof("Token of ended session used by user %s", claims.Username)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
 		log.Infof("Token valid. Username extracted: %s", claims.Username)
 		// Передаем username в контекст запроса
 		c.Set("username", claims.Username)
+		c.Set("session", claims.Session)
+		scopes := claims.Scopes
+		if len(scopes) == 0 {
+			// JWT, выданные до появления ролей, получают права обычного пользователя
+			scopes = services.RoleScopes(services.RoleUser)
+		}
+		c.Set("scopes", scopes)
 		c.Next()
 	}
 }
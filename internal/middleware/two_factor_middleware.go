@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// AdminTwoFactorMiddleware не пускает администраторов без двухфакторной аутентификации, если этого
+// требует политика. Должен стоять после AdminMiddleware.
+func AdminTwoFactorMiddleware(twoFactorService *services.TwoFactorService, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.GetString("username")
+
+		err := twoFactorService.CheckAdmin(username)
+		if errors.Is(err, models.ErrTwoFactorRequired) {
+			log.Infof("Admin %s has no two-factor authentication", username)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for administrators"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Errorf("Two-factor check failed for user %s: %v", username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ошибки аукционов
+var (
+	ErrAuctionNotFound     = errors.New("auction not found")
+	ErrAuctionNotActive    = errors.New("auction is not active")
+	ErrBidTooLow           = errors.New("bid must be higher than the current highest bid")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// Ошибки заказов
+var (
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+)
+
+// Ошибки каталога
+var (
+	ErrItemNotFound    = errors.New("item not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrInvalidVariant  = errors.New("variant attributes do not match item axes")
+	ErrOutOfStock      = errors.New("item is out of stock")
+)
+
+// Ошибки скидок и промокодов
+var (
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeExpired       = errors.New("promo code has expired or is used up")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this item")
+	ErrPromoCodeAlreadyUsed   = errors.New("promo code has already been used")
+)
+
+// ErrInvalidBundle - некорректный состав набора
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// ErrWishlistItemNotFound - товара нет в вишлисте
+var ErrWishlistItemNotFound = errors.New("item is not in wishlist")
+
+// ErrPriceChangeNotFound - запланированное изменение цены не найдено или уже применено
+var ErrPriceChangeNotFound = errors.New("scheduled price change not found")
+
+// ErrInvalidImage - файл не подходит как изображение товара или аватар
+var ErrInvalidImage = errors.New("invalid image")
+
+// Ошибки отзывов
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrItemNotOwned   = errors.New("only owners of the item can review it")
+)
+
+// Ошибки предзаказов
+var (
+	ErrPreOrderNotFound  = errors.New("pre-order not found")
+	ErrPreOrderNotActive = errors.New("pre-order is no longer active")
+	ErrItemInStock       = errors.New("item is in stock, buy it instead")
+)
+
+// Ошибки refresh-токенов
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// ErrSessionNotFound - у пользователя нет сеанса с таким id
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrValidation - запрос не прошёл проверку, подробности по полям в ValidationError
+var ErrValidation = errors.New("validation failed")
+
+// FieldError - ошибка проверки одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError - ошибки проверки запроса по полям
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return ErrValidation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// Ошибки пользователей и входа
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrInvalidPassword   = errors.New("invalid password")
+)
+
+// ErrAccountHasPendingOperations - у аккаунта есть предзаказы с зарезервированными монетами или
+// удерживаемые ставки на аукционах, удалить его пока нельзя
+var ErrAccountHasPendingOperations = errors.New("account has active pre-orders or held auction bids")
+
+// ErrCannotChangeOwnRole - администратор не может сменить роль самому себе
+var ErrCannotChangeOwnRole = errors.New("cannot change your own role")
+
+// ErrInvalidResetToken - токена сброса пароля нет, он истёк или уже использован
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// Ошибки двухфакторной аутентификации
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired two-factor challenge")
+)
+
+// Ошибки входа через OpenID Connect
+var (
+	ErrInvalidOIDCState = errors.New("invalid or expired OIDC login state")
+	ErrOIDCLoginFailed  = errors.New("OIDC login failed")
+)
+
+// Ошибки персональных API-токенов
+var (
+	ErrInvalidAPIToken   = errors.New("invalid or expired API token")
+	ErrAPITokenNotFound  = errors.New("API token not found")
+	ErrAPITokenNameTaken = errors.New("API token with this name already exists")
+)
+
+// ErrLoginLocked - вход временно заблокирован после неудачных попыток, подробности в LoginLockedError
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LoginLockedError - вход заблокирован, повторить попытку можно через RetryAfter
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
@@ -11,22 +11,57 @@ type User struct {
 	Balance  int    `json:"balance"`
 }
 
-// Transaction - структура для перевода монет
+// UserProfile - профиль пользователя. AvatarKey - ключ аватара в хранилище, JoinedAt - дата регистрации.
+type UserProfile struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Department  string    `json:"department"`
+	Title       string    `json:"title"`
+	AvatarKey   string    `json:"-"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// Label - как пользователь показывается другим: "Anna Petrova (Backend)", без имени - логин
+func (p UserProfile) Label() string {
+	name := p.DisplayName
+	if name == "" {
+		name = p.Username
+	}
+	if p.Department != "" {
+		name += " (" + p.Department + ")"
+	}
+	return name
+}
+
+// UpdateProfileRequest - частичное изменение профиля: меняются только переданные поля, пустая строка очищает поле
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	Department  *string `json:"department"`
+	Title       *string `json:"title"`
+}
+
+// Transaction - структура для перевода монет. FromName и ToName - подписи участников по их профилям.
 type Transaction struct {
 	ID       int       `json:"id"`
 	FromUser string    `json:"from_user"`
+	FromName string    `json:"from_name,omitempty"`
 	ToUser   string    `json:"to_user"`
+	ToName   string    `json:"to_name,omitempty"`
 	Amount   int       `json:"amount"`
 	Time     time.Time `json:"timestamp"`
 }
 
 // Purchase - структура для покупки товара
 type Purchase struct {
-	ID       int       `json:"id"`
-	UserID   int       `json:"user_id"`
-	ItemName string    `json:"item_name"`
-	Price    int       `json:"price"`
-	Time     time.Time `json:"timestamp"`
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ItemName  string    `json:"item_name"`
+	Variant   string    `json:"variant"`
+	ListPrice int       `json:"list_price"`
+	Discount  int       `json:"discount"`
+	Price     int       `json:"price"`
+	Time      time.Time `json:"timestamp"`
 }
 
 // AuthRequest - запрос на авторизацию
@@ -35,15 +70,200 @@ type AuthRequest struct {
 	Password string `json:"password"`
 }
 
-// AuthResponse - ответ с JWT токеном
+// AuthResponse - ответ с парой токенов: короткоживущий JWT и refresh-токен для его обновления.
+// ExpiresIn - время жизни JWT в секундах. Если у пользователя включена двухфакторная аутентификация,
+// вместо токенов возвращается ChallengeToken, который обменивается на токены вместе с кодом,
+// ExpiresIn тогда - время жизни ChallengeToken.
 type AuthResponse struct {
+	Token             string `json:"token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	ExpiresIn         int    `json:"expires_in"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// TwoFactorRequest - код TOTP или код восстановления
+type TwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorVerifyRequest - второй шаг входа: токен из ответа на вход и код
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorEnrollment - секрет TOTP для приложения-аутентификатора, в URI - для QR-кода
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TOTPSecret - секрет TOTP пользователя. LastUsedStep - последний принятый интервал,
+// код из него и более ранних повторно не принимается.
+type TOTPSecret struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+// OIDCLoginState - незавершённый вход через OpenID Connect. State связывает ответ провайдера с запросом,
+// Nonce - ID-токен с этим запросом, CodeVerifier - секрет PKCE для обмена кода на токены.
+type OIDCLoginState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// RefreshRequest - запрос на обновление токенов или выход
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// JWK - открытый ключ подписи JWT (RFC 7517). Для RSA заполняются N и E, для Ed25519 - Crv и X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS - набор открытых ключей для проверки наших токенов другими сервисами
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// RevokeTokensRequest - отзыв администратором всех токенов пользователя, выданных до Before.
+// Без Before отзываются все выданные к этому моменту токены.
+type RevokeTokensRequest struct {
+	Before *time.Time `json:"before"`
+}
+
+// RefreshToken - сохранённый refresh-токен. Вместо самого токена хранится его хэш,
+// токены одной цепочки обновлений объединены в семейство FamilyID.
+type RefreshToken struct {
+	ID        int
+	Username  string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// ClientInfo - откуда выполнен вход: IP-адрес и User-Agent клиента
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session - сеанс входа пользователя. Сеанс соответствует семейству refresh-токенов FamilyID и активен,
+// пока у семейства есть действующий refresh-токен. Current отмечает сеанс, из которого сделан запрос.
+type Session struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"-"`
+	FamilyID   string    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+// AccountExport - выгрузка личных данных пользователя: профиль, история монет, покупки и инвентарь
+type AccountExport struct {
+	ExportedAt  time.Time       `json:"exported_at"`
+	Profile     AccountProfile  `json:"profile"`
+	CoinHistory []Transaction   `json:"coin_history"`
+	Purchases   []Purchase      `json:"purchases"`
+	Inventory   []InventoryItem `json:"inventory"`
+}
+
+// AccountProfile - профиль в выгрузке. Coins - доступный баланс, Reserved - монеты под предзаказы.
+type AccountProfile struct {
+	UserProfile
+	Role     string `json:"role"`
+	Coins    int    `json:"coins"`
+	Reserved int    `json:"reserved"`
+}
+
+// DeleteAccountRequest - удаление аккаунта. Пароль не нужен только пользователям без пароля (вход через OIDC).
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// ChangePasswordRequest - смена пароля пользователем
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ResetPasswordRequest - установка пароля по токену сброса
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// PasswordResetToken - одноразовый токен сброса пароля, выданный администратором
+type PasswordResetToken struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// APIToken - персональный API-токен для ботов и интеграций. Сам токен показывается только при создании,
+// хранится его хэш. Scopes - разрешённые токену действия, например transfer:send.
+type APIToken struct {
+	ID         int        `json:"id"`
+	Username   string     `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPITokenRequest - запрос на создание API-токена. ExpiresInDays 0 - срок по умолчанию.
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreatedAPIToken - созданный API-токен вместе с самим токеном
+type CreatedAPIToken struct {
+	APIToken
 	Token string `json:"token"`
 }
 
+// SetUserRoleRequest - назначение роли пользователю: user, moderator или admin
+type SetUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// LoginAttempts - неудачные попытки входа по ключу (имени пользователя или IP-адресу)
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// UnlockLoginRequest - снятие блокировки входа администратором: по имени, по IP-адресу или по обоим
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
 // SendCoinRequest - запрос на перевод монет
+// TwoFactorCode нужен для переводов выше порога, если он задан
 type SendCoinRequest struct {
-	ToUser string `json:"to_user"`
-	Amount int    `json:"amount"`
+	ToUser        string `json:"to_user"`
+	Amount        int    `json:"amount" binding:"gt=0"`
+	TwoFactorCode string `json:"two_factor_code,omitempty"`
 }
 
 // InfoResponse - ответ с информацией о пользователе
@@ -56,6 +276,7 @@ type InfoResponse struct {
 // InventoryItem - предмет в инвентаре
 type InventoryItem struct {
 	Type     string `json:"type"`
+	Variant  string `json:"variant"`
 	Quantity int    `json:"quantity"`
 }
 
@@ -65,9 +286,326 @@ type CoinHistory struct {
 	Sent     []TransactionDetail `json:"sent"`
 }
 
-// TransactionDetail - детали транзакции (для истории)
+// TransactionDetail - детали транзакции (для истории). FromName и ToName - подписи по профилю, например "Anna Petrova (Backend)".
 type TransactionDetail struct {
 	FromUser string `json:"from_user,omitempty"`
+	FromName string `json:"from_name,omitempty"`
 	ToUser   string `json:"to_user,omitempty"`
+	ToName   string `json:"to_name,omitempty"`
 	Amount   int    `json:"amount"`
 }
+
+// Статусы аукциона
+const (
+	AuctionStatusOpen   = "open"
+	AuctionStatusClosed = "closed"
+)
+
+// Статусы ставки: монеты удерживаются, возвращаются при перебитии или списываются у победителя
+const (
+	BidStatusHeld     = "held"
+	BidStatusRefunded = "refunded"
+	BidStatusCaptured = "captured"
+)
+
+// Auction - аукцион на лимитированный товар
+type Auction struct {
+	ID           int       `json:"id"`
+	ItemName     string    `json:"item_name"`
+	ReservePrice int       `json:"reserve_price"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Status       string    `json:"status"`
+	Winner       string    `json:"winner,omitempty"`
+	FinalPrice   int       `json:"final_price,omitempty"`
+	HighestBid   int       `json:"highest_bid"`
+}
+
+// AuctionBid - ставка пользователя на аукционе
+type AuctionBid struct {
+	ID        int       `json:"id"`
+	AuctionID int       `json:"auction_id"`
+	Username  string    `json:"username"`
+	Amount    int       `json:"amount"`
+	Status    string    `json:"status"`
+	Time      time.Time `json:"timestamp"`
+}
+
+// CreateAuctionRequest - запрос администратора на создание аукциона
+type CreateAuctionRequest struct {
+	ItemName     string    `json:"item_name"`
+	ReservePrice int       `json:"reserve_price"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+// PlaceBidRequest - запрос на ставку
+type PlaceBidRequest struct {
+	Amount int `json:"amount"`
+}
+
+// Статусы заказа: placed → prepared → ready_for_pickup → delivered, до выдачи заказ можно отменить
+const (
+	OrderStatusPlaced         = "placed"
+	OrderStatusPrepared       = "prepared"
+	OrderStatusReadyForPickup = "ready_for_pickup"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+)
+
+// Order - заказ на выдачу купленного мерча
+type Order struct {
+	ID         int       `json:"id"`
+	PurchaseID int       `json:"purchase_id"`
+	Username   string    `json:"username"`
+	ItemName   string    `json:"item_name"`
+	Variant    string    `json:"variant"`
+	Price      int       `json:"price"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OrderStatusChange - запись в истории статусов заказа
+type OrderStatusChange struct {
+	Status    string    `json:"status"`
+	ChangedBy string    `json:"changed_by"`
+	Time      time.Time `json:"timestamp"`
+}
+
+// UpdateOrderStatusRequest - запрос администратора на смену статуса заказа
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// DefaultVariant - вариант товара без осей (размера, цвета и т.п.)
+const DefaultVariant = "default"
+
+// CatalogItem - товар каталога
+type CatalogItem struct {
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Description string            `json:"description"`
+	Category    string            `json:"category"`
+	Tags        []string          `json:"tags"`
+	ImageURL    string            `json:"image_url,omitempty"`
+	ThumbURL    string            `json:"thumbnail_url,omitempty"`
+	ImageKey    string            `json:"-"`
+	ThumbKey    string            `json:"-"`
+	VariantAxes []string          `json:"variant_axes"`
+	Variants    []CatalogVariant  `json:"variants"`
+	Components  []BundleComponent `json:"components,omitempty"`
+	Rating      *float64          `json:"rating"`
+	ReviewCount int               `json:"review_count"`
+	Affordable  *bool             `json:"affordable,omitempty"`
+}
+
+// Варианты сортировки каталога
+const (
+	SortByName      = "name"
+	SortByPrice     = "price"
+	SortByPriceDesc = "-price"
+	SortByRating    = "rating"
+)
+
+// CatalogQuery - параметры поиска по каталогу. Нумерация страниц начинается с 1.
+type CatalogQuery struct {
+	Query    string
+	Category string
+	Tag      string
+	Sort     string
+	Page     int
+	PerPage  int
+}
+
+// CatalogPage - страница результатов поиска по каталогу
+type CatalogPage struct {
+	Items   []CatalogItem `json:"items"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+}
+
+// Category - категория каталога с количеством товаров
+type Category struct {
+	Name      string `json:"name"`
+	ItemCount int    `json:"item_count"`
+}
+
+// ImagesPath - путь, по которому приложение отдаёт файлы из хранилища
+const ImagesPath = "/images/"
+
+// PriceChange - изменение цены товара. Applied = false - запланированное изменение, ещё не вступившее в силу.
+type PriceChange struct {
+	ID          int       `json:"id"`
+	ItemName    string    `json:"item_name"`
+	Price       int       `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	ChangedBy   string    `json:"changed_by"`
+	Applied     bool      `json:"applied"`
+}
+
+// SchedulePriceRequest - запрос администратора на изменение цены. Без EffectiveAt цена меняется сразу.
+type SchedulePriceRequest struct {
+	Price       int        `json:"price"`
+	EffectiveAt *time.Time `json:"effective_at"`
+}
+
+// BundleComponent - товар в составе набора. Пустой Variant означает, что берётся вариант самого набора.
+type BundleComponent struct {
+	ItemName string `json:"item_name"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// CreateBundleRequest - запрос администратора на создание набора
+type CreateBundleRequest struct {
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Description string            `json:"description"`
+	Category    string            `json:"category"`
+	Tags        []string          `json:"tags"`
+	Components  []BundleComponent `json:"components"`
+}
+
+// CatalogVariant - вариант товара, например размер M. Stock = nil означает неограниченный остаток.
+type CatalogVariant struct {
+	Key        string            `json:"variant"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Stock      *int              `json:"stock,omitempty"`
+}
+
+// UpsertVariantRequest - запрос администратора на добавление варианта или изменение его остатка
+type UpsertVariantRequest struct {
+	Attributes map[string]string `json:"attributes"`
+	Stock      *int              `json:"stock"`
+}
+
+// Типы скидок
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// Campaign - скидочная кампания на период. Пустой ItemNames означает весь каталог.
+type Campaign struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	ItemNames     []string  `json:"item_names"`
+	DiscountType  string    `json:"discount_type"`
+	DiscountValue int       `json:"discount_value"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+// PromoCode - промокод с ограничением числа использований
+type PromoCode struct {
+	ID            int        `json:"id"`
+	Code          string     `json:"code"`
+	ItemNames     []string   `json:"item_names"`
+	DiscountType  string     `json:"discount_type"`
+	DiscountValue int        `json:"discount_value"`
+	MaxUses       int        `json:"max_uses"`
+	Uses          int        `json:"uses"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// PriceQuote - итоговая цена покупки с учётом скидок
+type PriceQuote struct {
+	ListPrice   int  `json:"list_price"`
+	Discount    int  `json:"discount"`
+	Price       int  `json:"price"`
+	CampaignID  *int `json:"-"`
+	PromoCodeID *int `json:"-"`
+}
+
+// CreatePromoCodeRequest - запрос администратора на создание промокода. Пустой Code генерируется автоматически.
+type CreatePromoCodeRequest struct {
+	Code          string     `json:"code"`
+	ItemNames     []string   `json:"item_names"`
+	DiscountType  string     `json:"discount_type"`
+	DiscountValue int        `json:"discount_value"`
+	MaxUses       int        `json:"max_uses"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+// Типы уведомлений
+const (
+	NotificationPriceDrop  = "price_drop"
+	NotificationRestock    = "restock"
+	NotificationAffordable = "affordable"
+)
+
+// WishlistItem - товар в вишлисте с текущей ценой и недостающей суммой
+type WishlistItem struct {
+	Username    string    `json:"-"`
+	ItemName    string    `json:"item_name"`
+	ListPrice   int       `json:"list_price"`
+	Price       int       `json:"price"`
+	CoinsNeeded int       `json:"coins_needed"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// AddToWishlistRequest - запрос на добавление товара в вишлист
+type AddToWishlistRequest struct {
+	Item string `json:"item"`
+}
+
+// Notification - уведомление пользователя
+type Notification struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"-"`
+	Type      string    `json:"type"`
+	ItemName  string    `json:"item_name"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Review - отзыв пользователя о товаре с оценкой от 1 до 5
+type Review struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	ItemName  string    `json:"item_name"`
+	Rating    int       `json:"rating"`
+	Text      string    `json:"text"`
+	Hidden    bool      `json:"hidden"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReviewRequest - создание или изменение своего отзыва
+type ReviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// ModerateReviewRequest - скрытие отзыва администратором
+type ModerateReviewRequest struct {
+	Hidden bool `json:"hidden"`
+}
+
+// Статусы предзаказа
+const (
+	PreOrderStatusReserved  = "reserved"
+	PreOrderStatusCaptured  = "captured"
+	PreOrderStatusCancelled = "cancelled"
+	PreOrderStatusReleased  = "released"
+)
+
+// PreOrder - предзаказ товара, которого нет в наличии. Цена монет зарезервирована
+// и списывается при поступлении товара, тогда же создаётся заказ OrderID.
+type PreOrder struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"-"`
+	ItemName   string    `json:"item_name"`
+	Variant    string    `json:"variant"`
+	ListPrice  int       `json:"list_price"`
+	Discount   int       `json:"discount"`
+	Price      int       `json:"price"`
+	CampaignID *int      `json:"-"`
+	Status     string    `json:"status"`
+	OrderID    *int      `json:"order_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
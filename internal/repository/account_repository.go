@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// Личные данные, которые удаляются вместе с аккаунтом. Переводы, покупки, заказы, ставки и
+// погашенные промокоды остаются: они нужны в истории других пользователей и в учёте монет.
+var accountPersonalTables = []string{
+	"refresh_tokens",
+	"sessions",
+	"revoked_tokens",
+	"api_tokens",
+	"password_reset_tokens",
+	"user_totp",
+	"recovery_codes",
+	"user_identities",
+	"wishlist",
+	"notifications",
+	"reviews",
+	"inventory",
+}
+
+type AccountRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewAccountRepository(db *pgxpool.Pool, log *logrus.Logger) *AccountRepository {
+	return &AccountRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Выгрузка данных пользователя. Читается в одной транзакции, чтобы баланс и история были согласованы.
+func (r *AccountRepository) ExportAccount(username string) (*models.AccountExport, error) {
+	tx, err := r.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+		}
+	}()
+
+	export := &models.AccountExport{
+		CoinHistory: []models.Transaction{},
+		Purchases:   []models.Purchase{},
+		Inventory:   []models.InventoryItem{},
+	}
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`SELECT id, username, display_name, department, title, avatar_key, created_at, role, balance - reserved, reserved
+         FROM users WHERE username = $1 AND deleted_at IS NULL`, username).
+		Scan(&userID, &export.Profile.Username, &export.Profile.DisplayName, &export.Profile.Department, &export.Profile.Title,
+			&export.Profile.AvatarKey, &export.Profile.JoinedAt, &export.Profile.Role, &export.Profile.Coins, &export.Profile.Reserved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get profile of user %s: %v", username, err)
+		return nil, err
+	}
+
+	rows, err := tx.Query(context.Background(),
+		`SELECT t.id, uf.username, uf.display_name, uf.department, ut.username, ut.display_name, ut.department, t.amount, t.timestamp
+         FROM transactions t
+         JOIN users uf ON t.from_user = uf.id
+         JOIN users ut ON t.to_user = ut.id
+         WHERE t.from_user = $1 OR t.to_user = $1
+         ORDER BY t.timestamp, t.id`, userID)
+	if err != nil {
+		r.log.Errorf("Failed to export coin history of user %s: %v", username, err)
+		return nil, err
+	}
+	for rows.Next() {
+		var t models.Transaction
+		var from, to models.UserProfile
+		if err = rows.Scan(&t.ID, &from.Username, &from.DisplayName, &from.Department,
+			&to.Username, &to.DisplayName, &to.Department, &t.Amount, &t.Time); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.FromUser, t.FromName = from.Username, from.Label()
+		t.ToUser, t.ToName = to.Username, to.Label()
+		export.CoinHistory = append(export.CoinHistory, t)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(context.Background(),
+		`SELECT id, item_name, variant, list_price, discount, price, timestamp
+         FROM purchases WHERE user_id = $1 ORDER BY timestamp, id`, userID)
+	if err != nil {
+		r.log.Errorf("Failed to export purchases of user %s: %v", username, err)
+		return nil, err
+	}
+	for rows.Next() {
+		p := models.Purchase{UserID: userID}
+		if err = rows.Scan(&p.ID, &p.ItemName, &p.Variant, &p.ListPrice, &p.Discount, &p.Price, &p.Time); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Purchases = append(export.Purchases, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(context.Background(),
+		"SELECT item_type, variant, quantity FROM inventory WHERE user_id = $1 ORDER BY item_type, variant", userID)
+	if err != nil {
+		r.log.Errorf("Failed to export inventory of user %s: %v", username, err)
+		return nil, err
+	}
+	for rows.Next() {
+		var item models.InventoryItem
+		if err = rows.Scan(&item.Type, &item.Variant, &item.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Inventory = append(export.Inventory, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Удаление аккаунта: имя заменяется на deleted#<id> (такое имя нельзя зарегистрировать), пароль, роль и профиль
+// сбрасываются, личные данные удаляются. Строка пользователя остаётся для внешних ключей переводов и покупок.
+// Пока под предзаказы зарезервированы монеты или удерживаются ставки, возвращается ErrAccountHasPendingOperations.
+// Возвращает ключ аватара, файл которого нужно удалить из хранилища.
+func (r *AccountRepository) DeleteAccount(username string, now time.Time) (avatarKey string, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID, reserved int
+	err = tx.QueryRow(context.Background(),
+		"SELECT id, reserved, avatar_key FROM users WHERE username = $1 AND deleted_at IS NULL FOR UPDATE", username).
+		Scan(&userID, &reserved, &avatarKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrUserNotFound
+		return "", err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock user %s: %v", username, err)
+		return "", err
+	}
+
+	var heldBids bool
+	if err = tx.QueryRow(context.Background(),
+		"SELECT EXISTS(SELECT 1 FROM auction_bids WHERE user_id = $1 AND status = $2)", userID, models.BidStatusHeld).
+		Scan(&heldBids); err != nil {
+		r.log.Errorf("Failed to check auction bids of user %s: %v", username, err)
+		return "", err
+	}
+	if reserved > 0 || heldBids {
+		err = models.ErrAccountHasPendingOperations
+		return "", err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		`UPDATE users SET username = 'deleted#' || id, password = '', role = 'user',
+             display_name = '', department = '', title = '', avatar_key = '',
+             deleted_at = $2, tokens_revoked_before = $2
+         WHERE id = $1`, userID, now); err != nil {
+		r.log.Errorf("Failed to anonymise user %s: %v", username, err)
+		return "", err
+	}
+	for _, table := range accountPersonalTables {
+		if _, err = tx.Exec(context.Background(), "DELETE FROM "+table+" WHERE user_id = $1", userID); err != nil {
+			r.log.Errorf("Failed to delete %s of user %s: %v", table, username, err)
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return "", err
+	}
+	return avatarKey, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type APITokenRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewAPITokenRepository(db *pgxpool.Pool, log *logrus.Logger) *APITokenRepository {
+	return &APITokenRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Сохранение нового токена. Имя уникально среди токенов пользователя - иначе ErrAPITokenNameTaken.
+func (r *APITokenRepository) CreateAPIToken(token models.APIToken, tokenHash string) (*models.APIToken, error) {
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+         SELECT id, $2, $3, $4, $5 FROM users WHERE username = $1
+         RETURNING id, created_at`,
+		token.Username, token.Name, tokenHash, token.Scopes, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, models.ErrAPITokenNameTaken
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to create API token for user %s: %v", token.Username, err)
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Токены пользователя, включая истёкшие, новые первыми
+func (r *APITokenRepository) ListAPITokens(username string) ([]models.APIToken, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT t.id, t.name, t.scopes, t.expires_at, t.last_used_at, t.created_at
+         FROM api_tokens t JOIN users u ON t.user_id = u.id
+         WHERE u.username = $1
+         ORDER BY t.created_at DESC, t.id DESC`, username)
+	if err != nil {
+		r.log.Errorf("Failed to list API tokens of user %s: %v", username, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token := models.APIToken{Username: username}
+		if err = rows.Scan(&token.ID, &token.Name, &token.Scopes, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt); err != nil {
+			r.log.Errorf("Failed to scan API token: %v", err)
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Токен по хэшу вместе с именем владельца; срок действия проверяет сервис
+func (r *APITokenRepository) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.QueryRow(context.Background(),
+		`SELECT t.id, u.username, t.name, t.scopes, t.expires_at, t.last_used_at, t.created_at
+         FROM api_tokens t JOIN users u ON t.user_id = u.id
+         WHERE t.token_hash = $1`, tokenHash).
+		Scan(&token.ID, &token.Username, &token.Name, &token.Scopes, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrInvalidAPIToken
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get API token: %v", err)
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *APITokenRepository) TouchAPIToken(id int, at time.Time) error {
+	_, err := r.db.Exec(context.Background(), "UPDATE api_tokens SET last_used_at = $2 WHERE id = $1", id, at)
+	if err != nil {
+		r.log.Errorf("Failed to update last use of API token %d: %v", id, err)
+	}
+	return err
+}
+
+// Отзыв токена его владельцем: токен удаляется
+func (r *APITokenRepository) DeleteAPIToken(username string, id int) error {
+	tag, err := r.db.Exec(context.Background(),
+		"DELETE FROM api_tokens WHERE id = $1 AND user_id = (SELECT id FROM users WHERE username = $2)", id, username)
+	if err != nil {
+		r.log.Errorf("Failed to delete API token %d of user %s: %v", id, username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrAPITokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type AuctionRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewAuctionRepository(db *pgxpool.Pool, log *logrus.Logger) *AuctionRepository {
+	return &AuctionRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const auctionSelect = `SELECT a.id, a.item_name, a.reserve_price, a.starts_at, a.ends_at, a.status,
+       COALESCE(u.username, ''), COALESCE(a.final_price, 0),
+       COALESCE((SELECT MAX(b.amount) FROM auction_bids b WHERE b.auction_id = a.id AND b.status = 'held'), 0)
+FROM auctions a
+LEFT JOIN users u ON a.winner_id = u.id`
+
+func scanAuction(row pgx.Row) (*models.Auction, error) {
+	var a models.Auction
+	err := row.Scan(&a.ID, &a.ItemName, &a.ReservePrice, &a.StartsAt, &a.EndsAt, &a.Status,
+		&a.Winner, &a.FinalPrice, &a.HighestBid)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AuctionRepository) CreateAuction(auction models.Auction) (int, error) {
+	var id int
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO auctions (item_name, reserve_price, starts_at, ends_at, status)
+         VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		auction.ItemName, auction.ReservePrice, auction.StartsAt, auction.EndsAt, models.AuctionStatusOpen).Scan(&id)
+	if err != nil {
+		r.log.Errorf("Failed to create auction for item %s: %v", auction.ItemName, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *AuctionRepository) GetAuction(id int) (*models.Auction, error) {
+	auction, err := scanAuction(r.db.QueryRow(context.Background(), auctionSelect+" WHERE a.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrAuctionNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get auction %d: %v", id, err)
+		return nil, err
+	}
+	return auction, nil
+}
+
+func (r *AuctionRepository) ListOpenAuctions() ([]models.Auction, error) {
+	rows, err := r.db.Query(context.Background(),
+		auctionSelect+" WHERE a.status = $1 ORDER BY a.ends_at", models.AuctionStatusOpen)
+	if err != nil {
+		r.log.Errorf("Failed to fetch open auctions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var auctions []models.Auction
+	for rows.Next() {
+		auction, err := scanAuction(rows)
+		if err != nil {
+			r.log.Errorf("Failed to scan auction: %v", err)
+			return nil, err
+		}
+		auctions = append(auctions, *auction)
+	}
+	return auctions, rows.Err()
+}
+
+func (r *AuctionRepository) GetBids(auctionID int) ([]models.AuctionBid, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT b.id, b.auction_id, u.username, b.amount, b.status, b.timestamp
+         FROM auction_bids b
+         JOIN users u ON b.user_id = u.id
+         WHERE b.auction_id = $1
+         ORDER BY b.amount DESC`, auctionID)
+	if err != nil {
+		r.log.Errorf("Failed to fetch bids for auction %d: %v", auctionID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bids []models.AuctionBid
+	for rows.Next() {
+		var bid models.AuctionBid
+		if err = rows.Scan(&bid.ID, &bid.AuctionID, &bid.Username, &bid.Amount, &bid.Status, &bid.Time); err != nil {
+			r.log.Errorf("Failed to scan bid for auction %d: %v", auctionID, err)
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
+
+// Ставка: монеты нового лидера удерживаются, предыдущему лидеру сразу возвращаются
+func (r *AuctionRepository) PlaceBid(auctionID int, username string, amount int, now time.Time) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	// Блокируем аукцион, чтобы ставки и закрытие шли строго последовательно
+	var status string
+	var startsAt, endsAt time.Time
+	err = tx.QueryRow(context.Background(),
+		"SELECT status, starts_at, ends_at FROM auctions WHERE id = $1 FOR UPDATE", auctionID).
+		Scan(&status, &startsAt, &endsAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrAuctionNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock auction %d: %v", auctionID, err)
+		return err
+	}
+	if status != models.AuctionStatusOpen || now.Before(startsAt) || !now.Before(endsAt) {
+		return models.ErrAuctionNotActive
+	}
+
+	var userID int
+	err = tx.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	if err != nil {
+		r.log.Errorf("Failed to get user ID for username %s: %v", username, err)
+		return err
+	}
+
+	// Текущая лидирующая ставка (удерживается не более одной)
+	var prevBidID, prevUserID, prevAmount int
+	err = tx.QueryRow(context.Background(),
+		"SELECT id, user_id, amount FROM auction_bids WHERE auction_id = $1 AND status = $2",
+		auctionID, models.BidStatusHeld).Scan(&prevBidID, &prevUserID, &prevAmount)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.log.Errorf("Failed to get highest bid for auction %d: %v", auctionID, err)
+		return err
+	}
+	hasPrev := err == nil
+	err = nil
+	if hasPrev && amount <= prevAmount {
+		return models.ErrBidTooLow
+	}
+
+	// Возвращаем монеты перебитому участнику
+	if hasPrev {
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE users SET balance = balance + $1 WHERE id = $2", prevAmount, prevUserID); err != nil {
+			r.log.Errorf("Failed to refund outbid user %d: %v", prevUserID, err)
+			return err
+		}
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE auction_bids SET status = $1 WHERE id = $2", models.BidStatusRefunded, prevBidID); err != nil {
+			r.log.Errorf("Failed to mark bid %d as refunded: %v", prevBidID, err)
+			return err
+		}
+	}
+
+	// Удерживаем монеты нового лидера
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE users SET balance = balance - $1 WHERE id = $2 AND balance - reserved >= $1", amount, userID)
+	if err != nil {
+		r.log.Errorf("Failed to hold coins for user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrInsufficientBalance
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO auction_bids (auction_id, user_id, amount, status) VALUES ($1, $2, $3, $4)",
+		auctionID, userID, amount, models.BidStatusHeld); err != nil {
+		r.log.Errorf("Failed to insert bid for user %s: %v", username, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit bid for user %s: %v", username, err)
+		return err
+	}
+	r.log.Infof("Bid placed: auction=%d, user=%s, amount=%d", auctionID, username, amount)
+	return nil
+}
+
+func (r *AuctionRepository) GetDueAuctionIDs(now time.Time) ([]int, error) {
+	rows, err := r.db.Query(context.Background(),
+		"SELECT id FROM auctions WHERE status = $1 AND ends_at <= $2 ORDER BY ends_at",
+		models.AuctionStatusOpen, now)
+	if err != nil {
+		r.log.Errorf("Failed to fetch due auctions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			r.log.Errorf("Failed to scan due auction: %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Закрытие аукциона: монеты победителя списываются, товар попадает в инвентарь.
+// Если резервная цена не достигнута, удержанные монеты возвращаются.
+// Повторный вызов для уже закрытого аукциона ничего не делает.
+func (r *AuctionRepository) CloseAuction(auctionID int) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var itemName, status string
+	var reservePrice int
+	err = tx.QueryRow(context.Background(),
+		"SELECT item_name, reserve_price, status FROM auctions WHERE id = $1 FOR UPDATE", auctionID).
+		Scan(&itemName, &reservePrice, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrAuctionNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock auction %d: %v", auctionID, err)
+		return err
+	}
+	if status != models.AuctionStatusOpen {
+		return tx.Rollback(context.Background())
+	}
+
+	var bidID, userID, amount int
+	err = tx.QueryRow(context.Background(),
+		"SELECT id, user_id, amount FROM auction_bids WHERE auction_id = $1 AND status = $2",
+		auctionID, models.BidStatusHeld).Scan(&bidID, &userID, &amount)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.log.Errorf("Failed to get winning bid for auction %d: %v", auctionID, err)
+		return err
+	}
+	hasBid := err == nil
+	err = nil
+
+	switch {
+	case !hasBid:
+		r.log.Infof("Auction %d closed without bids", auctionID)
+	case amount < reservePrice:
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE users SET balance = balance + $1 WHERE id = $2", amount, userID); err != nil {
+			r.log.Errorf("Failed to refund bid %d: %v", bidID, err)
+			return err
+		}
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE auction_bids SET status = $1 WHERE id = $2", models.BidStatusRefunded, bidID); err != nil {
+			r.log.Errorf("Failed to mark bid %d as refunded: %v", bidID, err)
+			return err
+		}
+		r.log.Infof("Auction %d closed below reserve price: %d < %d", auctionID, amount, reservePrice)
+	default:
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE auction_bids SET status = $1 WHERE id = $2", models.BidStatusCaptured, bidID); err != nil {
+			r.log.Errorf("Failed to capture bid %d: %v", bidID, err)
+			return err
+		}
+		var purchaseID int
+		if err = tx.QueryRow(context.Background(),
+			"INSERT INTO purchases (user_id, item_name, list_price, price) VALUES ($1, $2, $3, $3) RETURNING id",
+			userID, itemName, amount).Scan(&purchaseID); err != nil {
+			r.log.Errorf("Failed to insert purchase record for auction %d: %v", auctionID, err)
+			return err
+		}
+		if _, err = createOrder(tx, purchaseID, userID, itemName, "auction"); err != nil {
+			r.log.Errorf("Failed to create order for auction %d: %v", auctionID, err)
+			return err
+		}
+		if _, err = tx.Exec(context.Background(),
+			`INSERT INTO inventory (user_id, item_type, quantity)
+             VALUES ($1, $2, 1)
+             ON CONFLICT (user_id, item_type, variant)
+             DO UPDATE SET quantity = inventory.quantity + 1`,
+			userID, itemName); err != nil {
+			r.log.Errorf("Failed to update inventory for auction %d: %v", auctionID, err)
+			return err
+		}
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE auctions SET winner_id = $1, final_price = $2 WHERE id = $3",
+			userID, amount, auctionID); err != nil {
+			r.log.Errorf("Failed to set winner for auction %d: %v", auctionID, err)
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE auctions SET status = $1 WHERE id = $2", models.AuctionStatusClosed, auctionID); err != nil {
+		r.log.Errorf("Failed to close auction %d: %v", auctionID, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit closing of auction %d: %v", auctionID, err)
+		return fmt.Errorf("failed to close auction: %w", err)
+	}
+	r.log.Infof("Auction %d closed", auctionID)
+	return nil
+}
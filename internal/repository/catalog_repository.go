@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type CatalogRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewCatalogRepository(db *pgxpool.Pool, log *logrus.Logger) *CatalogRepository {
+	return &CatalogRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Средняя оценка считается только по видимым отзывам
+const catalogItemSelect = `SELECT c.name, c.price, c.description, c.category, c.tags,
+       COALESCE(c.image_key, ''), COALESCE(c.thumbnail_key, ''), c.variant_axes,
+       r.rating, COALESCE(r.review_count, 0)
+FROM catalog_items c
+LEFT JOIN (
+    SELECT item_name, ROUND(AVG(rating), 1)::float8 AS rating, COUNT(*) AS review_count
+    FROM reviews WHERE NOT hidden GROUP BY item_name
+) r ON r.item_name = c.name`
+
+func (r *CatalogRepository) queryItems(query string, args ...any) ([]models.CatalogItem, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		r.log.Errorf("Failed to fetch catalog items: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.CatalogItem
+	for rows.Next() {
+		var item models.CatalogItem
+		if err = rows.Scan(&item.Name, &item.Price, &item.Description, &item.Category, &item.Tags,
+			&item.ImageKey, &item.ThumbKey, &item.VariantAxes,
+			&item.Rating, &item.ReviewCount); err != nil {
+			r.log.Errorf("Failed to scan catalog item: %v", err)
+			return nil, err
+		}
+		if item.ImageKey != "" {
+			item.ImageURL = models.ImagesPath + item.ImageKey
+			item.ThumbURL = models.ImagesPath + item.ThumbKey
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].Variants, err = r.getVariants(items[i].Name); err != nil {
+			return nil, err
+		}
+		if items[i].Components, err = r.getComponents(items[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (r *CatalogRepository) getComponents(bundleName string) ([]models.BundleComponent, error) {
+	rows, err := r.db.Query(context.Background(),
+		"SELECT item_name, COALESCE(variant, ''), quantity FROM catalog_bundle_items WHERE bundle_name = $1 ORDER BY id",
+		bundleName)
+	if err != nil {
+		r.log.Errorf("Failed to fetch components of bundle %s: %v", bundleName, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []models.BundleComponent
+	for rows.Next() {
+		var component models.BundleComponent
+		if err = rows.Scan(&component.ItemName, &component.Variant, &component.Quantity); err != nil {
+			r.log.Errorf("Failed to scan component of bundle %s: %v", bundleName, err)
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// Вариант default у товара с осями - след покупок, сделанных до появления вариантов, в каталоге он не показывается
+func (r *CatalogRepository) getVariants(itemName string) ([]models.CatalogVariant, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT v.variant, v.attributes, v.stock
+         FROM catalog_variants v
+         JOIN catalog_items i ON i.name = v.item_name
+         WHERE v.item_name = $1 AND (v.variant <> 'default' OR i.variant_axes = '{}')
+         ORDER BY v.id`, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to fetch variants for item %s: %v", itemName, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []models.CatalogVariant
+	for rows.Next() {
+		var variant models.CatalogVariant
+		if err = rows.Scan(&variant.Key, &variant.Attributes, &variant.Stock); err != nil {
+			r.log.Errorf("Failed to scan variant for item %s: %v", itemName, err)
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+	return variants, rows.Err()
+}
+
+// Порядок выдачи для каждого варианта сортировки
+var catalogOrders = map[string]string{
+	models.SortByName:      "c.name",
+	models.SortByPrice:     "c.price, c.name",
+	models.SortByPriceDesc: "c.price DESC, c.name",
+	models.SortByRating:    "r.rating DESC NULLS LAST, r.review_count DESC NULLS LAST, c.name",
+}
+
+// Поиск по каталогу: страница товаров и общее число найденных.
+// Без явной сортировки результаты полнотекстового поиска упорядочены по релевантности.
+func (r *CatalogRepository) SearchItems(query models.CatalogQuery) ([]models.CatalogItem, int, error) {
+	var conditions []string
+	var args []any
+	if query.Query != "" {
+		args = append(args, query.Query)
+		conditions = append(conditions, fmt.Sprintf("c.search_vector @@ plainto_tsquery('simple', $%d)", len(args)))
+	}
+	if query.Category != "" {
+		args = append(args, query.Category)
+		conditions = append(conditions, fmt.Sprintf("c.category = $%d", len(args)))
+	}
+	if query.Tag != "" {
+		args = append(args, query.Tag)
+		conditions = append(conditions, fmt.Sprintf("$%d = ANY(c.tags)", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM catalog_items c"+where, args...).Scan(&total); err != nil {
+		r.log.Errorf("Failed to count catalog items: %v", err)
+		return nil, 0, err
+	}
+
+	order, ok := catalogOrders[query.Sort]
+	if !ok {
+		order = catalogOrders[models.SortByName]
+		if query.Query != "" {
+			order = "ts_rank(c.search_vector, plainto_tsquery('simple', $1)) DESC, c.name"
+		}
+	}
+	args = append(args, query.PerPage, (query.Page-1)*query.PerPage)
+	items, err := r.queryItems(
+		fmt.Sprintf("%s%s ORDER BY %s LIMIT $%d OFFSET $%d", catalogItemSelect, where, order, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *CatalogRepository) ListCategories() ([]models.Category, error) {
+	rows, err := r.db.Query(context.Background(),
+		"SELECT category, COUNT(*) FROM catalog_items GROUP BY category ORDER BY category")
+	if err != nil {
+		r.log.Errorf("Failed to fetch categories: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var category models.Category
+		if err = rows.Scan(&category.Name, &category.ItemCount); err != nil {
+			r.log.Errorf("Failed to scan category: %v", err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *CatalogRepository) GetItem(name string) (*models.CatalogItem, error) {
+	items, err := r.queryItems(catalogItemSelect+" WHERE c.name = $1", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, models.ErrItemNotFound
+	}
+	return &items[0], nil
+}
+
+// Добавление варианта товара или изменение его остатка
+func (r *CatalogRepository) UpsertVariant(itemName string, variant models.CatalogVariant) error {
+	_, err := r.db.Exec(context.Background(),
+		`INSERT INTO catalog_variants (item_name, variant, attributes, stock)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (item_name, variant)
+         DO UPDATE SET stock = EXCLUDED.stock`,
+		itemName, variant.Key, variant.Attributes, variant.Stock)
+	if err != nil {
+		r.log.Errorf("Failed to upsert variant %s of item %s: %v", variant.Key, itemName, err)
+	}
+	return err
+}
+
+func (r *CatalogRepository) SetItemImage(itemName, imageKey, thumbKey string) error {
+	tag, err := r.db.Exec(context.Background(),
+		"UPDATE catalog_items SET image_key = $1, thumbnail_key = $2 WHERE name = $3",
+		imageKey, thumbKey, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to set image of item %s: %v", itemName, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrItemNotFound
+	}
+	return nil
+}
+
+// Создание набора вместе с его вариантами и составом
+func (r *CatalogRepository) CreateBundle(bundle models.CatalogItem) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	tag, err := tx.Exec(context.Background(),
+		`INSERT INTO catalog_items (name, price, description, category, tags, variant_axes)
+         VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (name) DO NOTHING`,
+		bundle.Name, bundle.Price, bundle.Description, bundle.Category, bundle.Tags, bundle.VariantAxes)
+	if err != nil {
+		r.log.Errorf("Failed to create bundle %s: %v", bundle.Name, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: item %s already exists", models.ErrInvalidBundle, bundle.Name)
+	}
+
+	for _, variant := range bundle.Variants {
+		if _, err = tx.Exec(context.Background(),
+			"INSERT INTO catalog_variants (item_name, variant, attributes, stock) VALUES ($1, $2, $3, $4)",
+			bundle.Name, variant.Key, variant.Attributes, variant.Stock); err != nil {
+			r.log.Errorf("Failed to create variant %s of bundle %s: %v", variant.Key, bundle.Name, err)
+			return err
+		}
+	}
+
+	for _, component := range bundle.Components {
+		if _, err = tx.Exec(context.Background(),
+			"INSERT INTO catalog_bundle_items (bundle_name, item_name, variant, quantity) VALUES ($1, $2, NULLIF($3, ''), $4)",
+			bundle.Name, component.ItemName, component.Variant, component.Quantity); err != nil {
+			r.log.Errorf("Failed to add %s to bundle %s: %v", component.ItemName, bundle.Name, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit bundle %s: %v", bundle.Name, err)
+		return err
+	}
+	r.log.Infof("Bundle %s created with %d components", bundle.Name, len(bundle.Components))
+	return nil
+}
+
+func (r *CatalogRepository) AddPriceChange(change models.PriceChange) (int, error) {
+	var id int
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO catalog_price_history (item_name, price, effective_at, changed_by)
+         VALUES ($1, $2, $3, $4) RETURNING id`,
+		change.ItemName, change.Price, change.EffectiveAt, change.ChangedBy).Scan(&id)
+	if err != nil {
+		r.log.Errorf("Failed to add price change for %s: %v", change.ItemName, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// История цен товара вместе с запланированными изменениями, по времени вступления в силу
+func (r *CatalogRepository) GetPriceHistory(itemName string) ([]models.PriceChange, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT id, item_name, price, effective_at, changed_by, applied
+         FROM catalog_price_history WHERE item_name = $1
+         ORDER BY effective_at, id`, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to fetch price history of %s: %v", itemName, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.PriceChange
+	for rows.Next() {
+		var change models.PriceChange
+		err = rows.Scan(&change.ID, &change.ItemName, &change.Price, &change.EffectiveAt, &change.ChangedBy, &change.Applied)
+		if err != nil {
+			r.log.Errorf("Failed to scan price change of %s: %v", itemName, err)
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// Отмена запланированного изменения. Применённые изменения остаются в истории.
+func (r *CatalogRepository) DeletePendingPriceChange(id int) error {
+	tag, err := r.db.Exec(context.Background(),
+		"DELETE FROM catalog_price_history WHERE id = $1 AND NOT applied", id)
+	if err != nil {
+		r.log.Errorf("Failed to delete price change %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrPriceChangeNotFound
+	}
+	return nil
+}
+
+// Перенос наступивших изменений в каталог. Изменения одного товара применяются по порядку,
+// SKIP LOCKED позволяет нескольким экземплярам сервиса не мешать друг другу.
+func (r *CatalogRepository) ApplyDuePriceChanges(now time.Time) (applied []models.PriceChange, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	rows, err := tx.Query(context.Background(),
+		`SELECT id, item_name, price, effective_at, changed_by
+         FROM catalog_price_history
+         WHERE NOT applied AND effective_at <= $1
+         ORDER BY effective_at, id
+         FOR UPDATE SKIP LOCKED`, now)
+	if err != nil {
+		r.log.Errorf("Failed to fetch due price changes: %v", err)
+		return nil, err
+	}
+	for rows.Next() {
+		change := models.PriceChange{Applied: true}
+		if err = rows.Scan(&change.ID, &change.ItemName, &change.Price, &change.EffectiveAt, &change.ChangedBy); err != nil {
+			rows.Close()
+			r.log.Errorf("Failed to scan due price change: %v", err)
+			return nil, err
+		}
+		applied = append(applied, change)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, change := range applied {
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE catalog_items SET price = $1 WHERE name = $2", change.Price, change.ItemName); err != nil {
+			r.log.Errorf("Failed to update price of %s: %v", change.ItemName, err)
+			return nil, err
+		}
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE catalog_price_history SET applied = TRUE WHERE id = $1", change.ID); err != nil {
+			r.log.Errorf("Failed to mark price change %d as applied: %v", change.ID, err)
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit price changes: %v", err)
+		return nil, err
+	}
+	return applied, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type DiscountRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewDiscountRepository(db *pgxpool.Pool, log *logrus.Logger) *DiscountRepository {
+	return &DiscountRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *DiscountRepository) CreateCampaign(campaign models.Campaign) (int, error) {
+	var id int
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO campaigns (name, item_names, discount_type, discount_value, starts_at, ends_at)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		campaign.Name, campaign.ItemNames, campaign.DiscountType, campaign.DiscountValue,
+		campaign.StartsAt, campaign.EndsAt).Scan(&id)
+	if err != nil {
+		r.log.Errorf("Failed to create campaign %s: %v", campaign.Name, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *DiscountRepository) queryCampaigns(query string, args ...any) ([]models.Campaign, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		r.log.Errorf("Failed to fetch campaigns: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []models.Campaign
+	for rows.Next() {
+		var c models.Campaign
+		if err = rows.Scan(&c.ID, &c.Name, &c.ItemNames, &c.DiscountType, &c.DiscountValue, &c.StartsAt, &c.EndsAt); err != nil {
+			r.log.Errorf("Failed to scan campaign: %v", err)
+			return nil, err
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+func (r *DiscountRepository) ListCampaigns() ([]models.Campaign, error) {
+	return r.queryCampaigns(
+		`SELECT id, name, item_names, discount_type, discount_value, starts_at, ends_at
+         FROM campaigns ORDER BY starts_at DESC`)
+}
+
+// Кампании, действующие на товар в момент at
+func (r *DiscountRepository) GetActiveCampaigns(itemName string, at time.Time) ([]models.Campaign, error) {
+	return r.queryCampaigns(
+		`SELECT id, name, item_names, discount_type, discount_value, starts_at, ends_at
+         FROM campaigns
+         WHERE starts_at <= $2 AND ends_at > $2 AND (item_names = '{}' OR $1 = ANY(item_names))`,
+		itemName, at)
+}
+
+func (r *DiscountRepository) ListActiveCampaigns(at time.Time) ([]models.Campaign, error) {
+	return r.queryCampaigns(
+		`SELECT id, name, item_names, discount_type, discount_value, starts_at, ends_at
+         FROM campaigns
+         WHERE starts_at <= $1 AND ends_at > $1`,
+		at)
+}
+
+func (r *DiscountRepository) CreatePromoCode(code models.PromoCode) (int, error) {
+	var id int
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO promo_codes (code, item_names, discount_type, discount_value, max_uses, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		code.Code, code.ItemNames, code.DiscountType, code.DiscountValue, code.MaxUses, code.ExpiresAt).Scan(&id)
+	if err != nil {
+		r.log.Errorf("Failed to create promo code: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+const promoCodeSelect = `SELECT id, code, item_names, discount_type, discount_value, max_uses, uses, expires_at FROM promo_codes`
+
+func scanPromoCode(row pgx.Row) (*models.PromoCode, error) {
+	var p models.PromoCode
+	err := row.Scan(&p.ID, &p.Code, &p.ItemNames, &p.DiscountType, &p.DiscountValue, &p.MaxUses, &p.Uses, &p.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *DiscountRepository) GetPromoCode(code string) (*models.PromoCode, error) {
+	promo, err := scanPromoCode(r.db.QueryRow(context.Background(), promoCodeSelect+" WHERE code = $1", code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrPromoCodeNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get promo code: %v", err)
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (r *DiscountRepository) ListPromoCodes() ([]models.PromoCode, error) {
+	rows, err := r.db.Query(context.Background(), promoCodeSelect+" ORDER BY id DESC")
+	if err != nil {
+		r.log.Errorf("Failed to fetch promo codes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.PromoCode
+	for rows.Next() {
+		promo, err := scanPromoCode(rows)
+		if err != nil {
+			r.log.Errorf("Failed to scan promo code: %v", err)
+			return nil, err
+		}
+		codes = append(codes, *promo)
+	}
+	return codes, rows.Err()
+}
@@ -30,7 +30,7 @@ func (r *InventoryRepository) GetInventory(username string) ([]models.InventoryI
 	}
 
 	rows, err := r.db.Query(context.Background(),
-		"SELECT item_type, quantity FROM inventory WHERE user_id = (SELECT id FROM users WHERE username=$1)", username)
+		"SELECT item_type, variant, quantity FROM inventory WHERE user_id = (SELECT id FROM users WHERE username=$1) ORDER BY item_type, variant", username)
 	if err != nil {
 		r.log.Errorf("Error fetching inventory: %v", err)
 		return nil, err
@@ -40,7 +40,7 @@ func (r *InventoryRepository) GetInventory(username string) ([]models.InventoryI
 	var inventory []models.InventoryItem
 	for rows.Next() {
 		var item models.InventoryItem
-		if err = rows.Scan(&item.Type, &item.Quantity); err != nil {
+		if err = rows.Scan(&item.Type, &item.Variant, &item.Quantity); err != nil {
 			r.log.Errorf("Error scanning inventory row: %v", err)
 			continue
 		}
@@ -66,7 +66,7 @@ func (r *InventoryRepository) AddToInventory(username, itemType string, quantity
 	_, err = r.db.Exec(context.Background(),
 		`INSERT INTO inventory (user_id, item_type, quantity)
          VALUES ($1, $2, $3)
-         ON CONFLICT (user_id, item_type)
+         ON CONFLICT (user_id, item_type, variant)
          DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity`,
 		userID, itemType, quantity)
 
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// LoginAttemptRepository хранит неудачные попытки входа в Postgres, счётчики общие для всех реплик
+type LoginAttemptRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool, log *logrus.Logger) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Строка счётчика блокируется до конца транзакции, поэтому параллельные попытки резервируются по очереди
+// и каждая видит неудачи, зарезервированные до неё
+func (r *LoginAttemptRepository) ReserveLoginAttempt(key string, at, since time.Time) (previous *models.LoginAttempts, err error) {
+	// Postgres хранит время с точностью до микросекунд, ReleaseLoginAttempt сравнивает его с at
+	at = at.Truncate(time.Microsecond)
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 0, $2) ON CONFLICT (key) DO NOTHING",
+		key, at); err != nil {
+		r.log.Errorf("Failed to reserve login attempt for %s: %v", key, err)
+		return nil, err
+	}
+	attempts := models.LoginAttempts{Key: key}
+	if err = tx.QueryRow(context.Background(),
+		"SELECT failures, last_failure_at FROM login_attempts WHERE key = $1 FOR UPDATE", key).
+		Scan(&attempts.Failures, &attempts.LastFailureAt); err != nil {
+		r.log.Errorf("Failed to lock login attempts for %s: %v", key, err)
+		return nil, err
+	}
+	if _, err = tx.Exec(context.Background(),
+		`UPDATE login_attempts SET
+             failures = CASE WHEN last_failure_at < $3 THEN 1 ELSE failures + 1 END,
+             last_failure_at = $2
+         WHERE key = $1`, key, at, since); err != nil {
+		r.log.Errorf("Failed to reserve login attempt for %s: %v", key, err)
+		return nil, err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for %s: %v", key, err)
+		return nil, err
+	}
+	if attempts.Failures == 0 || attempts.LastFailureAt.Before(since) {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) ReleaseLoginAttempt(key string, at time.Time, previous *models.LoginAttempts) error {
+	var previousAt *time.Time
+	if previous != nil {
+		previousAt = &previous.LastFailureAt
+	}
+	_, err := r.db.Exec(context.Background(),
+		`WITH released AS (
+             UPDATE login_attempts SET
+                 failures = failures - 1,
+                 last_failure_at = CASE WHEN last_failure_at = $2 AND $3::timestamp IS NOT NULL THEN $3 ELSE last_failure_at END
+             WHERE key = $1 AND failures > 0
+             RETURNING key, failures
+         )
+         DELETE FROM login_attempts WHERE key IN (SELECT key FROM released WHERE failures = 0)`,
+		key, at.Truncate(time.Microsecond), previousAt)
+	if err != nil {
+		r.log.Errorf("Failed to release login attempt for %s: %v", key, err)
+	}
+	return err
+}
+
+func (r *LoginAttemptRepository) ResetLoginAttempts(key string) error {
+	_, err := r.db.Exec(context.Background(), "DELETE FROM login_attempts WHERE key = $1", key)
+	if err != nil {
+		r.log.Errorf("Failed to reset login attempts for %s: %v", key, err)
+	}
+	return err
+}
+
+// Удаление счётчиков, по которым давно не было неудачных попыток
+func (r *LoginAttemptRepository) DeleteStaleLoginAttempts(before time.Time) (int64, error) {
+	tag, err := r.db.Exec(context.Background(), "DELETE FROM login_attempts WHERE last_failure_at < $1", before)
+	if err != nil {
+		r.log.Errorf("Failed to delete stale login attempts: %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// MemoryLoginAttemptRepository хранит неудачные попытки входа в памяти процесса.
+// Подходит для одного экземпляра сервиса: счётчики не разделяются между репликами и теряются при перезапуске.
+type MemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempts
+}
+
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{attempts: map[string]models.LoginAttempts{}}
+}
+
+func (r *MemoryLoginAttemptRepository) ReserveLoginAttempt(key string, at, since time.Time) (*models.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var previous *models.LoginAttempts
+	attempts, ok := r.attempts[key]
+	if ok && !attempts.LastFailureAt.Before(since) {
+		copied := attempts
+		previous = &copied
+	} else {
+		attempts = models.LoginAttempts{Key: key}
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = at
+	r.attempts[key] = attempts
+	return previous, nil
+}
+
+func (r *MemoryLoginAttemptRepository) ReleaseLoginAttempt(key string, at time.Time, previous *models.LoginAttempts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts, ok := r.attempts[key]
+	if !ok || attempts.Failures == 0 {
+		return nil
+	}
+	attempts.Failures--
+	if attempts.Failures == 0 {
+		delete(r.attempts, key)
+		return nil
+	}
+	if previous != nil && attempts.LastFailureAt.Equal(at) {
+		attempts.LastFailureAt = previous.LastFailureAt
+	}
+	r.attempts[key] = attempts
+	return nil
+}
+
+func (r *MemoryLoginAttemptRepository) ResetLoginAttempts(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *MemoryLoginAttemptRepository) DeleteStaleLoginAttempts(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for key, attempts := range r.attempts {
+		if attempts.LastFailureAt.Before(before) {
+			delete(r.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
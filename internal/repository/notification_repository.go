@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewNotificationRepository(db *pgxpool.Pool, log *logrus.Logger) *NotificationRepository {
+	return &NotificationRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Пакетное создание уведомлений, получатель определяется по Username
+func (r *NotificationRepository) CreateNotifications(notifications []models.Notification) error {
+	batch := &pgx.Batch{}
+	for _, n := range notifications {
+		batch.Queue(
+			`INSERT INTO notifications (user_id, type, item_name, message)
+             SELECT id, $2, $3, $4 FROM users WHERE username = $1`,
+			n.Username, n.Type, n.ItemName, n.Message)
+	}
+	if err := r.db.SendBatch(context.Background(), batch).Close(); err != nil {
+		r.log.Errorf("Failed to create %d notifications: %v", len(notifications), err)
+		return err
+	}
+	return nil
+}
+
+func (r *NotificationRepository) GetNotifications(username string, unreadOnly bool) ([]models.Notification, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT n.id, n.type, n.item_name, n.message, n.is_read, n.created_at
+         FROM notifications n
+         JOIN users u ON n.user_id = u.id
+         WHERE u.username = $1 AND (NOT $2 OR NOT n.is_read)
+         ORDER BY n.created_at DESC, n.id DESC`, username, unreadOnly)
+	if err != nil {
+		r.log.Errorf("Failed to fetch notifications for user %s: %v", username, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err = rows.Scan(&n.ID, &n.Type, &n.ItemName, &n.Message, &n.Read, &n.CreatedAt); err != nil {
+			r.log.Errorf("Failed to scan notification: %v", err)
+			return nil, err
+		}
+		n.Username = username
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) MarkAllRead(username string) error {
+	_, err := r.db.Exec(context.Background(),
+		"UPDATE notifications SET is_read = TRUE WHERE user_id = (SELECT id FROM users WHERE username = $1) AND NOT is_read",
+		username)
+	if err != nil {
+		r.log.Errorf("Failed to mark notifications read for user %s: %v", username, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type OIDCRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewOIDCRepository(db *pgxpool.Pool, log *logrus.Logger) *OIDCRepository {
+	return &OIDCRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *OIDCRepository) SaveOIDCState(state models.OIDCLoginState) error {
+	_, err := r.db.Exec(context.Background(),
+		"INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)",
+		state.State, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		r.log.Errorf("Failed to save OIDC login state: %v", err)
+	}
+	return err
+}
+
+// Состояние входа удаляется при чтении, поэтому ответ провайдера принимается только один раз
+func (r *OIDCRepository) ConsumeOIDCState(state string, now time.Time) (*models.OIDCLoginState, error) {
+	loginState := models.OIDCLoginState{State: state}
+	err := r.db.QueryRow(context.Background(),
+		"DELETE FROM oidc_login_states WHERE state = $1 RETURNING nonce, code_verifier, expires_at", state).
+		Scan(&loginState.Nonce, &loginState.CodeVerifier, &loginState.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrInvalidOIDCState
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get OIDC login state: %v", err)
+		return nil, err
+	}
+	if !loginState.ExpiresAt.After(now) {
+		return nil, models.ErrInvalidOIDCState
+	}
+	return &loginState, nil
+}
+
+// Удаление незавершённых входов, которые уже не могут быть завершены
+func (r *OIDCRepository) DeleteExpiredOIDCStates(now time.Time) (int64, error) {
+	tag, err := r.db.Exec(context.Background(), "DELETE FROM oidc_login_states WHERE expires_at <= $1", now)
+	if err != nil {
+		r.log.Errorf("Failed to delete expired OIDC login states: %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *OIDCRepository) GetUsernameByIdentity(issuer, subject string) (string, error) {
+	var username string
+	err := r.db.QueryRow(context.Background(),
+		`SELECT u.username FROM user_identities i
+         JOIN users u ON i.user_id = u.id
+         WHERE i.issuer = $1 AND i.subject = $2`, issuer, subject).Scan(&username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get user by identity %s/%s: %v", issuer, subject, err)
+		return "", err
+	}
+	return username, nil
+}
+
+// Создание пользователя, впервые вошедшего через провайдера, вместе с привязкой его учётной записи.
+// Занятое имя или уже привязанная учётная запись (параллельный первый вход) - ErrUserAlreadyExists.
+func (r *OIDCRepository) CreateUserWithIdentity(user models.User, issuer, subject string) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		"INSERT INTO users (username, password, balance) VALUES ($1, $2, $3) RETURNING id",
+		user.Username, user.Password, user.Balance).Scan(&userID)
+	if err == nil {
+		_, err = tx.Exec(context.Background(),
+			"INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)", userID, issuer, subject)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		err = models.ErrUserAlreadyExists
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to create user %s for identity %s/%s: %v", user.Username, issuer, subject, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", user.Username, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type OrderRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewOrderRepository(db *pgxpool.Pool, log *logrus.Logger) *OrderRepository {
+	return &OrderRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const orderSelect = `SELECT o.id, o.purchase_id, u.username, o.item_name, p.variant, p.price, o.status, o.created_at, o.updated_at
+FROM orders o
+JOIN users u ON o.user_id = u.id
+JOIN purchases p ON o.purchase_id = p.id`
+
+func (r *OrderRepository) queryOrders(query string, args ...any) ([]models.Order, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		r.log.Errorf("Failed to fetch orders: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		err = rows.Scan(&order.ID, &order.PurchaseID, &order.Username, &order.ItemName, &order.Variant, &order.Price,
+			&order.Status, &order.CreatedAt, &order.UpdatedAt)
+		if err != nil {
+			r.log.Errorf("Failed to scan order: %v", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *OrderRepository) GetOrder(id int) (*models.Order, error) {
+	orders, err := r.queryOrders(orderSelect+" WHERE o.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, models.ErrOrderNotFound
+	}
+	return &orders[0], nil
+}
+
+func (r *OrderRepository) GetUserOrders(username string) ([]models.Order, error) {
+	return r.queryOrders(orderSelect+" WHERE u.username = $1 ORDER BY o.created_at DESC", username)
+}
+
+// Список заказов для администратора, при пустом status возвращаются все
+func (r *OrderRepository) ListOrders(status string) ([]models.Order, error) {
+	if status == "" {
+		return r.queryOrders(orderSelect + " ORDER BY o.created_at")
+	}
+	return r.queryOrders(orderSelect+" WHERE o.status = $1 ORDER BY o.created_at", status)
+}
+
+func (r *OrderRepository) GetStatusHistory(orderID int) ([]models.OrderStatusChange, error) {
+	rows, err := r.db.Query(context.Background(),
+		"SELECT status, changed_by, timestamp FROM order_status_history WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		r.log.Errorf("Failed to fetch status history for order %d: %v", orderID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var change models.OrderStatusChange
+		if err = rows.Scan(&change.Status, &change.ChangedBy, &change.Time); err != nil {
+			r.log.Errorf("Failed to scan status history for order %d: %v", orderID, err)
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+// Смена статуса заказа. Статус меняется, только если заказ всё ещё в статусе from.
+func (r *OrderRepository) UpdateOrderStatus(orderID int, from, to, changedBy string) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3", to, orderID, from)
+	if err != nil {
+		r.log.Errorf("Failed to update status of order %d: %v", orderID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		err = tx.QueryRow(context.Background(), "SELECT TRUE FROM orders WHERE id = $1", orderID).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return models.ErrInvalidOrderTransition
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO order_status_history (order_id, status, changed_by) VALUES ($1, $2, $3)",
+		orderID, to, changedBy); err != nil {
+		r.log.Errorf("Failed to record status history for order %d: %v", orderID, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit status change of order %d: %v", orderID, err)
+		return err
+	}
+	r.log.Infof("Order %d moved from %s to %s by %s", orderID, from, to, changedBy)
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewPasswordResetRepository(db *pgxpool.Pool, log *logrus.Logger) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Новый токен сброса. Неиспользованные токены пользователя, выданные раньше, удаляются:
+// действует только последний.
+func (r *PasswordResetRepository) CreatePasswordResetToken(username, tokenHash, createdBy string, expiresAt time.Time) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrUserNotFound
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get user %s: %v", username, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		r.log.Errorf("Failed to delete previous reset tokens of user %s: %v", username, err)
+		return err
+	}
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_by) VALUES ($1, $2, $3, $4)",
+		userID, tokenHash, expiresAt, createdBy); err != nil {
+		r.log.Errorf("Failed to save reset token of user %s: %v", username, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return err
+	}
+	return nil
+}
+
+// Владелец действующего токена сброса. Токен при этом не расходуется.
+func (r *PasswordResetRepository) GetPasswordResetTokenOwner(tokenHash string, now time.Time) (string, error) {
+	var username string
+	err := r.db.QueryRow(context.Background(),
+		`SELECT u.username FROM password_reset_tokens t
+         JOIN users u ON t.user_id = u.id
+         WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > $2`,
+		tokenHash, now).Scan(&username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", models.ErrInvalidResetToken
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get password reset token: %v", err)
+		return "", err
+	}
+	return username, nil
+}
+
+// Установка пароля по токену сброса. Токен помечается использованным в той же транзакции,
+// поэтому второй раз тот же токен не сработает. Возвращает имя пользователя.
+func (r *PasswordResetRepository) ResetPassword(tokenHash, passwordHash string, now time.Time) (username string, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`UPDATE password_reset_tokens SET used_at = $2
+         WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+         RETURNING user_id`, tokenHash, now).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrInvalidResetToken
+		return "", err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to use password reset token: %v", err)
+		return "", err
+	}
+
+	err = tx.QueryRow(context.Background(),
+		"UPDATE users SET password = $1 WHERE id = $2 RETURNING username", passwordHash, userID).Scan(&username)
+	if err != nil {
+		r.log.Errorf("Failed to reset password of user %d: %v", userID, err)
+		return "", err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return "", err
+	}
+	return username, nil
+}
+
+// Удаление истёкших и использованных токенов сброса
+func (r *PasswordResetRepository) DeleteExpiredPasswordResetTokens(now time.Time) (int64, error) {
+	tag, err := r.db.Exec(context.Background(),
+		"DELETE FROM password_reset_tokens WHERE expires_at <= $1 OR used_at IS NOT NULL", now)
+	if err != nil {
+		r.log.Errorf("Failed to delete expired password reset tokens: %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type PreOrderRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewPreOrderRepository(db *pgxpool.Pool, log *logrus.Logger) *PreOrderRepository {
+	return &PreOrderRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const preOrderSelect = `SELECT p.id, u.username, p.item_name, p.variant, p.list_price, p.discount, p.price,
+           p.campaign_id, p.status, p.order_id, p.created_at, p.updated_at
+    FROM pre_orders p
+    JOIN users u ON p.user_id = u.id`
+
+func scanPreOrder(row pgx.Row) (*models.PreOrder, error) {
+	var preOrder models.PreOrder
+	err := row.Scan(&preOrder.ID, &preOrder.Username, &preOrder.ItemName, &preOrder.Variant, &preOrder.ListPrice,
+		&preOrder.Discount, &preOrder.Price, &preOrder.CampaignID, &preOrder.Status, &preOrder.OrderID,
+		&preOrder.CreatedAt, &preOrder.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &preOrder, nil
+}
+
+// Оформление предзаказа: цена фиксируется, монеты резервируются, но не списываются.
+// Предзаказ возможен только для варианта, которого нет в наличии.
+func (r *PreOrderRepository) CreatePreOrder(username, itemName, variant string, pricing PricingFunc) (preOrder *models.PreOrder, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	if err != nil {
+		r.log.Errorf("Failed to get user ID for username %s: %v", username, err)
+		return nil, err
+	}
+
+	// Остаток блокируется, чтобы поступление товара не разошлось с проверкой
+	var stock *int
+	err = tx.QueryRow(context.Background(),
+		"SELECT stock FROM catalog_variants WHERE item_name = $1 AND variant = $2 FOR SHARE", itemName, variant).
+		Scan(&stock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrVariantNotFound
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get stock of %s (%s): %v", itemName, variant, err)
+		return nil, err
+	}
+	if stock == nil || *stock > 0 {
+		err = models.ErrItemInStock
+		return nil, err
+	}
+
+	listPrice, err := effectivePrice(tx, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to get price of %s: %v", itemName, err)
+		return nil, err
+	}
+	quote, err := pricing(listPrice)
+	if err != nil {
+		r.log.Errorf("Failed to calculate price of %s for user %s: %v", itemName, username, err)
+		return nil, err
+	}
+
+	// Резервируем монеты из доступного баланса
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE users SET reserved = reserved + $1 WHERE id = $2 AND balance - reserved >= $1", quote.Price, userID)
+	if err != nil {
+		r.log.Errorf("Failed to reserve coins for user %s: %v", username, err)
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		err = models.ErrInsufficientBalance
+		return nil, err
+	}
+
+	preOrder = &models.PreOrder{
+		Username:   username,
+		ItemName:   itemName,
+		Variant:    variant,
+		ListPrice:  quote.ListPrice,
+		Discount:   quote.Discount,
+		Price:      quote.Price,
+		CampaignID: quote.CampaignID,
+		Status:     models.PreOrderStatusReserved,
+	}
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO pre_orders (user_id, item_name, variant, list_price, discount, price, campaign_id, status)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`,
+		userID, itemName, variant, quote.ListPrice, quote.Discount, quote.Price, quote.CampaignID, preOrder.Status).
+		Scan(&preOrder.ID, &preOrder.CreatedAt, &preOrder.UpdatedAt)
+	if err != nil {
+		r.log.Errorf("Failed to insert pre-order for user %s: %v", username, err)
+		return nil, err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return nil, err
+	}
+	r.log.Infof("Pre-order %d for user %s: %s (%s), reserved %d", preOrder.ID, username, itemName, variant, preOrder.Price)
+	return preOrder, nil
+}
+
+func (r *PreOrderRepository) GetUserPreOrders(username string) ([]models.PreOrder, error) {
+	rows, err := r.db.Query(context.Background(),
+		preOrderSelect+" WHERE u.username = $1 ORDER BY p.created_at DESC, p.id DESC", username)
+	if err != nil {
+		r.log.Errorf("Failed to fetch pre-orders for user %s: %v", username, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var preOrders []models.PreOrder
+	for rows.Next() {
+		preOrder, err := scanPreOrder(rows)
+		if err != nil {
+			r.log.Errorf("Failed to scan pre-order for user %s: %v", username, err)
+			return nil, err
+		}
+		preOrders = append(preOrders, *preOrder)
+	}
+	return preOrders, rows.Err()
+}
+
+// Отмена предзаказа пользователем: резерв снимается, монеты снова доступны
+func (r *PreOrderRepository) CancelPreOrder(username string, preOrderID int) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var owner string
+	err = tx.QueryRow(context.Background(),
+		`SELECT u.username FROM pre_orders p JOIN users u ON p.user_id = u.id
+         WHERE p.id = $1 FOR UPDATE OF p`, preOrderID).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && owner != username) {
+		err = models.ErrPreOrderNotFound
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock pre-order %d: %v", preOrderID, err)
+		return err
+	}
+
+	released, err := releasePreOrders(tx, "p.id = $1", preOrderID, models.PreOrderStatusCancelled)
+	if err != nil {
+		r.log.Errorf("Failed to cancel pre-order %d: %v", preOrderID, err)
+		return err
+	}
+	if released == 0 {
+		err = models.ErrPreOrderNotActive
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for pre-order %d: %v", preOrderID, err)
+		return err
+	}
+	r.log.Infof("Pre-order %d cancelled by user %s", preOrderID, username)
+	return nil
+}
+
+// Снятие товара с предзаказа: все активные предзаказы закрываются, резервы возвращаются.
+// Возвращает количество закрытых предзаказов.
+func (r *PreOrderRepository) ReleaseItemPreOrders(itemName string) (released int, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	released, err = releasePreOrders(tx, "p.item_name = $1", itemName, models.PreOrderStatusReleased)
+	if err != nil {
+		r.log.Errorf("Failed to release pre-orders of %s: %v", itemName, err)
+		return 0, err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for pre-orders of %s: %v", itemName, err)
+		return 0, err
+	}
+	return released, nil
+}
+
+// Закрытие активных предзаказов по условию с возвратом резерва каждому пользователю
+func releasePreOrders(tx pgx.Tx, condition string, arg any, status string) (int, error) {
+	rows, err := tx.Query(context.Background(),
+		`UPDATE pre_orders p SET status = $2, updated_at = NOW()
+         WHERE `+condition+` AND p.status = 'reserved'
+         RETURNING p.user_id, p.price`, arg, status)
+	if err != nil {
+		return 0, err
+	}
+	reservations := map[int]int{}
+	released := 0
+	for rows.Next() {
+		var userID, price int
+		if err = rows.Scan(&userID, &price); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		reservations[userID] += price
+		released++
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for userID, amount := range reservations {
+		if _, err = tx.Exec(context.Background(),
+			"UPDATE users SET reserved = reserved - $1 WHERE id = $2", amount, userID); err != nil {
+			return 0, err
+		}
+	}
+	return released, nil
+}
+
+// Активные предзаказы, товар для которых появился на складе, в порядке оформления
+func (r *PreOrderRepository) GetCapturablePreOrderIDs() ([]int, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT p.id FROM pre_orders p
+         JOIN catalog_variants v ON v.item_name = p.item_name AND v.variant = p.variant
+         WHERE p.status = 'reserved' AND (v.stock IS NULL OR v.stock > 0)
+         ORDER BY p.created_at, p.id`)
+	if err != nil {
+		r.log.Errorf("Failed to fetch capturable pre-orders: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			r.log.Errorf("Failed to scan capturable pre-order: %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Выкуп предзаказа: резерв снимается и списывается как обычная покупка по зафиксированной цене.
+// Если товар уже разобран, возвращается ErrOutOfStock и предзаказ остаётся активным.
+// Повторный вызов для уже закрытого предзаказа ничего не делает.
+func (r *PreOrderRepository) CapturePreOrder(preOrderID int) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback(context.Background())
+			r.log.Errorf("Transaction panicked: %v", p)
+		} else if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	preOrder, err := scanPreOrder(tx.QueryRow(context.Background(),
+		preOrderSelect+" WHERE p.id = $1 FOR UPDATE OF p", preOrderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrPreOrderNotFound
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock pre-order %d: %v", preOrderID, err)
+		return err
+	}
+	if preOrder.Status != models.PreOrderStatusReserved {
+		return tx.Rollback(context.Background())
+	}
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		"UPDATE users SET reserved = reserved - $1 WHERE username = $2 RETURNING id", preOrder.Price, preOrder.Username).
+		Scan(&userID)
+	if err != nil {
+		r.log.Errorf("Failed to release reservation of pre-order %d: %v", preOrderID, err)
+		return err
+	}
+
+	quote := models.PriceQuote{
+		ListPrice:  preOrder.ListPrice,
+		Discount:   preOrder.Discount,
+		Price:      preOrder.Price,
+		CampaignID: preOrder.CampaignID,
+	}
+	orderID, err := completePurchase(tx, r.log, userID, preOrder.Username, preOrder.ItemName, preOrder.Variant, quote)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(),
+		"UPDATE pre_orders SET status = $1, order_id = $2, updated_at = NOW() WHERE id = $3",
+		models.PreOrderStatusCaptured, orderID, preOrderID)
+	if err != nil {
+		r.log.Errorf("Failed to mark pre-order %d captured: %v", preOrderID, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for pre-order %d: %v", preOrderID, err)
+		return err
+	}
+	r.log.Infof("Pre-order %d captured for user %s, order %d", preOrderID, preOrder.Username, orderID)
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+const profileColumns = "username, display_name, department, title, avatar_key, created_at"
+
+type ProfileRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewProfileRepository(db *pgxpool.Pool, log *logrus.Logger) *ProfileRepository {
+	return &ProfileRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *ProfileRepository) GetProfile(username string) (*models.UserProfile, error) {
+	profile, err := scanProfile(r.db.QueryRow(context.Background(),
+		"SELECT "+profileColumns+" FROM users WHERE username = $1 AND deleted_at IS NULL", username))
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		r.log.Errorf("Failed to get profile of user %s: %v", username, err)
+	}
+	return profile, err
+}
+
+// Поля запроса со значением nil не меняются
+func (r *ProfileRepository) UpdateProfile(username string, req models.UpdateProfileRequest) (*models.UserProfile, error) {
+	profile, err := scanProfile(r.db.QueryRow(context.Background(),
+		`UPDATE users SET display_name = COALESCE($2, display_name),
+             department = COALESCE($3, department),
+             title = COALESCE($4, title)
+         WHERE username = $1 AND deleted_at IS NULL
+         RETURNING `+profileColumns, username, req.DisplayName, req.Department, req.Title))
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		r.log.Errorf("Failed to update profile of user %s: %v", username, err)
+	}
+	return profile, err
+}
+
+// Замена аватара, возвращает ключ прежнего (пустой, если аватара не было)
+func (r *ProfileRepository) SetAvatar(username, avatarKey string) (string, error) {
+	var oldKey string
+	err := r.db.QueryRow(context.Background(),
+		`UPDATE users u SET avatar_key = $2
+         FROM (SELECT id, avatar_key FROM users WHERE username = $1 AND deleted_at IS NULL FOR UPDATE) old
+         WHERE u.id = old.id
+         RETURNING old.avatar_key`, username, avatarKey).Scan(&oldKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", models.ErrUserNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to set avatar of user %s: %v", username, err)
+		return "", err
+	}
+	return oldKey, nil
+}
+
+func scanProfile(row pgx.Row) (*models.UserProfile, error) {
+	var p models.UserProfile
+	err := row.Scan(&p.Username, &p.DisplayName, &p.Department, &p.Title, &p.AvatarKey, &p.JoinedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
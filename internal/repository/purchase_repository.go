@@ -3,7 +3,8 @@ package repository
 import (
 	"ShopAvito/internal/models"
 	"context"
-	"fmt"
+	"errors"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
@@ -20,11 +21,14 @@ func NewPurchaseRepository(db *pgxpool.Pool, log *logrus.Logger) *PurchaseReposi
 	}
 }
 
-func (r *PurchaseRepository) BuyItem(username, itemName string, price int) error {
+// Покупка товара: списание монет, запись покупки, заказ на выдачу и пополнение инвентаря.
+// Цена по каталогу определяется внутри транзакции, pricing применяет к ней скидки.
+// Промокод из итоговой цены погашается в той же транзакции. Возвращает ID созданного заказа и списанную цену.
+func (r *PurchaseRepository) BuyItem(username, itemName, variant string, pricing PricingFunc) (orderID int, quote models.PriceQuote, err error) {
 	tx, err := r.db.Begin(context.Background())
 	if err != nil {
 		r.log.Errorf("Error starting transaction: %s", err)
-		return err
+		return 0, quote, err
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -42,55 +46,123 @@ func (r *PurchaseRepository) BuyItem(username, itemName string, price int) error
 	err = tx.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
 	if err != nil {
 		r.log.Errorf("Failed to get user ID for username %s: %v", username, err)
-		return err
+		return 0, quote, err
 	}
 
-	// Проверяем, достаточно ли баланса
-	var currentBalance int
-	err = tx.QueryRow(context.Background(), "SELECT balance FROM users WHERE id = $1", userID).Scan(&currentBalance)
+	// Цена, действующая на момент транзакции. Блокировка строки товара не даёт
+	// планировщику изменить цену, пока покупка не завершится.
+	listPrice, err := effectivePrice(tx, itemName)
 	if err != nil {
-		r.log.Errorf("Failed to get balance for user %s: %v", username, err)
-		return err
+		r.log.Errorf("Failed to get price of %s: %v", itemName, err)
+		return 0, quote, err
 	}
-	if currentBalance < price {
-		r.log.Errorf("Insufficient balance for user %s: %d < %d", username, currentBalance, price)
-		return fmt.Errorf("insufficient balance")
+	if quote, err = pricing(listPrice); err != nil {
+		r.log.Errorf("Failed to calculate price of %s for user %s: %v", itemName, username, err)
+		return 0, quote, err
 	}
-	// Вычитаем баланс
-	_, err = tx.Exec(context.Background(),
-		"UPDATE users SET balance = balance - $1 WHERE id = $2", price, userID)
+
+	if orderID, err = completePurchase(tx, r.log, userID, username, itemName, variant, quote); err != nil {
+		return 0, quote, err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return 0, quote, err
+	}
+	r.log.Infof("Purchase successful for user %s: %s (%s), order %d", username, itemName, variant, orderID)
+	return orderID, quote, nil
+}
+
+// Завершение покупки по рассчитанной цене внутри транзакции: остатки, списание монет, запись покупки,
+// погашение промокода, заказ на выдачу и инвентарь. Общая часть покупки и выкупа предзаказа.
+func completePurchase(tx pgx.Tx, log *logrus.Logger, userID int, username, itemName, variant string, quote models.PriceQuote) (int, error) {
+	price := quote.Price
+
+	// Списываем остаток варианта (NULL - остаток не ограничен)
+	if err := takeStock(tx, itemName, variant, 1); err != nil {
+		log.Errorf("Failed to take stock of %s (%s) for user %s: %v", itemName, variant, username, err)
+		return 0, err
+	}
+
+	// Набор раскладывается на компоненты, остаток проверяется по каждому из них
+	components, err := bundleComponents(tx, itemName, variant)
 	if err != nil {
-		r.log.Errorf("Failed to update balance for user %s: %v", username, err)
-		return err
+		log.Errorf("Failed to expand bundle %s: %v", itemName, err)
+		return 0, err
+	}
+	for _, component := range components {
+		if err = takeStock(tx, component.ItemName, component.Variant, component.Quantity); err != nil {
+			log.Errorf("Failed to take stock of bundle component %s (%s): %v", component.ItemName, component.Variant, err)
+			return 0, err
+		}
+	}
+
+	// Вычитаем баланс. Монеты, зарезервированные под предзаказы, списывать нельзя.
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE users SET balance = balance - $1 WHERE id = $2 AND balance - reserved >= $1", price, userID)
+	if err != nil {
+		log.Errorf("Failed to update balance for user %s: %v", username, err)
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		log.Errorf("Insufficient balance for user %s: price %d", username, price)
+		return 0, models.ErrInsufficientBalance
 	}
 
 	// Добавляем запись в purchases
-	_, err = tx.Exec(context.Background(),
-		"INSERT INTO purchases (user_id, item_name, price) VALUES ($1, $2, $3)",
-		userID, itemName, price)
+	var purchaseID int
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO purchases (user_id, item_name, variant, list_price, discount, price, campaign_id, promo_code_id)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		userID, itemName, variant, quote.ListPrice, quote.Discount, price, quote.CampaignID, quote.PromoCodeID).
+		Scan(&purchaseID)
 	if err != nil {
-		r.log.Errorf("Failed to insert purchase record for user %s: %v", username, err)
-		return err
+		log.Errorf("Failed to insert purchase record for user %s: %v", username, err)
+		return 0, err
 	}
 
-	// Добавляем в инвентарь или увеличиваем количество
-	_, err = tx.Exec(context.Background(),
-		`INSERT INTO inventory (user_id, item_type, quantity) 
-         VALUES ($1, $2, 1) 
-         ON CONFLICT (user_id, item_type) 
-         DO UPDATE SET quantity = inventory.quantity + 1`,
-		userID, itemName)
+	// Погашаем промокод
+	if quote.PromoCodeID != nil {
+		if err = redeemPromoCode(tx, *quote.PromoCodeID, userID, purchaseID); err != nil {
+			log.Errorf("Failed to redeem promo code for user %s: %v", username, err)
+			return 0, err
+		}
+	}
+
+	// Запоминаем состав набора на момент покупки
+	for _, component := range components {
+		if _, err = tx.Exec(context.Background(),
+			"INSERT INTO purchase_components (purchase_id, item_name, variant, quantity) VALUES ($1, $2, $3, $4)",
+			purchaseID, component.ItemName, component.Variant, component.Quantity); err != nil {
+			log.Errorf("Failed to record bundle component for user %s: %v", username, err)
+			return 0, err
+		}
+	}
+
+	// Создаём заказ на выдачу товара
+	orderID, err := createOrder(tx, purchaseID, userID, itemName, username)
 	if err != nil {
-		r.log.Errorf("Failed to update inventory for user %s: %v", username, err)
-		return err
+		log.Errorf("Failed to create order for user %s: %v", username, err)
+		return 0, err
 	}
 
-	if err = tx.Commit(context.Background()); err != nil {
-		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
-		return err
+	// Добавляем в инвентарь или увеличиваем количество. Вместо набора в инвентарь попадают его компоненты.
+	if len(components) == 0 {
+		components = []models.BundleComponent{{ItemName: itemName, Variant: variant, Quantity: 1}}
 	}
-	r.log.Infof("Purchase successful for user %s: %s", username, itemName)
-	return nil
+	for _, component := range components {
+		_, err = tx.Exec(context.Background(),
+			`INSERT INTO inventory (user_id, item_type, variant, quantity) 
+             VALUES ($1, $2, $3, $4) 
+             ON CONFLICT (user_id, item_type, variant) 
+             DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity`,
+			userID, component.ItemName, component.Variant, component.Quantity)
+		if err != nil {
+			log.Errorf("Failed to update inventory for user %s: %v", username, err)
+			return 0, err
+		}
+	}
+	return orderID, nil
 }
 
 func (r *PurchaseRepository) GetUserPurchases(username string) ([]models.Purchase, error) {
@@ -102,7 +174,7 @@ func (r *PurchaseRepository) GetUserPurchases(username string) ([]models.Purchas
 	}
 
 	rows, err := r.db.Query(context.Background(),
-		"SELECT item_name, price, timestamp FROM purchases WHERE user_id = $1", userID)
+		"SELECT item_name, variant, list_price, discount, price, timestamp FROM purchases WHERE user_id = $1", userID)
 	if err != nil {
 		r.log.Errorf("Failed to fetch purchases for user %s: %v", username, err)
 		return nil, err
@@ -112,7 +184,7 @@ func (r *PurchaseRepository) GetUserPurchases(username string) ([]models.Purchas
 	var purchases []models.Purchase
 	for rows.Next() {
 		var purchase models.Purchase
-		err = rows.Scan(&purchase.ItemName, &purchase.Price, &purchase.Time)
+		err = rows.Scan(&purchase.ItemName, &purchase.Variant, &purchase.ListPrice, &purchase.Discount, &purchase.Price, &purchase.Time)
 		if err != nil {
 			r.log.Errorf("Failed to scan purchase for user %s: %v", username, err)
 			return nil, err
@@ -127,3 +199,259 @@ func (r *PurchaseRepository) GetUserPurchases(username string) ([]models.Purchas
 	r.log.Infof("Fetched %d purchases for user %s", len(purchases), username)
 	return purchases, nil
 }
+
+// PricingFunc рассчитывает итоговую цену покупки по цене каталога
+type PricingFunc func(listPrice int) (models.PriceQuote, error)
+
+// Цена товара на момент транзакции: последнее наступившее изменение из истории цен,
+// даже если планировщик ещё не перенёс его в каталог
+func effectivePrice(tx pgx.Tx, itemName string) (int, error) {
+	var price int
+	err := tx.QueryRow(context.Background(),
+		`SELECT COALESCE(
+             (SELECT h.price FROM catalog_price_history h
+              WHERE h.item_name = c.name AND h.effective_at <= NOW()
+              ORDER BY h.effective_at DESC, h.id DESC LIMIT 1),
+             c.price)
+         FROM catalog_items c WHERE c.name = $1
+         FOR SHARE`, itemName).Scan(&price)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, models.ErrItemNotFound
+	}
+	return price, err
+}
+
+// Компоненты набора с подставленным вариантом. Для обычного товара список пуст.
+func bundleComponents(tx pgx.Tx, bundleName, variant string) ([]models.BundleComponent, error) {
+	rows, err := tx.Query(context.Background(),
+		"SELECT item_name, COALESCE(variant, $2), quantity FROM catalog_bundle_items WHERE bundle_name = $1 ORDER BY id",
+		bundleName, variant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []models.BundleComponent
+	for rows.Next() {
+		var component models.BundleComponent
+		if err = rows.Scan(&component.ItemName, &component.Variant, &component.Quantity); err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// Компоненты, выданные по покупке набора
+func purchaseComponents(tx pgx.Tx, purchaseID int) ([]models.BundleComponent, error) {
+	rows, err := tx.Query(context.Background(),
+		"SELECT item_name, variant, quantity FROM purchase_components WHERE purchase_id = $1 ORDER BY id", purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []models.BundleComponent
+	for rows.Next() {
+		var component models.BundleComponent
+		if err = rows.Scan(&component.ItemName, &component.Variant, &component.Quantity); err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// Списание варианта со склада внутри транзакции покупки
+func takeStock(tx pgx.Tx, itemName, variant string, quantity int) error {
+	tag, err := tx.Exec(context.Background(),
+		`UPDATE catalog_variants SET stock = stock - $3
+         WHERE item_name = $1 AND variant = $2 AND (stock IS NULL OR stock >= $3)`,
+		itemName, variant, quantity)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = tx.QueryRow(context.Background(),
+		"SELECT TRUE FROM catalog_variants WHERE item_name = $1 AND variant = $2", itemName, variant).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrVariantNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return models.ErrOutOfStock
+}
+
+// Погашение промокода: счётчик использований и запись о применении пользователем
+func redeemPromoCode(tx pgx.Tx, promoCodeID, userID, purchaseID int) error {
+	tag, err := tx.Exec(context.Background(),
+		`UPDATE promo_codes SET uses = uses + 1
+         WHERE id = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > NOW())`, promoCodeID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrPromoCodeExpired
+	}
+
+	tag, err = tx.Exec(context.Background(),
+		`INSERT INTO promo_code_redemptions (promo_code_id, user_id, purchase_id) VALUES ($1, $2, $3)
+         ON CONFLICT (promo_code_id, user_id) DO NOTHING`,
+		promoCodeID, userID, purchaseID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrPromoCodeAlreadyUsed
+	}
+	return nil
+}
+
+// Отмена погашения промокода при возврате покупки: использование возвращается, пользователь может применить код снова
+func restorePromoCode(tx pgx.Tx, purchaseID int) error {
+	_, err := tx.Exec(context.Background(),
+		`WITH redemption AS (
+             DELETE FROM promo_code_redemptions WHERE purchase_id = $1 RETURNING promo_code_id
+         )
+         UPDATE promo_codes p SET uses = p.uses - 1
+         FROM redemption r
+         WHERE p.id = r.promo_code_id AND p.uses > 0`, purchaseID)
+	return err
+}
+
+// Возврат варианта на склад
+func returnStock(tx pgx.Tx, itemName, variant string, quantity int) error {
+	_, err := tx.Exec(context.Background(),
+		"UPDATE catalog_variants SET stock = stock + $3 WHERE item_name = $1 AND variant = $2 AND stock IS NOT NULL",
+		itemName, variant, quantity)
+	return err
+}
+
+// Создание заказа в статусе placed внутри транзакции покупки
+func createOrder(tx pgx.Tx, purchaseID, userID int, itemName, changedBy string) (int, error) {
+	var orderID int
+	err := tx.QueryRow(context.Background(),
+		"INSERT INTO orders (purchase_id, user_id, item_name, status) VALUES ($1, $2, $3, $4) RETURNING id",
+		purchaseID, userID, itemName, models.OrderStatusPlaced).Scan(&orderID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(context.Background(),
+		"INSERT INTO order_status_history (order_id, status, changed_by) VALUES ($1, $2, $3)",
+		orderID, models.OrderStatusPlaced, changedBy)
+	return orderID, err
+}
+
+// Отмена заказа с возвратом монет: покупка помечается возвращённой, товар убирается из инвентаря.
+// expectedStatus защищает от гонки со сменой статуса администратором.
+func (r *PurchaseRepository) CancelOrder(orderID int, expectedStatus, changedBy string) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var purchaseID, userID int
+	var itemName, variant, status string
+	err = tx.QueryRow(context.Background(),
+		`SELECT o.purchase_id, o.user_id, o.item_name, p.variant, o.status
+         FROM orders o
+         JOIN purchases p ON o.purchase_id = p.id
+         WHERE o.id = $1
+         FOR UPDATE OF o`, orderID).
+		Scan(&purchaseID, &userID, &itemName, &variant, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrOrderNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock order %d: %v", orderID, err)
+		return err
+	}
+	if status != expectedStatus {
+		return models.ErrInvalidOrderTransition
+	}
+
+	// Возвращаем монеты по цене покупки
+	var price int
+	err = tx.QueryRow(context.Background(),
+		"UPDATE purchases SET refunded = TRUE WHERE id = $1 AND refunded = FALSE RETURNING price", purchaseID).
+		Scan(&price)
+	if err != nil {
+		r.log.Errorf("Failed to mark purchase %d as refunded: %v", purchaseID, err)
+		return err
+	}
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE users SET balance = balance + $1 WHERE id = $2", price, userID); err != nil {
+		r.log.Errorf("Failed to refund user %d: %v", userID, err)
+		return err
+	}
+	if err = restorePromoCode(tx, purchaseID); err != nil {
+		r.log.Errorf("Failed to restore promo code of purchase %d: %v", purchaseID, err)
+		return err
+	}
+
+	// Убираем товар из инвентаря и возвращаем его на склад
+	if err = returnStock(tx, itemName, variant, 1); err != nil {
+		r.log.Errorf("Failed to return stock of %s (%s): %v", itemName, variant, err)
+		return err
+	}
+	components, err := purchaseComponents(tx, purchaseID)
+	if err != nil {
+		r.log.Errorf("Failed to fetch components of purchase %d: %v", purchaseID, err)
+		return err
+	}
+	for _, component := range components {
+		if err = returnStock(tx, component.ItemName, component.Variant, component.Quantity); err != nil {
+			r.log.Errorf("Failed to return stock of %s (%s): %v", component.ItemName, component.Variant, err)
+			return err
+		}
+	}
+	if len(components) == 0 {
+		components = []models.BundleComponent{{ItemName: itemName, Variant: variant, Quantity: 1}}
+	}
+	for _, component := range components {
+		if _, err = tx.Exec(context.Background(),
+			`UPDATE inventory SET quantity = GREATEST(quantity - $4, 0)
+             WHERE user_id = $1 AND item_type = $2 AND variant = $3`,
+			userID, component.ItemName, component.Variant, component.Quantity); err != nil {
+			r.log.Errorf("Failed to update inventory for user %d: %v", userID, err)
+			return err
+		}
+	}
+	if _, err = tx.Exec(context.Background(),
+		"DELETE FROM inventory WHERE user_id = $1 AND quantity <= 0", userID); err != nil {
+		r.log.Errorf("Failed to clean up inventory for user %d: %v", userID, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2", models.OrderStatusCancelled, orderID); err != nil {
+		r.log.Errorf("Failed to cancel order %d: %v", orderID, err)
+		return err
+	}
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO order_status_history (order_id, status, changed_by) VALUES ($1, $2, $3)",
+		orderID, models.OrderStatusCancelled, changedBy); err != nil {
+		r.log.Errorf("Failed to record status history for order %d: %v", orderID, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit cancellation of order %d: %v", orderID, err)
+		return err
+	}
+	r.log.Infof("Order %d cancelled by %s, refunded %d coins", orderID, changedBy, price)
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type RefreshTokenRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewRefreshTokenRepository(db *pgxpool.Pool, log *logrus.Logger) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Новый сеанс входа вместе с первым refresh-токеном его семейства
+func (r *RefreshTokenRepository) CreateSession(session models.Session, token models.RefreshToken) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO sessions (user_id, family_id, user_agent, ip)
+         SELECT id, $2, $3, $4 FROM users WHERE username = $1
+         RETURNING user_id`,
+		session.Username, session.FamilyID, session.UserAgent, session.IP).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = errors.New("user not found")
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to save session for user %s: %v", session.Username, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, token.FamilyID, token.TokenHash, token.ExpiresAt); err != nil {
+		r.log.Errorf("Failed to save refresh token for user %s: %v", session.Username, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", session.Username, err)
+		return err
+	}
+	return nil
+}
+
+// Обмен refresh-токена на новый из того же семейства. Старый токен помечается использованным.
+// Для уже использованного токена возвращается ErrRefreshTokenReused, отзыв семейства остаётся вызывающему.
+// Возвращает старый токен: его владельца и семейство.
+func (r *RefreshTokenRepository) RotateRefreshToken(tokenHash string, next models.RefreshToken, now time.Time) (previous *models.RefreshToken, err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var current models.RefreshToken
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`SELECT t.id, t.user_id, u.username, t.family_id, t.expires_at, t.used_at, t.revoked_at
+         FROM refresh_tokens t
+         JOIN users u ON t.user_id = u.id
+         WHERE t.token_hash = $1
+         FOR UPDATE OF t`, tokenHash).
+		Scan(&current.ID, &userID, &current.Username, &current.FamilyID, &current.ExpiresAt, &current.UsedAt, &current.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrInvalidRefreshToken
+		return nil, err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to lock refresh token: %v", err)
+		return nil, err
+	}
+	switch {
+	case current.RevokedAt != nil, !current.ExpiresAt.After(now):
+		err = models.ErrInvalidRefreshToken
+		return nil, err
+	case current.UsedAt != nil:
+		err = models.ErrRefreshTokenReused
+		return nil, err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE refresh_tokens SET used_at = $1 WHERE id = $2", now, current.ID); err != nil {
+		r.log.Errorf("Failed to mark refresh token %d used: %v", current.ID, err)
+		return nil, err
+	}
+	if _, err = tx.Exec(context.Background(),
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, current.FamilyID, next.TokenHash, next.ExpiresAt); err != nil {
+		r.log.Errorf("Failed to save rotated refresh token for user %s: %v", current.Username, err)
+		return nil, err
+	}
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE sessions SET last_used_at = $1 WHERE family_id = $2", now, current.FamilyID); err != nil {
+		r.log.Errorf("Failed to update session of refresh token family: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for refresh token %d: %v", current.ID, err)
+		return nil, err
+	}
+	return &current, nil
+}
+
+// Отзыв всего семейства, к которому относится токен
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(tokenHash string) error {
+	tag, err := r.db.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = NOW()
+         WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL`,
+		tokenHash)
+	if err != nil {
+		r.log.Errorf("Failed to revoke refresh token family: %v", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// Удаление истёкших токенов и сеансов, у которых не осталось токенов. Возвращает количество удалённых токенов.
+func (r *RefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	tag, err := r.db.Exec(context.Background(), "DELETE FROM refresh_tokens WHERE expires_at <= $1", now)
+	if err != nil {
+		r.log.Errorf("Failed to delete expired refresh tokens: %v", err)
+		return 0, err
+	}
+	if _, err = r.db.Exec(context.Background(),
+		`DELETE FROM sessions s
+         WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.family_id = s.family_id)`); err != nil {
+		r.log.Errorf("Failed to delete ended sessions: %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
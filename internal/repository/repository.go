@@ -1,10 +1,22 @@
 package repository
 
-import "ShopAvito/internal/models"
+import (
+	"ShopAvito/internal/models"
+	"time"
+)
 
 type PurchaseRepositoryInterface interface {
-	BuyItem(username, itemName string, price int) error
+	BuyItem(username, itemName, variant string, pricing PricingFunc) (int, models.PriceQuote, error)
 	GetUserPurchases(username string) ([]models.Purchase, error)
+	CancelOrder(orderID int, expectedStatus, changedBy string) error
+}
+
+type OrderRepositoryInterface interface {
+	GetOrder(id int) (*models.Order, error)
+	GetUserOrders(username string) ([]models.Order, error)
+	ListOrders(status string) ([]models.Order, error)
+	GetStatusHistory(orderID int) ([]models.OrderStatusChange, error)
+	UpdateOrderStatus(orderID int, from, to, changedBy string) error
 }
 
 type InventoryRepositoryInterface interface {
@@ -25,4 +37,148 @@ type UserRepositoryInterface interface {
 	GetUserByUsername(username string) (*models.User, error)
 	UpdateUserBalance(username string, newBalance int) error
 	UserExists(username string) (bool, error)
+	GetUserRole(username string) (string, error)
+	SetUserRole(username, role string) error
+	UpdatePassword(username, passwordHash string) error
+}
+
+type AuctionRepositoryInterface interface {
+	CreateAuction(auction models.Auction) (int, error)
+	GetAuction(id int) (*models.Auction, error)
+	ListOpenAuctions() ([]models.Auction, error)
+	GetBids(auctionID int) ([]models.AuctionBid, error)
+	PlaceBid(auctionID int, username string, amount int, now time.Time) error
+	GetDueAuctionIDs(now time.Time) ([]int, error)
+	CloseAuction(auctionID int) error
+}
+
+type CatalogRepositoryInterface interface {
+	SearchItems(query models.CatalogQuery) ([]models.CatalogItem, int, error)
+	ListCategories() ([]models.Category, error)
+	GetItem(name string) (*models.CatalogItem, error)
+	UpsertVariant(itemName string, variant models.CatalogVariant) error
+	CreateBundle(bundle models.CatalogItem) error
+	SetItemImage(itemName, imageKey, thumbKey string) error
+	AddPriceChange(change models.PriceChange) (int, error)
+	GetPriceHistory(itemName string) ([]models.PriceChange, error)
+	DeletePendingPriceChange(id int) error
+	ApplyDuePriceChanges(now time.Time) ([]models.PriceChange, error)
+}
+
+type DiscountRepositoryInterface interface {
+	CreateCampaign(campaign models.Campaign) (int, error)
+	ListCampaigns() ([]models.Campaign, error)
+	GetActiveCampaigns(itemName string, at time.Time) ([]models.Campaign, error)
+	// Все кампании, действующие в момент at, - для расчёта цен целой страницы каталога одним запросом
+	ListActiveCampaigns(at time.Time) ([]models.Campaign, error)
+	CreatePromoCode(code models.PromoCode) (int, error)
+	GetPromoCode(code string) (*models.PromoCode, error)
+	ListPromoCodes() ([]models.PromoCode, error)
+}
+
+type WishlistRepositoryInterface interface {
+	GetWishlist(username string) ([]models.WishlistItem, error)
+	GetWishlistsByItems(itemNames []string) ([]models.WishlistItem, error)
+	AddItem(username, itemName string) error
+	RemoveItem(username, itemName string) error
+}
+
+type NotificationRepositoryInterface interface {
+	CreateNotifications(notifications []models.Notification) error
+	GetNotifications(username string, unreadOnly bool) ([]models.Notification, error)
+	MarkAllRead(username string) error
+}
+
+type ReviewRepositoryInterface interface {
+	UpsertReview(review models.Review) (*models.Review, error)
+	GetItemReviews(itemName string) ([]models.Review, error)
+	SetHidden(reviewID int, hidden bool) error
+}
+
+type PreOrderRepositoryInterface interface {
+	CreatePreOrder(username, itemName, variant string, pricing PricingFunc) (*models.PreOrder, error)
+	GetUserPreOrders(username string) ([]models.PreOrder, error)
+	CancelPreOrder(username string, preOrderID int) error
+	ReleaseItemPreOrders(itemName string) (int, error)
+	GetCapturablePreOrderIDs() ([]int, error)
+	CapturePreOrder(preOrderID int) error
+}
+
+type AccountRepositoryInterface interface {
+	ExportAccount(username string) (*models.AccountExport, error)
+	DeleteAccount(username string, now time.Time) (string, error)
+}
+
+type ProfileRepositoryInterface interface {
+	GetProfile(username string) (*models.UserProfile, error)
+	UpdateProfile(username string, req models.UpdateProfileRequest) (*models.UserProfile, error)
+	SetAvatar(username, avatarKey string) (string, error)
+}
+
+type RefreshTokenRepositoryInterface interface {
+	CreateSession(session models.Session, token models.RefreshToken) error
+	RotateRefreshToken(tokenHash string, next models.RefreshToken, now time.Time) (*models.RefreshToken, error)
+	RevokeRefreshTokenFamily(tokenHash string) error
+	DeleteExpiredRefreshTokens(now time.Time) (int64, error)
+}
+
+// Сеансы входа. Сеанс создаётся вместе с первым refresh-токеном (RefreshTokenRepositoryInterface.CreateSession).
+type SessionRepositoryInterface interface {
+	ListSessions(username string, now time.Time) ([]models.Session, error)
+	RevokeSession(username string, id int) (string, error)
+	IsSessionActive(familyID string, now time.Time) (bool, error)
+}
+
+// Хранилище неудачных попыток входа. Реализации: в памяти (один экземпляр сервиса) и в Postgres (несколько реплик).
+type LoginAttemptRepositoryInterface interface {
+	// Резерв попытки до проверки пароля: счётчик увеличивается, неудачи до since забываются, и счёт
+	// начинается заново. Одним шагом возвращается состояние до увеличения (nil - действующих неудач не было)
+	ReserveLoginAttempt(key string, at, since time.Time) (*models.LoginAttempts, error)
+	// Отмена резерва, сделанного в момент at, если попытка не оказалась неудачной. Время последней
+	// неудачи возвращается к previous, если после резерва других неудач не было.
+	ReleaseLoginAttempt(key string, at time.Time, previous *models.LoginAttempts) error
+	ResetLoginAttempts(key string) error
+	DeleteStaleLoginAttempts(before time.Time) (int64, error)
+}
+
+type PasswordResetRepositoryInterface interface {
+	CreatePasswordResetToken(username, tokenHash, createdBy string, expiresAt time.Time) error
+	GetPasswordResetTokenOwner(tokenHash string, now time.Time) (string, error)
+	ResetPassword(tokenHash, passwordHash string, now time.Time) (string, error)
+	DeleteExpiredPasswordResetTokens(now time.Time) (int64, error)
+}
+
+type TwoFactorRepositoryInterface interface {
+	SaveTOTPSecret(username, secret string) error
+	GetTOTPSecret(username string) (*models.TOTPSecret, error)
+	EnableTOTP(username string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(username string, step int64) (bool, error)
+	UseRecoveryCode(username, codeHash string) (bool, error)
+	DisableTOTP(username string) error
+}
+
+type OIDCRepositoryInterface interface {
+	SaveOIDCState(state models.OIDCLoginState) error
+	ConsumeOIDCState(state string, now time.Time) (*models.OIDCLoginState, error)
+	DeleteExpiredOIDCStates(now time.Time) (int64, error)
+	// Пустое имя - учётная запись провайдера ещё не привязана
+	GetUsernameByIdentity(issuer, subject string) (string, error)
+	CreateUserWithIdentity(user models.User, issuer, subject string) error
+}
+
+type APITokenRepositoryInterface interface {
+	CreateAPIToken(token models.APIToken, tokenHash string) (*models.APIToken, error)
+	ListAPITokens(username string) ([]models.APIToken, error)
+	GetAPITokenByHash(tokenHash string) (*models.APIToken, error)
+	TouchAPIToken(id int, at time.Time) error
+	DeleteAPIToken(username string, id int) error
+}
+
+type TokenRevocationRepositoryInterface interface {
+	RevokeToken(jti, username string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeUserTokens(username string, before time.Time) error
+	// id пользователя и момент, до которого его токены отозваны (nil - отзыва не было)
+	GetTokensRevokedBefore(username string) (int, *time.Time, error)
+	DeleteExpiredRevokedTokens(now time.Time) (int64, error)
 }
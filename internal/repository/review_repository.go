@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type ReviewRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewReviewRepository(db *pgxpool.Pool, log *logrus.Logger) *ReviewRepository {
+	return &ReviewRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Создание отзыва или изменение существующего. Решение модератора при правке сохраняется.
+func (r *ReviewRepository) UpsertReview(review models.Review) (*models.Review, error) {
+	err := r.db.QueryRow(context.Background(),
+		`INSERT INTO reviews (user_id, item_name, rating, text)
+         SELECT id, $2, $3, $4 FROM users WHERE username = $1
+         ON CONFLICT (user_id, item_name)
+         DO UPDATE SET rating = EXCLUDED.rating, text = EXCLUDED.text, updated_at = NOW()
+         RETURNING id, hidden, created_at, updated_at`,
+		review.Username, review.ItemName, review.Rating, review.Text).
+		Scan(&review.ID, &review.Hidden, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		r.log.Errorf("Failed to save review of %s by %s: %v", review.ItemName, review.Username, err)
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Видимые отзывы о товаре, новые сверху
+func (r *ReviewRepository) GetItemReviews(itemName string) ([]models.Review, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT rv.id, u.username, rv.item_name, rv.rating, rv.text, rv.hidden, rv.created_at, rv.updated_at
+         FROM reviews rv
+         JOIN users u ON rv.user_id = u.id
+         WHERE rv.item_name = $1 AND NOT rv.hidden
+         ORDER BY rv.updated_at DESC, rv.id DESC`, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to fetch reviews of %s: %v", itemName, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var review models.Review
+		err = rows.Scan(&review.ID, &review.Username, &review.ItemName, &review.Rating, &review.Text,
+			&review.Hidden, &review.CreatedAt, &review.UpdatedAt)
+		if err != nil {
+			r.log.Errorf("Failed to scan review of %s: %v", itemName, err)
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+func (r *ReviewRepository) SetHidden(reviewID int, hidden bool) error {
+	tag, err := r.db.Exec(context.Background(), "UPDATE reviews SET hidden = $1 WHERE id = $2", hidden, reviewID)
+	if err != nil {
+		r.log.Errorf("Failed to moderate review %d: %v", reviewID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrReviewNotFound
+	}
+	return nil
+}
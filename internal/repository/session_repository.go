@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// Сеанс активен, пока у его семейства есть действующий refresh-токен
+const activeSessionCondition = `EXISTS (SELECT 1 FROM refresh_tokens t
+         WHERE t.family_id = s.family_id AND t.used_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > $2)`
+
+type SessionRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewSessionRepository(db *pgxpool.Pool, log *logrus.Logger) *SessionRepository {
+	return &SessionRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Активные сеансы пользователя, последние использованные первыми
+func (r *SessionRepository) ListSessions(username string, now time.Time) ([]models.Session, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT s.id, s.family_id, s.user_agent, s.ip, s.created_at, s.last_used_at
+         FROM sessions s
+         JOIN users u ON s.user_id = u.id
+         WHERE u.username = $1 AND `+activeSessionCondition+`
+         ORDER BY s.last_used_at DESC, s.id DESC`, username, now)
+	if err != nil {
+		r.log.Errorf("Failed to list sessions of user %s: %v", username, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session := models.Session{Username: username}
+		if err = rows.Scan(&session.ID, &session.FamilyID, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastUsedAt); err != nil {
+			r.log.Errorf("Failed to scan session: %v", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Завершение сеанса: отзыв всех refresh-токенов его семейства. Возвращает семейство сеанса.
+func (r *SessionRepository) RevokeSession(username string, id int) (string, error) {
+	var familyID string
+	err := r.db.QueryRow(context.Background(),
+		`WITH s AS (
+             SELECT s.family_id FROM sessions s
+             JOIN users u ON s.user_id = u.id
+             WHERE s.id = $1 AND u.username = $2
+         ), revoked AS (
+             UPDATE refresh_tokens SET revoked_at = NOW()
+             WHERE family_id IN (SELECT family_id FROM s) AND revoked_at IS NULL
+         )
+         SELECT family_id FROM s`, id, username).Scan(&familyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", models.ErrSessionNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to revoke session %d: %v", id, err)
+		return "", err
+	}
+	return familyID, nil
+}
+
+func (r *SessionRepository) IsSessionActive(familyID string, now time.Time) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM sessions s WHERE s.family_id = $1 AND `+activeSessionCondition+`)`,
+		familyID, now).Scan(&active)
+	if err != nil {
+		r.log.Errorf("Failed to check session: %v", err)
+		return false, err
+	}
+	return active, nil
+}
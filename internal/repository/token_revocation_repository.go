@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type TokenRevocationRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewTokenRevocationRepository(db *pgxpool.Pool, log *logrus.Logger) *TokenRevocationRepository {
+	return &TokenRevocationRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Отзыв одного токена по jti
+func (r *TokenRevocationRepository) RevokeToken(jti, username string, expiresAt time.Time) error {
+	tag, err := r.db.Exec(context.Background(),
+		`INSERT INTO revoked_tokens (jti, user_id, expires_at)
+         SELECT $1, id, $3 FROM users WHERE username = $2
+         ON CONFLICT (jti) DO NOTHING`,
+		jti, username, expiresAt)
+	if err != nil {
+		r.log.Errorf("Failed to revoke token of user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// Токен уже отозван либо пользователя нет
+		var exists bool
+		if err = r.db.QueryRow(context.Background(),
+			"SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+func (r *TokenRevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(context.Background(),
+		"SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	if err != nil {
+		r.log.Errorf("Failed to check token revocation: %v", err)
+	}
+	return revoked, err
+}
+
+// Отзыв всех токенов пользователя, выданных до before, вместе с его refresh-токенами.
+// Персональные API-токены, созданные до before, удаляются.
+// Граница только сдвигается вперёд, более ранняя дата ничего не возвращает.
+func (r *TokenRevocationRepository) RevokeUserTokens(username string, before time.Time) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`UPDATE users SET tokens_revoked_before = GREATEST(tokens_revoked_before, $2)
+         WHERE username = $1 RETURNING id`, username, before).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrUserNotFound
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to revoke tokens of user %s: %v", username, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND created_at <= $2 AND revoked_at IS NULL",
+		userID, before); err != nil {
+		r.log.Errorf("Failed to revoke refresh tokens of user %s: %v", username, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(),
+		"DELETE FROM api_tokens WHERE user_id = $1 AND created_at <= $2", userID, before); err != nil {
+		r.log.Errorf("Failed to delete API tokens of user %s: %v", username, err)
+		return err
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return err
+	}
+	return nil
+}
+
+// id пользователя и момент, до которого его токены отозваны. nil - отзыва не было.
+// id нужен, чтобы токен удалённого пользователя не подошёл новому владельцу освободившегося имени.
+func (r *TokenRevocationRepository) GetTokensRevokedBefore(username string) (int, *time.Time, error) {
+	var userID int
+	var before *time.Time
+	err := r.db.QueryRow(context.Background(),
+		"SELECT id, tokens_revoked_before FROM users WHERE username = $1 AND deleted_at IS NULL", username).Scan(&userID, &before)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get token revocation of user %s: %v", username, err)
+		return 0, nil, err
+	}
+	return userID, before, nil
+}
+
+// Удаление записей об отозванных токенах, которые истекли сами
+func (r *TokenRevocationRepository) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	tag, err := r.db.Exec(context.Background(), "DELETE FROM revoked_tokens WHERE expires_at <= $1", now)
+	if err != nil {
+		r.log.Errorf("Failed to delete expired revoked tokens: %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -19,9 +19,11 @@ func NewTransactionRepository(db *pgxpool.Pool, log *logrus.Logger) *Transaction
 	}
 }
 
+// ID пользователя по имени без учёта регистра. Удалённые аккаунты не находятся, переводить им монеты нельзя.
 func (r *TransactionRepository) GetUserID(username string) (int, error) {
 	var userID int
-	err := r.db.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	err := r.db.QueryRow(context.Background(),
+		"SELECT id FROM users WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL", username).Scan(&userID)
 	if err != nil {
 		r.log.Errorf("Failed to get user ID for username %s: %v", username, err)
 		return 0, err
@@ -58,13 +60,17 @@ func (r *TransactionRepository) TransferCoins(fromUser, toUser string, amount in
 		}
 	}()
 
-	// Вычитаем монеты у отправителя
-	_, err = tx.Exec(context.Background(),
-		"UPDATE users SET balance = balance - $1 WHERE id = $2 AND balance >= $1", amount, fromUserID)
+	// Вычитаем монеты у отправителя, зарезервированные под предзаказы монеты не трогаем
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE users SET balance = balance - $1 WHERE id = $2 AND balance - reserved >= $1", amount, fromUserID)
 	if err != nil {
 		r.log.Error("Failed to update sender balance: ", err)
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		err = models.ErrInsufficientBalance
+		return err
+	}
 
 	// Добавляем монеты получателю
 	_, err = tx.Exec(context.Background(),
@@ -102,7 +108,7 @@ func (r *TransactionRepository) GetReceivedTransactions(username string) ([]mode
 	}
 
 	rows, err := r.db.Query(context.Background(),
-		`SELECT u1.username AS from_user, t.amount, t.timestamp 
+		`SELECT u1.username AS from_user, u1.display_name, u1.department, t.amount, t.timestamp 
         FROM transactions t
         JOIN users u1 ON t.from_user = u1.id
         WHERE t.to_user = $1`, userID)
@@ -115,11 +121,13 @@ func (r *TransactionRepository) GetReceivedTransactions(username string) ([]mode
 	var transactions []models.Transaction
 	for rows.Next() {
 		var transaction models.Transaction
-		err = rows.Scan(&transaction.FromUser, &transaction.Amount, &transaction.Time)
+		var sender models.UserProfile
+		err = rows.Scan(&sender.Username, &sender.DisplayName, &sender.Department, &transaction.Amount, &transaction.Time)
 		if err != nil {
 			r.log.Error("Error scanning received transaction: ", err)
 			continue
 		}
+		transaction.FromUser, transaction.FromName = sender.Username, sender.Label()
 		transactions = append(transactions, transaction)
 	}
 	return transactions, nil
@@ -132,7 +140,7 @@ func (r *TransactionRepository) GetSentTransactions(username string) ([]models.T
 		r.log.Errorf("Failed to get user ID for username %s: %v", username, err)
 	}
 	rows, err := r.db.Query(context.Background(),
-		`SELECT u2.username AS to_user, t.amount, t.timestamp 
+		`SELECT u2.username AS to_user, u2.display_name, u2.department, t.amount, t.timestamp 
         FROM transactions t
         JOIN users u2 ON t.to_user = u2.id
         WHERE t.from_user = $1`, userID)
@@ -145,11 +153,13 @@ func (r *TransactionRepository) GetSentTransactions(username string) ([]models.T
 	var transactions []models.Transaction
 	for rows.Next() {
 		var transaction models.Transaction
-		err = rows.Scan(&transaction.ToUser, &transaction.Amount, &transaction.Time)
+		var recipient models.UserProfile
+		err = rows.Scan(&recipient.Username, &recipient.DisplayName, &recipient.Department, &transaction.Amount, &transaction.Time)
 		if err != nil {
 			r.log.Error("Error scanning sent transaction: ", err)
 			continue
 		}
+		transaction.ToUser, transaction.ToName = recipient.Username, recipient.Label()
 		transactions = append(transactions, transaction)
 	}
 	return transactions, nil
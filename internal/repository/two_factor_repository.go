@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type TwoFactorRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool, log *logrus.Logger) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Сохранение нового секрета, ожидающего подтверждения. Неподтверждённый секрет заменяется,
+// включённую двухфакторную аутентификацию так заменить нельзя.
+func (r *TwoFactorRepository) SaveTOTPSecret(username, secret string) error {
+	tag, err := r.db.Exec(context.Background(),
+		`INSERT INTO user_totp (user_id, secret)
+         SELECT id, $2 FROM users WHERE username = $1
+         ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+         WHERE user_totp.enabled_at IS NULL`,
+		username, secret)
+	if err != nil {
+		r.log.Errorf("Failed to save TOTP secret of user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err = r.db.QueryRow(context.Background(),
+			"SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrUserNotFound
+		}
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+// Секрет TOTP пользователя. nil - двухфакторная аутентификация не настраивалась.
+func (r *TwoFactorRepository) GetTOTPSecret(username string) (*models.TOTPSecret, error) {
+	var secret models.TOTPSecret
+	err := r.db.QueryRow(context.Background(),
+		`SELECT t.secret, t.enabled_at IS NOT NULL, t.last_used_step
+         FROM user_totp t
+         JOIN users u ON t.user_id = u.id
+         WHERE u.username = $1`, username).Scan(&secret.Secret, &secret.Enabled, &secret.LastUsedStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get TOTP secret of user %s: %v", username, err)
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// Включение после подтверждения кодом из интервала step. Коды восстановления заменяются новыми.
+func (r *TwoFactorRepository) EnableTOTP(username string, step int64, recoveryCodeHashes []string) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	var userID int
+	err = tx.QueryRow(context.Background(),
+		`UPDATE user_totp SET enabled_at = NOW(), last_used_step = $2
+         WHERE user_id = (SELECT id FROM users WHERE username = $1) AND enabled_at IS NULL
+         RETURNING user_id`, username, step).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = models.ErrTwoFactorAlreadyEnabled
+		return err
+	}
+	if err != nil {
+		r.log.Errorf("Failed to enable TOTP of user %s: %v", username, err)
+		return err
+	}
+
+	if _, err = tx.Exec(context.Background(), "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		r.log.Errorf("Failed to delete recovery codes of user %s: %v", username, err)
+		return err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err = tx.Exec(context.Background(),
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			r.log.Errorf("Failed to save recovery code of user %s: %v", username, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return err
+	}
+	return nil
+}
+
+// Отметка использованного интервала TOTP. false - код из этого интервала уже принимался
+// (в том числе параллельным запросом).
+func (r *TwoFactorRepository) UseTOTPStep(username string, step int64) (bool, error) {
+	tag, err := r.db.Exec(context.Background(),
+		`UPDATE user_totp SET last_used_step = $2
+         WHERE user_id = (SELECT id FROM users WHERE username = $1)
+           AND enabled_at IS NOT NULL AND last_used_step < $2`, username, step)
+	if err != nil {
+		r.log.Errorf("Failed to use TOTP step of user %s: %v", username, err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Погашение кода восстановления. false - такого неиспользованного кода нет.
+func (r *TwoFactorRepository) UseRecoveryCode(username, codeHash string) (bool, error) {
+	tag, err := r.db.Exec(context.Background(),
+		`UPDATE recovery_codes SET used_at = NOW()
+         WHERE user_id = (SELECT id FROM users WHERE username = $1) AND code_hash = $2 AND used_at IS NULL`,
+		username, codeHash)
+	if err != nil {
+		r.log.Errorf("Failed to use recovery code of user %s: %v", username, err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Отключение двухфакторной аутентификации вместе с кодами восстановления
+func (r *TwoFactorRepository) DisableTOTP(username string) (err error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		r.log.Errorf("Error starting transaction: %s", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+				r.log.Errorf("Transaction rollback failed: %v", rollbackErr)
+			}
+		}
+	}()
+
+	for _, query := range []string{
+		"DELETE FROM recovery_codes WHERE user_id = (SELECT id FROM users WHERE username = $1)",
+		"DELETE FROM user_totp WHERE user_id = (SELECT id FROM users WHERE username = $1)",
+	} {
+		if _, err = tx.Exec(context.Background(), query, username); err != nil {
+			r.log.Errorf("Failed to disable TOTP of user %s: %v", username, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(context.Background()); err != nil {
+		r.log.Errorf("Failed to commit transaction for user %s: %v", username, err)
+		return err
+	}
+	return nil
+}
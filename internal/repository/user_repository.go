@@ -3,6 +3,9 @@ package repository
 import (
 	"ShopAvito/internal/models"
 	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
@@ -19,24 +22,31 @@ func NewUserRepository(db *pgxpool.Pool, log *logrus.Logger) *UserRepository {
 	}
 }
 
+// Имя, занятое с точностью до регистра (в том числе параллельной регистрацией), - ErrUserAlreadyExists
 func (r *UserRepository) CreateUser(user models.User) error {
 	_, err := r.db.Exec(context.Background(),
 		"INSERT INTO users (username, password, balance) VALUES ($1, $2, $3)",
 		user.Username, user.Password, user.Balance)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return models.ErrUserAlreadyExists
+	}
 	return err
 }
 
+// Доступный баланс: монеты, зарезервированные под предзаказы, не учитываются
 func (r *UserRepository) GetUserBalance(username string) (int, error) {
 	var balance int
 	err := r.db.QueryRow(context.Background(),
-		"SELECT balance FROM users WHERE username = $1", username).Scan(&balance)
+		"SELECT balance - reserved FROM users WHERE username = $1", username).Scan(&balance)
 	return balance, err
 }
 
+// Поиск пользователя без учёта регистра имени, возвращается имя в том виде, в каком оно сохранено
 func (r *UserRepository) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
 	err := r.db.QueryRow(context.Background(),
-		"SELECT id, username, password, balance FROM users WHERE username = $1", username).
+		"SELECT id, username, password, balance FROM users WHERE LOWER(username) = LOWER($1)", username).
 		Scan(&user.ID, &user.Username, &user.Password, &user.Balance)
 	if err != nil {
 		return nil, err
@@ -44,18 +54,68 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 	return &user, nil
 }
 
+// Новый баланс не может быть меньше зарезервированных монет
 func (r *UserRepository) UpdateUserBalance(username string, newBalance int) error {
-	_, err := r.db.Exec(context.Background(),
-		"UPDATE users SET balance = $1 WHERE username = $2", newBalance, username)
-	return err
+	tag, err := r.db.Exec(context.Background(),
+		"UPDATE users SET balance = $1 WHERE username = $2 AND $1 >= reserved", newBalance, username)
+	if err != nil {
+		r.log.Errorf("Failed to update balance of user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrInsufficientBalance
+	}
+	return nil
 }
 
+// Проверка без учёта регистра: имена уникальны независимо от регистра
 func (r *UserRepository) UserExists(username string) (bool, error) {
 	var exists bool
 	err := r.db.QueryRow(context.Background(),
-		"SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", username).Scan(&exists)
+		"SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists)
 	if err != nil {
 		r.log.Errorf("The error is in the database request itself to check if the user exists: %s", err.Error())
 	}
 	return exists, err
 }
+
+// Замена хэша пароля: при смене пароля и при пересчёте хэша с новой стоимостью bcrypt
+func (r *UserRepository) UpdatePassword(username, passwordHash string) error {
+	tag, err := r.db.Exec(context.Background(),
+		"UPDATE users SET password = $1 WHERE username = $2", passwordHash, username)
+	if err != nil {
+		r.log.Errorf("Failed to update password of user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Роль пользователя: user, moderator или admin
+func (r *UserRepository) GetUserRole(username string) (string, error) {
+	var role string
+	err := r.db.QueryRow(context.Background(),
+		"SELECT role FROM users WHERE username = $1", username).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", models.ErrUserNotFound
+	}
+	if err != nil {
+		r.log.Errorf("Failed to get role of user %s: %v", username, err)
+	}
+	return role, err
+}
+
+func (r *UserRepository) SetUserRole(username, role string) error {
+	tag, err := r.db.Exec(context.Background(),
+		"UPDATE users SET role = $1 WHERE username = $2", role, username)
+	if err != nil {
+		r.log.Errorf("Failed to set role of user %s: %v", username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"ShopAvito/internal/models"
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+type WishlistRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewWishlistRepository(db *pgxpool.Pool, log *logrus.Logger) *WishlistRepository {
+	return &WishlistRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const wishlistSelect = `SELECT u.username, w.item_name, c.price, w.added_at
+FROM wishlist w
+JOIN users u ON w.user_id = u.id
+JOIN catalog_items c ON w.item_name = c.name`
+
+func (r *WishlistRepository) queryWishlist(query string, args ...any) ([]models.WishlistItem, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		r.log.Errorf("Failed to fetch wishlist: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.WishlistItem
+	for rows.Next() {
+		var item models.WishlistItem
+		if err = rows.Scan(&item.Username, &item.ItemName, &item.ListPrice, &item.AddedAt); err != nil {
+			r.log.Errorf("Failed to scan wishlist item: %v", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *WishlistRepository) GetWishlist(username string) ([]models.WishlistItem, error) {
+	return r.queryWishlist(wishlistSelect+" WHERE u.username = $1 ORDER BY w.added_at", username)
+}
+
+// Записи всех пользователей, ожидающих указанные товары. Пустой список означает весь каталог.
+func (r *WishlistRepository) GetWishlistsByItems(itemNames []string) ([]models.WishlistItem, error) {
+	if len(itemNames) == 0 {
+		return r.queryWishlist(wishlistSelect + " ORDER BY w.id")
+	}
+	return r.queryWishlist(wishlistSelect+" WHERE w.item_name = ANY($1) ORDER BY w.id", itemNames)
+}
+
+func (r *WishlistRepository) AddItem(username, itemName string) error {
+	_, err := r.db.Exec(context.Background(),
+		`INSERT INTO wishlist (user_id, item_name)
+         SELECT id, $2 FROM users WHERE username = $1
+         ON CONFLICT (user_id, item_name) DO NOTHING`,
+		username, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to add %s to wishlist of %s: %v", itemName, username, err)
+		return err
+	}
+	return nil
+}
+
+func (r *WishlistRepository) RemoveItem(username, itemName string) error {
+	tag, err := r.db.Exec(context.Background(),
+		"DELETE FROM wishlist WHERE user_id = (SELECT id FROM users WHERE username = $1) AND item_name = $2",
+		username, itemName)
+	if err != nil {
+		r.log.Errorf("Failed to remove %s from wishlist of %s: %v", itemName, username, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrWishlistItemNotFound
+	}
+	return nil
+}
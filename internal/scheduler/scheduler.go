@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// Job - периодическая фоновая задача. Состояние задач хранится в БД,
+// поэтому после перезапуска сервиса работа продолжается с того же места.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+type Scheduler struct {
+	jobs []Job
+	log  *logrus.Logger
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewScheduler(log *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		log:  log,
+		stop: make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Add(name string, interval time.Duration, run func() error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Запуск всех задач. Каждая задача выполняется сразу при старте, а затем по таймеру.
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	s.log.Infof("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Остановка планировщика с ожиданием завершения текущих запусков
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	s.log.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(job)
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.log.Errorf("Job %s panicked: %v", job.Name, p)
+		}
+	}()
+
+	if err := job.Run(); err != nil {
+		s.log.Errorf("Job %s failed: %v", job.Name, err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/storage"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// AccountService - выгрузка личных данных и удаление аккаунта
+type AccountService struct {
+	accountRepo repository.AccountRepositoryInterface
+	userRepo    repository.UserRepositoryInterface
+	revocations *RevocationStore
+	avatars     storage.BlobStore
+	log         *logrus.Logger
+	now         func() time.Time
+}
+
+func NewAccountService(accountRepo repository.AccountRepositoryInterface, userRepo repository.UserRepositoryInterface, revocations *RevocationStore, avatars storage.BlobStore, log *logrus.Logger) *AccountService {
+	return &AccountService{
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		revocations: revocations,
+		avatars:     avatars,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+// Все данные пользователя: профиль, история монет, покупки и инвентарь
+func (s *AccountService) Export(username string) (*models.AccountExport, error) {
+	export, err := s.accountRepo.ExportAccount(username)
+	if err != nil {
+		return nil, err
+	}
+	export.ExportedAt = s.now().UTC().Truncate(time.Second)
+	withAvatarURL(&export.Profile.UserProfile)
+	s.log.Infof("User %s exported account data", username)
+	return export, nil
+}
+
+// Удаление аккаунта с подтверждением паролем. У пользователей, входящих только через OIDC, пароля нет,
+// им достаточно JWT входа. Все токены и сеансы пользователя перестают действовать, аватар удаляется из хранилища.
+func (s *AccountService) Delete(username, password string) error {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		s.log.Errorf("Error getting user %s: %v", username, err)
+		return models.ErrUserNotFound
+	}
+	if user.Password != "" {
+		if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return models.ErrInvalidPassword
+		}
+	}
+
+	avatarKey, err := s.accountRepo.DeleteAccount(user.Username, s.now())
+	if err != nil {
+		return err
+	}
+	if avatarKey != "" && s.avatars != nil {
+		if err = s.avatars.Delete(avatarKey); err != nil {
+			s.log.Warnf("Failed to delete avatar %s of deleted user: %v", avatarKey, err)
+		}
+	}
+	if s.revocations != nil {
+		s.revocations.ForgetUser(user.Username)
+	}
+	s.log.Infof("User %s deleted account", user.Username)
+	return nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+// По префиксу AuthMiddleware отличает API-токен от JWT
+const APITokenPrefix = "shop_pat_"
+
+// Срок действия API-токена: по умолчанию и наибольший
+const (
+	APITokenDefaultTTLDays = 90
+	APITokenMaxTTLDays     = 365
+)
+
+const apiTokenNameMaxLength = 64
+
+// Время последнего использования обновляется не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+const apiTokenTouchInterval = time.Minute
+
+type APITokenService struct {
+	repo        repository.APITokenRepositoryInterface
+	revocations *RevocationStore
+	log         *logrus.Logger
+	now         func() time.Time
+}
+
+// revocations может быть nil: тогда отзыв всех токенов пользователя API-токены не проверяет
+func NewAPITokenService(repo repository.APITokenRepositoryInterface, revocations *RevocationStore, log *logrus.Logger) *APITokenService {
+	return &APITokenService{
+		repo:        repo,
+		revocations: revocations,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+// Создание токена. Сам токен есть только в ответе, повторно его получить нельзя.
+func (s *APITokenService) Create(username string, req models.CreateAPITokenRequest) (*models.CreatedAPIToken, error) {
+	name := strings.TrimSpace(req.Name)
+	scopes, err := validateAPIToken(name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		return nil, err
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = APITokenDefaultTTLDays
+	}
+
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := APITokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	token, err := s.repo.CreateAPIToken(models.APIToken{
+		Username:  username,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: s.now().Add(time.Duration(days) * 24 * time.Hour).Truncate(time.Second),
+	}, hashAPIToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	s.log.Infof("User %s created API token %q with scopes %v", username, name, scopes)
+	return &models.CreatedAPIToken{APIToken: *token, Token: secret}, nil
+}
+
+func (s *APITokenService) List(username string) ([]models.APIToken, error) {
+	return s.repo.ListAPITokens(username)
+}
+
+func (s *APITokenService) Revoke(username string, id int) error {
+	if err := s.repo.DeleteAPIToken(username, id); err != nil {
+		return err
+	}
+	s.log.Infof("User %s revoked API token %d", username, id)
+	return nil
+}
+
+// Проверка API-токена из заголовка Authorization. Неизвестный, истёкший или отозванный токен - ErrInvalidAPIToken.
+func (s *APITokenService) Authenticate(secret string) (*models.APIToken, error) {
+	if !IsAPIToken(secret) {
+		return nil, models.ErrInvalidAPIToken
+	}
+	token, err := s.repo.GetAPITokenByHash(hashAPIToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !token.ExpiresAt.After(now) {
+		return nil, models.ErrInvalidAPIToken
+	}
+	// Токен, созданный до отзыва всех токенов пользователя (администратором или при смене пароля), не принимается
+	if s.revocations != nil {
+		revoked, err := s.revocations.IsRevoked("", token.Username, 0, token.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, models.ErrInvalidAPIToken
+		}
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err = s.repo.TouchAPIToken(token.ID, now); err != nil {
+			// Не мешает запросу: время использования справочное
+			s.log.Errorf("Error updating last use of API token %d: %v", token.ID, err)
+		}
+	}
+	return token, nil
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// Проверка запроса на создание токена, возвращает области действия без повторов
+func validateAPIToken(name string, scopes []string, expiresInDays int) ([]string, error) {
+	var errs []models.FieldError
+	switch {
+	case name == "":
+		errs = append(errs, fieldError("name", "required", "name is required"))
+	case len([]rune(name)) > apiTokenNameMaxLength:
+		errs = append(errs, fieldError("name", "too_long", fmt.Sprintf("name must be at most %d characters", apiTokenNameMaxLength)))
+	}
+
+	seen := map[string]bool{}
+	var unique []string
+	for _, scope := range scopes {
+		if !isAPITokenScope(scope) {
+			errs = append(errs, fieldError("scopes", "unknown_scope", fmt.Sprintf("unknown scope %q", scope)))
+			continue
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		errs = append(errs, fieldError("scopes", "required", "at least one scope is required"))
+	}
+	sort.Strings(unique)
+
+	if expiresInDays < 0 || expiresInDays > APITokenMaxTTLDays {
+		errs = append(errs, fieldError("expires_in_days", "out_of_range",
+			fmt.Sprintf("expires_in_days must be between 1 and %d", APITokenMaxTTLDays)))
+	}
+	if err := validationError(errs); err != nil {
+		return nil, err
+	}
+	return unique, nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type AuctionService struct {
+	auctionRepo repository.AuctionRepositoryInterface
+	log         *logrus.Logger
+	now         func() time.Time
+}
+
+func NewAuctionService(auctionRepo repository.AuctionRepositoryInterface, log *logrus.Logger) *AuctionService {
+	return &AuctionService{
+		auctionRepo: auctionRepo,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+// Создание аукциона администратором
+func (s *AuctionService) CreateAuction(req models.CreateAuctionRequest) (*models.Auction, error) {
+	itemName := strings.TrimSpace(req.ItemName)
+	if itemName == "" {
+		return nil, errors.New("item name is required")
+	}
+	if req.ReservePrice < 0 {
+		return nil, errors.New("reserve price must not be negative")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, errors.New("auction must end after it starts")
+	}
+	if !req.EndsAt.After(s.now()) {
+		return nil, errors.New("auction end time must be in the future")
+	}
+
+	auction := models.Auction{
+		ItemName:     itemName,
+		ReservePrice: req.ReservePrice,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		Status:       models.AuctionStatusOpen,
+	}
+	id, err := s.auctionRepo.CreateAuction(auction)
+	if err != nil {
+		s.log.Errorf("Error creating auction: %v", err)
+		return nil, err
+	}
+	auction.ID = id
+	return &auction, nil
+}
+
+func (s *AuctionService) GetAuction(id int) (*models.Auction, error) {
+	return s.auctionRepo.GetAuction(id)
+}
+
+func (s *AuctionService) ListOpenAuctions() ([]models.Auction, error) {
+	return s.auctionRepo.ListOpenAuctions()
+}
+
+func (s *AuctionService) GetBids(auctionID int) ([]models.AuctionBid, error) {
+	if _, err := s.auctionRepo.GetAuction(auctionID); err != nil {
+		return nil, err
+	}
+	return s.auctionRepo.GetBids(auctionID)
+}
+
+// Ставка на аукционе
+func (s *AuctionService) PlaceBid(auctionID int, username string, amount int) error {
+	if amount <= 0 {
+		return errors.New("bid amount must be positive")
+	}
+	if err := s.auctionRepo.PlaceBid(auctionID, username, amount, s.now()); err != nil {
+		s.log.Errorf("Error placing bid: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Закрытие всех аукционов, срок которых истёк. Вызывается планировщиком,
+// в том числе сразу после старта, чтобы закрыть аукционы, истёкшие во время простоя.
+func (s *AuctionService) CloseDueAuctions() error {
+	ids, err := s.auctionRepo.GetDueAuctionIDs(s.now())
+	if err != nil {
+		s.log.Errorf("Error fetching due auctions: %v", err)
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err = s.auctionRepo.CloseAuction(id); err != nil {
+			s.log.Errorf("Error closing auction %d: %v", id, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
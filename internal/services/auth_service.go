@@ -3,43 +3,114 @@ package services
 import (
 	"ShopAvito/internal/models"
 	"ShopAvito/internal/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"github.com/golang-jwt/jwt"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+	"sync"
 	"time"
 )
 
+// Время жизни токенов: JWT живёт недолго, дальше он обновляется по refresh-токену.
+// ChallengeTokenTTL - сколько есть времени на ввод кода двухфакторной аутентификации после пароля.
+const (
+	AccessTokenTTL    = 15 * time.Minute
+	RefreshTokenTTL   = 30 * 24 * time.Hour
+	ChallengeTokenTTL = 5 * time.Minute
+)
+
+// Назначение токена двухфакторного входа. Такой токен не принимается как JWT доступа.
+const challengePurpose = "2fa"
+
+// Длиннее User-Agent в списке сеансов не хранится
+const sessionUserAgentMaxLength = 512
+
 type AuthService struct {
-	userRepo  repository.UserRepositoryInterface
-	secretKey string
-	log       *logrus.Logger
+	userRepo    repository.UserRepositoryInterface
+	refreshRepo repository.RefreshTokenRepositoryInterface
+	revocations *RevocationStore
+	keys        *KeySet
+	passwords   PasswordPolicy
+	throttle    *LoginThrottle
+	twoFactor   *TwoFactorService
+	log         *logrus.Logger
+	now         func() time.Time
+
+	dummyOnce sync.Once
+	dummyHash string
 }
 
-func NewAuthService(userRepo repository.UserRepositoryInterface, secretKey string, log *logrus.Logger) *AuthService {
+func NewAuthService(userRepo repository.UserRepositoryInterface, refreshRepo repository.RefreshTokenRepositoryInterface, revocations *RevocationStore, keys *KeySet, passwords PasswordPolicy, throttle *LoginThrottle, twoFactor *TwoFactorService, log *logrus.Logger) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		secretKey: secretKey,
-		log:       log,
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		revocations: revocations,
+		keys:        keys,
+		passwords:   passwords,
+		throttle:    throttle,
+		twoFactor:   twoFactor,
+		log:         log,
+		now:         time.Now,
 	}
 }
 
+// Purpose пуст у JWT доступа и задан у служебных токенов, например у токена двухфакторного входа.
+// Scopes - области действия роли пользователя на момент выдачи, у служебных токенов их нет.
+// Session - семейство refresh-токенов сеанса, в котором выдан JWT; после завершения сеанса JWT не принимается.
+// UserID - id пользователя: имя удалённого аккаунта может занять другой, а токен остаётся привязан к прежнему.
+// IssuedAtMicro - время выдачи в микросекундах: iat хранится с точностью до секунды, а отзыв действует с точного момента.
 type Claims struct {
-	Username string `json:"username"`
+	Username      string   `json:"username"`
+	UserID        int      `json:"uid,omitempty"`
+	Purpose       string   `json:"purpose,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	Session       string   `json:"sid,omitempty"`
+	IssuedAtMicro int64    `json:"iat_us,omitempty"`
 	jwt.StandardClaims
 }
 
+// JWT доступа с областями действия текущей роли пользователя, не привязанный к сеансу
 func (s *AuthService) GenerateToken(username string) (string, error) {
-	claims := &Claims{
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
-			IssuedAt:  time.Now().Unix(),
-		},
+	return s.accessToken(username, "")
+}
+
+func (s *AuthService) accessToken(username, session string) (string, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		s.log.Errorf("Error getting user %s: %v", username, err)
+		return "", err
+	}
+	role, err := s.userRepo.GetUserRole(username)
+	if err != nil {
+		s.log.Errorf("Error getting role of user %s: %v", username, err)
+		return "", err
+	}
+	return s.signToken(&Claims{Username: username, UserID: user.ID, Scopes: RoleScopes(role), Session: session}, AccessTokenTTL)
+}
+
+// Подпись токена: к claims добавляются jti и сроки
+func (s *AuthService) signToken(claims *Claims, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := s.now()
+	claims.IssuedAtMicro = now.UnixMicro()
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        hex.EncodeToString(jti),
+		ExpiresAt: now.Add(ttl).Unix(),
+		IssuedAt:  now.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	rToken, err := token.SignedString([]byte(s.secretKey))
+	key := s.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	rToken, err := token.SignedString(key.signKey)
 	if err != nil {
 		s.log.Errorf("Error while signing token: %v", err)
 		return "", err
@@ -47,10 +118,24 @@ func (s *AuthService) GenerateToken(username string) (string, error) {
 	return rToken, nil
 }
 
+// Проверка JWT доступа: подпись и срок действия. Принимаются только алгоритмы наших ключей,
+// ключ выбирается по kid из заголовка токена. Служебные токены отклоняются.
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.secretKey), nil
-	})
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		s.log.Errorf("Token with purpose %q used as access token", claims.Purpose)
+		return nil, errors.New("invalid token")
+	}
+	s.log.Infof("Token validated successfully. Username: %s", claims.Username)
+	return claims, nil
+}
+
+func (s *AuthService) parseToken(tokenString string) (*Claims, error) {
+	parser := &jwt.Parser{ValidMethods: s.keys.algorithms()}
+	token, err := parser.ParseWithClaims(tokenString, &Claims{}, s.keys.keyFunc(s.now()))
 
 	if err != nil {
 		s.log.Errorf("Token validation error: %v", err)
@@ -62,60 +147,366 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 		s.log.Error("Invalid token or claims")
 		return nil, errors.New("invalid token")
 	}
-
-	s.log.Infof("Token validated successfully. Username: %s", claims.Username)
 	return claims, nil
 }
 
-// Логин (проверка пароля и выдача токена)
-func (s *AuthService) Login(username, password string) (string, error) {
+// Логин (проверка пароля и выдача токенов). IP-адрес клиента нужен для учёта неудачных попыток и,
+// вместе с User-Agent, для списка сеансов. Во время блокировки возвращается *models.LoginLockedError,
+// пароль не проверяется.
+func (s *AuthService) Login(username, password string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if err := validateLogin(username, password); err != nil {
+		return nil, err
+	}
+	// Попытка учитывается как неудачная до проверки пароля. Несуществующее имя тоже считается, иначе по
+	// отсутствию блокировки можно было бы узнать, что такого пользователя нет.
+	attempt, err := s.reserveLoginAttempt(username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetUserByUsername(username)
 	if err != nil {
 		s.log.Errorf("Error login while getting user by username: %v", err)
-		return "", errors.New("user not found")
+		// Пароль всё равно сравнивается, чтобы по времени ответа нельзя было узнать, что пользователя нет
+		_ = s.passwords.compare(s.dummyPasswordHash(), password)
+		return nil, models.ErrUserNotFound
 	}
 
 	// Проверяем пароль
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	if err = s.passwords.compare(user.Password, password); err != nil {
 		s.log.Errorf("Error login while comparing password: %v", err)
-		return "", errors.New("invalid password")
+		return nil, models.ErrInvalidPassword
 	}
+	attempt.Release()
+	s.rehashPassword(user, password)
+
+	return s.completeLogin(user.Username, client)
+}
 
-	// Генерируем токен
-	return s.GenerateToken(user.Username)
+// Вход пользователя, которого уже опознал внешний провайдер (OpenID Connect). Пароль не проверяется,
+// двухфакторная аутентификация, если включена, требуется так же, как при входе по паролю.
+func (s *AuthService) ExternalLogin(username string, client models.ClientInfo) (*models.AuthResponse, error) {
+	return s.completeLogin(username, client)
 }
 
-// Регистрация (создание пользователя и выдача токена)
-func (s *AuthService) Register(username, password string) (string, error) {
-	// Проверяем, есть ли такой пользователь
+// Завершение входа после проверки пароля: токены или, с двухфакторной аутентификацией, токен второго шага
+func (s *AuthService) completeLogin(username string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if s.twoFactor != nil {
+		enabled, err := s.twoFactor.IsEnabled(username)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			challenge, err := s.signToken(&Claims{Username: username, Purpose: challengePurpose}, ChallengeTokenTTL)
+			if err != nil {
+				return nil, err
+			}
+			return &models.AuthResponse{TwoFactorRequired: true, ChallengeToken: challenge, ExpiresIn: int(ChallengeTokenTTL.Seconds())}, nil
+		}
+	}
+	s.loginSucceeded(username)
+
+	// Генерируем токены, вход начинает новый сеанс и новое семейство refresh-токенов
+	return s.issueTokens(username, client)
+}
+
+// Второй шаг входа: код TOTP или код восстановления в обмен на токен из ответа Login.
+// Неверные коды учитываются так же, как неверные пароли.
+func (s *AuthService) VerifyTwoFactor(challengeToken, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if s.twoFactor == nil {
+		return nil, models.ErrTwoFactorNotEnabled
+	}
+	claims, err := s.parseToken(challengeToken)
+	if err != nil || claims.Purpose != challengePurpose {
+		return nil, models.ErrInvalidChallenge
+	}
+	attempt, err := s.reserveLoginAttempt(claims.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.twoFactor.Verify(claims.Username, code)
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		return nil, err
+	}
+	attempt.Release()
+	if err != nil {
+		return nil, err
+	}
+	s.loginSucceeded(claims.Username)
+	return s.issueTokens(claims.Username, client)
+}
+
+// Успешный вход сбрасывает счётчик неудачных попыток
+func (s *AuthService) loginSucceeded(username string) {
+	if s.throttle == nil {
+		return
+	}
+	if err := s.throttle.RecordSuccess(username); err != nil {
+		s.log.Errorf("Error resetting failed login attempts: %v", err)
+	}
+}
+
+// Пересчёт хэша после смены стоимости bcrypt. Пароль известен только при входе, поэтому хэши
+// обновляются постепенно; ошибка не мешает войти.
+func (s *AuthService) rehashPassword(user *models.User, password string) {
+	if !s.passwords.needsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := s.passwords.hash(password)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(user.Username, hashedPassword)
+	}
+	if err != nil {
+		s.log.Errorf("Error upgrading password hash of user %s: %v", user.Username, err)
+		return
+	}
+	s.log.Infof("Upgraded password hash of user %s", user.Username)
+}
+
+// Хэш с настроенной стоимостью bcrypt для входа под несуществующим именем. Считается один раз, при первой надобности.
+func (s *AuthService) dummyPasswordHash() string {
+	s.dummyOnce.Do(func() {
+		hashed, err := s.passwords.hash("dummy password")
+		if err != nil {
+			s.log.Errorf("Error hashing dummy password: %v", err)
+		}
+		s.dummyHash = hashed
+	})
+	return s.dummyHash
+}
+
+// Резерв попытки входа до проверки пароля или кода; без защиты от перебора - nil
+func (s *AuthService) reserveLoginAttempt(username, clientIP string) (*LoginAttempt, error) {
+	if s.throttle == nil {
+		return nil, nil
+	}
+	return s.throttle.Reserve(username, clientIP)
+}
+
+// Снятие блокировки входа администратором по имени и/или IP-адресу
+func (s *AuthService) UnlockLogin(username, ip string) error {
+	if s.throttle == nil {
+		return errors.New("login throttling is not configured")
+	}
+	if err := s.throttle.Unlock(username, ip); err != nil {
+		return err
+	}
+	s.log.Infof("Login unlocked for user %q, ip %q", username, ip)
+	return nil
+}
+
+// Удаление устаревших счётчиков неудачных попыток входа. Запускается планировщиком.
+func (s *AuthService) PurgeLoginAttempts() error {
+	if s.throttle == nil {
+		return nil
+	}
+	deleted, err := s.throttle.PurgeStale()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.log.Infof("Deleted %d stale login attempt counters", deleted)
+	}
+	return nil
+}
+
+// Регистрация (создание пользователя и выдача токенов)
+func (s *AuthService) Register(username, password string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if err := s.passwords.validateRegistration(username, password); err != nil {
+		return nil, err
+	}
+
+	// Проверяем, есть ли такой пользователь (без учёта регистра)
 	exists, err := s.userRepo.UserExists(username)
 	if err != nil {
 		s.log.Errorf("Error finding user: %v", err)
-		return "", err
+		return nil, err
 	}
 	if exists {
-		return "", errors.New("user already exists")
+		return nil, models.ErrUserAlreadyExists
 	}
 
 	// Хешируем пароль
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.hash(password)
 	if err != nil {
 		s.log.Errorf("Error hashing password: %v", err)
-		return "", err
+		return nil, err
 	}
 
 	// Создаём пользователя
 	user := models.User{
 		Username: username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		Balance:  1000,
 	}
 	err = s.userRepo.CreateUser(user)
 	if err != nil {
 		s.log.Errorf("Error creating user: %v", err)
-		return "", err
+		return nil, err
+	}
+
+	// Генерируем токены
+	return s.issueTokens(username, client)
+}
+
+// Обмен refresh-токена на новую пару токенов. Каждый refresh-токен одноразовый:
+// повторное предъявление уже обменянного токена означает его утечку, и всё семейство отзывается.
+func (s *AuthService) Refresh(refreshToken string) (*models.AuthResponse, error) {
+	next, nextHash, err := newRefreshToken()
+	if err != nil {
+		s.log.Errorf("Error generating refresh token: %v", err)
+		return nil, err
+	}
+
+	tokenHash := hashRefreshToken(refreshToken)
+	previous, err := s.refreshRepo.RotateRefreshToken(tokenHash,
+		models.RefreshToken{TokenHash: nextHash, ExpiresAt: s.now().Add(RefreshTokenTTL)}, s.now())
+	if errors.Is(err, models.ErrRefreshTokenReused) {
+		s.log.Warn("Refresh token reuse detected, revoking token family")
+		if revokeErr := s.refreshRepo.RevokeRefreshTokenFamily(tokenHash); revokeErr != nil {
+			s.log.Errorf("Error revoking refresh token family: %v", revokeErr)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.accessToken(previous.Username, previous.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthResponse{Token: token, RefreshToken: next, ExpiresIn: int(AccessTokenTTL.Seconds())}, nil
+}
+
+// Выход: отзыв семейства refresh-токенов текущего входа. Если передан JWT, он тоже отзывается,
+// чтобы им нельзя было пользоваться до истечения.
+func (s *AuthService) Logout(refreshToken, accessToken string) error {
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(hashRefreshToken(refreshToken)); err != nil {
+		return err
+	}
+	if accessToken == "" || s.revocations == nil {
+		return nil
+	}
+	claims, err := s.ValidateToken(accessToken)
+	if err != nil {
+		// Истёкший или чужой JWT отзывать не нужно
+		return nil
+	}
+	return s.revocations.RevokeToken(claims.Id, claims.Username, time.Unix(claims.ExpiresAt, 0))
+}
+
+// Проверка токена по списку отзыва
+func (s *AuthService) IsTokenRevoked(claims *Claims) (bool, error) {
+	if s.revocations == nil {
+		return false, nil
+	}
+	// У токенов, выданных до появления iat_us, время выдачи известно только с точностью до секунды
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if claims.IssuedAtMicro != 0 {
+		issuedAt = time.UnixMicro(claims.IssuedAtMicro)
 	}
+	return s.revocations.IsRevoked(claims.Id, claims.Username, claims.UserID, issuedAt)
+}
+
+// Отзыв администратором всех токенов пользователя, выданных до before (по умолчанию - до текущего момента),
+// включая refresh-токены
+func (s *AuthService) RevokeUserTokens(username string, before *time.Time) (time.Time, error) {
+	cutoff := s.now()
+	if before != nil {
+		cutoff = *before
+	}
+	if s.revocations == nil {
+		return cutoff, errors.New("token revocation is not configured")
+	}
+	if err := s.revocations.RevokeUserTokens(username, cutoff); err != nil {
+		return cutoff, err
+	}
+	s.log.Infof("Revoked tokens of user %s issued before %s", username, cutoff.Format(time.RFC3339))
+	return cutoff, nil
+}
+
+// Удаление истёкших refresh-токенов и записей об отозванных JWT. Запускается планировщиком.
+func (s *AuthService) PurgeExpiredTokens() error {
+	deleted, err := s.refreshRepo.DeleteExpiredRefreshTokens(s.now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.log.Infof("Deleted %d expired refresh tokens", deleted)
+	}
+
+	if s.revocations == nil {
+		return nil
+	}
+	deleted, err = s.revocations.PurgeExpired()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.log.Infof("Deleted %d expired revoked tokens", deleted)
+	}
+	return nil
+}
+
+// Выдача JWT и refresh-токена из нового семейства. Семейство - это новый сеанс входа,
+// он запоминается вместе с IP-адресом и User-Agent клиента.
+func (s *AuthService) issueTokens(username string, client models.ClientInfo) (*models.AuthResponse, error) {
+	refreshToken, tokenHash, err := newRefreshToken()
+	if err != nil {
+		s.log.Errorf("Error generating refresh token: %v", err)
+		return nil, err
+	}
+	rawFamilyID := make([]byte, 16)
+	if _, err = rand.Read(rawFamilyID); err != nil {
+		return nil, err
+	}
+	familyID := hex.EncodeToString(rawFamilyID)
+
+	token, err := s.accessToken(username, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := []rune(client.UserAgent)
+	if len(userAgent) > sessionUserAgentMaxLength {
+		userAgent = userAgent[:sessionUserAgentMaxLength]
+	}
+	err = s.refreshRepo.CreateSession(models.Session{
+		Username:  username,
+		FamilyID:  familyID,
+		UserAgent: string(userAgent),
+		IP:        client.IP,
+	}, models.RefreshToken{
+		Username:  username,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: s.now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{Token: token, RefreshToken: refreshToken, ExpiresIn: int(AccessTokenTTL.Seconds())}, nil
+}
+
+// Случайный refresh-токен и его хэш для хранения
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	// Генерируем токен
-	return s.GenerateToken(username)
+// Открытые ключи подписи в формате JWKS
+func (s *AuthService) JWKS() models.JWKS {
+	return s.keys.JWKS(s.now())
 }
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения имени пользователя
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+)
+
+// bcrypt учитывает только первые 72 байта пароля, более длинные пароли отклоняются,
+// чтобы два разных пароля не давали один и тот же хэш
+const PasswordMaxBytes = 72
+
+// Предельные размеры полей при входе: проверяются до обращения к базе и bcrypt
+const (
+	loginUsernameMaxBytes = 256
+	loginPasswordMaxBytes = 1024
+)
+
+const defaultPasswordMinLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// PasswordPolicy - требования к паролю и стоимость bcrypt для новых хэшей.
+// Нулевые MinLength и Cost означают значения по умолчанию, nil Compare - bcrypt.CompareHashAndPassword.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Cost          int
+	Compare       func(hashed, password []byte) error
+}
+
+// Проверка имени пользователя: латиница, цифры, точка, дефис и подчёркивание, начинается с буквы или цифры.
+// Уникальность без учёта регистра обеспечивается индексом в базе.
+func ValidateUsername(username string) []models.FieldError {
+	if username == "" {
+		return []models.FieldError{fieldError("username", "required", "username is required")}
+	}
+	if length := utf8.RuneCountInString(username); length < UsernameMinLength {
+		return []models.FieldError{fieldError("username", "too_short",
+			fmt.Sprintf("username must be at least %d characters", UsernameMinLength))}
+	} else if length > UsernameMaxLength {
+		return []models.FieldError{fieldError("username", "too_long",
+			fmt.Sprintf("username must be at most %d characters", UsernameMaxLength))}
+	}
+	if !usernamePattern.MatchString(username) {
+		return []models.FieldError{fieldError("username", "invalid_characters",
+			"username may contain only latin letters, digits, '.', '_' and '-' and must start with a letter or digit")}
+	}
+	return nil
+}
+
+// Проверка пароля по политике. Возвращает все нарушения сразу, чтобы клиент мог показать их вместе.
+func (p PasswordPolicy) Validate(password, username string) []models.FieldError {
+	if password == "" {
+		return []models.FieldError{fieldError("password", "required", "password is required")}
+	}
+
+	var errs []models.FieldError
+	minLength := p.MinLength
+	if minLength <= 0 {
+		minLength = defaultPasswordMinLength
+	}
+	if utf8.RuneCountInString(password) < minLength {
+		errs = append(errs, fieldError("password", "too_short",
+			fmt.Sprintf("password must be at least %d characters", minLength)))
+	}
+	if len(password) > PasswordMaxBytes {
+		errs = append(errs, fieldError("password", "too_long",
+			fmt.Sprintf("password must be at most %d bytes", PasswordMaxBytes)))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		errs = append(errs, fieldError("password", "missing_uppercase", "password must contain an uppercase letter"))
+	}
+	if p.RequireLower && !hasLower {
+		errs = append(errs, fieldError("password", "missing_lowercase", "password must contain a lowercase letter"))
+	}
+	if p.RequireDigit && !hasDigit {
+		errs = append(errs, fieldError("password", "missing_digit", "password must contain a digit"))
+	}
+	if p.RequireSymbol && !hasSymbol {
+		errs = append(errs, fieldError("password", "missing_symbol", "password must contain a symbol"))
+	}
+	// Короткие имена не проверяются: иначе однобуквенное имя запрещало бы половину паролей
+	if utf8.RuneCountInString(username) >= UsernameMinLength && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		errs = append(errs, fieldError("password", "contains_username", "password must not contain the username"))
+	}
+	return errs
+}
+
+// Хэш пароля с настроенной стоимостью bcrypt
+func (p PasswordPolicy) hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.cost())
+	return string(hashed), err
+}
+
+// Сравнение пароля с хэшем при входе
+func (p PasswordPolicy) compare(hashed, password string) error {
+	if p.Compare != nil {
+		return p.Compare([]byte(hashed), []byte(password))
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+}
+
+// Хэш, посчитанный с другой стоимостью, пересчитывается при следующем входе
+func (p PasswordPolicy) needsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err == nil && cost != p.cost()
+}
+
+func (p PasswordPolicy) cost() int {
+	if p.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return p.Cost
+}
+
+// Проверка данных регистрации
+func (p PasswordPolicy) validateRegistration(username, password string) error {
+	errs := ValidateUsername(username)
+	errs = append(errs, p.Validate(password, username)...)
+	return validationError(errs)
+}
+
+// При входе правила регистрации не применяются - они могли измениться после создания аккаунта.
+// Отсекаются только пустые и заведомо слишком длинные значения.
+func validateLogin(username, password string) error {
+	var errs []models.FieldError
+	if username == "" {
+		errs = append(errs, fieldError("username", "required", "username is required"))
+	} else if len(username) > loginUsernameMaxBytes {
+		errs = append(errs, fieldError("username", "too_long",
+			fmt.Sprintf("username must be at most %d bytes", loginUsernameMaxBytes)))
+	}
+	if password == "" {
+		errs = append(errs, fieldError("password", "required", "password is required"))
+	} else if len(password) > loginPasswordMaxBytes {
+		errs = append(errs, fieldError("password", "too_long",
+			fmt.Sprintf("password must be at most %d bytes", loginPasswordMaxBytes)))
+	}
+	return validationError(errs)
+}
+
+func fieldError(field, code, message string) models.FieldError {
+	return models.FieldError{Field: field, Code: code, Message: message}
+}
+
+func validationError(errs []models.FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &models.ValidationError{Fields: errs}
+}
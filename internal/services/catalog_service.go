@@ -0,0 +1,311 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type CatalogService struct {
+	catalogRepo  repository.CatalogRepositoryInterface
+	discountRepo repository.DiscountRepositoryInterface
+	notifier     WishlistNotifier
+	log          *logrus.Logger
+	now          func() time.Time
+}
+
+func NewCatalogService(catalogRepo repository.CatalogRepositoryInterface, discountRepo repository.DiscountRepositoryInterface, notifier WishlistNotifier, log *logrus.Logger) *CatalogService {
+	return &CatalogService{
+		catalogRepo:  catalogRepo,
+		discountRepo: discountRepo,
+		notifier:     notifier,
+		log:          log,
+		now:          time.Now,
+	}
+}
+
+// Ключ варианта строится по осям товара в порядке их объявления: "size=M", "colour=black,size=M"
+func VariantKey(axes []string, attributes map[string]string) (string, error) {
+	if len(axes) == 0 {
+		if len(attributes) > 0 {
+			return "", models.ErrInvalidVariant
+		}
+		return models.DefaultVariant, nil
+	}
+	if len(attributes) != len(axes) {
+		return "", models.ErrInvalidVariant
+	}
+
+	parts := make([]string, 0, len(axes))
+	for _, axis := range axes {
+		value := strings.TrimSpace(attributes[axis])
+		if value == "" || strings.ContainsAny(value, "=,") {
+			return "", models.ErrInvalidVariant
+		}
+		parts = append(parts, axis+"="+value)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Размер страницы каталога по умолчанию и максимальный
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Поиск по каталогу с пометкой, хватает ли баланса на каждый товар. Баланс сравнивается с ценой
+// с учётом действующих кампаний, как при покупке.
+func (s *CatalogService) SearchItems(query models.CatalogQuery, balance int) (*models.CatalogPage, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	switch query.Sort {
+	case "", models.SortByName, models.SortByPrice, models.SortByPriceDesc, models.SortByRating:
+	default:
+		return nil, errors.New("sort must be one of name, price, -price, rating")
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 {
+		query.PerPage = defaultPageSize
+	}
+	if query.PerPage > maxPageSize {
+		query.PerPage = maxPageSize
+	}
+
+	items, total, err := s.catalogRepo.SearchItems(query)
+	if err != nil {
+		s.log.Errorf("Error searching catalog: %v", err)
+		return nil, err
+	}
+	campaigns, err := s.discountRepo.ListActiveCampaigns(s.now())
+	if err != nil {
+		s.log.Errorf("Error getting active campaigns: %v", err)
+		return nil, err
+	}
+	for i := range items {
+		discount, _ := bestCampaignDiscount(campaigns, items[i].Name, items[i].Price)
+		affordable := items[i].Price-discount <= balance
+		items[i].Affordable = &affordable
+	}
+	if items == nil {
+		items = []models.CatalogItem{}
+	}
+	return &models.CatalogPage{Items: items, Total: total, Page: query.Page, PerPage: query.PerPage}, nil
+}
+
+func (s *CatalogService) ListCategories() ([]models.Category, error) {
+	return s.catalogRepo.ListCategories()
+}
+
+func (s *CatalogService) GetItem(name string) (*models.CatalogItem, error) {
+	return s.catalogRepo.GetItem(name)
+}
+
+// Определение варианта товара по значениям его осей
+func (s *CatalogService) ResolveVariant(item *models.CatalogItem, attributes map[string]string) (string, error) {
+	key, err := VariantKey(item.VariantAxes, attributes)
+	if err != nil {
+		return "", err
+	}
+	for _, variant := range item.Variants {
+		if variant.Key == key {
+			return key, nil
+		}
+	}
+	return "", models.ErrVariantNotFound
+}
+
+// Добавление варианта или изменение его остатка администратором
+func (s *CatalogService) UpsertVariant(itemName string, req models.UpsertVariantRequest) (*models.CatalogVariant, error) {
+	if req.Stock != nil && *req.Stock < 0 {
+		return nil, errors.New("stock must not be negative")
+	}
+
+	item, err := s.catalogRepo.GetItem(itemName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := VariantKey(item.VariantAxes, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := models.CatalogVariant{
+		Key:        key,
+		Attributes: req.Attributes,
+		Stock:      req.Stock,
+	}
+	if variant.Attributes == nil {
+		variant.Attributes = map[string]string{}
+	}
+	if err = s.catalogRepo.UpsertVariant(itemName, variant); err != nil {
+		s.log.Errorf("Error upserting variant %s of %s: %v", key, itemName, err)
+		return nil, err
+	}
+
+	// Закончившийся вариант снова в наличии
+	for _, previous := range item.Variants {
+		soldOut := previous.Stock != nil && *previous.Stock == 0
+		inStock := variant.Stock == nil || *variant.Stock > 0
+		if previous.Key == key && soldOut && inStock && s.notifier != nil {
+			s.notifier.ItemRestocked(itemName, key)
+		}
+	}
+	return &variant, nil
+}
+
+// Создание набора из существующих товаров. Компоненты без варианта наследуют вариант набора,
+// поэтому у них должны совпадать оси, а варианты набора - общие для всех таких компонентов.
+func (s *CatalogService) CreateBundle(req models.CreateBundleRequest) (*models.CatalogItem, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || req.Price < 0 || len(req.Components) == 0 {
+		return nil, models.ErrInvalidBundle
+	}
+
+	bundle := models.CatalogItem{
+		Name:        name,
+		Price:       req.Price,
+		Description: strings.TrimSpace(req.Description),
+		Category:    strings.TrimSpace(req.Category),
+		Tags:        req.Tags,
+	}
+	if bundle.Category == "" {
+		bundle.Category = "bundles"
+	}
+	if bundle.Tags == nil {
+		bundle.Tags = []string{}
+	}
+	var inherited []*models.CatalogItem
+	seen := make(map[string]bool, len(req.Components))
+	for _, component := range req.Components {
+		if component.Quantity == 0 {
+			component.Quantity = 1
+		}
+		if component.Quantity < 0 || component.ItemName == name || seen[component.ItemName] {
+			return nil, models.ErrInvalidBundle
+		}
+		seen[component.ItemName] = true
+
+		item, err := s.catalogRepo.GetItem(component.ItemName)
+		if err != nil {
+			return nil, err
+		}
+		if len(item.Components) > 0 {
+			return nil, fmt.Errorf("%w: %s is a bundle itself", models.ErrInvalidBundle, item.Name)
+		}
+
+		if component.Variant == "" {
+			inherited = append(inherited, item)
+		} else if !hasVariant(item, component.Variant) {
+			return nil, models.ErrVariantNotFound
+		}
+		bundle.Components = append(bundle.Components, component)
+	}
+
+	if len(inherited) == 0 {
+		bundle.Variants = []models.CatalogVariant{{Key: models.DefaultVariant, Attributes: map[string]string{}}}
+	} else {
+		bundle.VariantAxes = inherited[0].VariantAxes
+		for _, item := range inherited[1:] {
+			if strings.Join(item.VariantAxes, ",") != strings.Join(bundle.VariantAxes, ",") {
+				return nil, fmt.Errorf("%w: %s has different variant axes", models.ErrInvalidBundle, item.Name)
+			}
+		}
+		for _, variant := range inherited[0].Variants {
+			shared := true
+			for _, item := range inherited[1:] {
+				if !hasVariant(item, variant.Key) {
+					shared = false
+					break
+				}
+			}
+			if shared {
+				bundle.Variants = append(bundle.Variants, models.CatalogVariant{Key: variant.Key, Attributes: variant.Attributes})
+			}
+		}
+		if len(bundle.Variants) == 0 {
+			return nil, fmt.Errorf("%w: components have no common variants", models.ErrInvalidBundle)
+		}
+	}
+
+	if err := s.catalogRepo.CreateBundle(bundle); err != nil {
+		s.log.Errorf("Error creating bundle %s: %v", name, err)
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func hasVariant(item *models.CatalogItem, key string) bool {
+	for _, variant := range item.Variants {
+		if variant.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Изменение цены товара администратором: сразу или с указанной даты
+func (s *CatalogService) SchedulePriceChange(itemName string, req models.SchedulePriceRequest, changedBy string) (*models.PriceChange, error) {
+	if req.Price < 0 {
+		return nil, errors.New("price must not be negative")
+	}
+	if _, err := s.catalogRepo.GetItem(itemName); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	change := models.PriceChange{
+		ItemName:    itemName,
+		Price:       req.Price,
+		EffectiveAt: now,
+		ChangedBy:   changedBy,
+	}
+	if req.EffectiveAt != nil && req.EffectiveAt.After(now) {
+		change.EffectiveAt = *req.EffectiveAt
+	}
+
+	id, err := s.catalogRepo.AddPriceChange(change)
+	if err != nil {
+		s.log.Errorf("Error scheduling price change for %s: %v", itemName, err)
+		return nil, err
+	}
+	change.ID = id
+
+	// Немедленное изменение применяется сразу, не дожидаясь планировщика
+	if !change.EffectiveAt.After(now) {
+		if err = s.ApplyDuePriceChanges(); err != nil {
+			return nil, err
+		}
+		change.Applied = true
+	}
+	return &change, nil
+}
+
+func (s *CatalogService) GetPriceHistory(itemName string) ([]models.PriceChange, error) {
+	if _, err := s.catalogRepo.GetItem(itemName); err != nil {
+		return nil, err
+	}
+	return s.catalogRepo.GetPriceHistory(itemName)
+}
+
+func (s *CatalogService) CancelPriceChange(id int) error {
+	return s.catalogRepo.DeletePendingPriceChange(id)
+}
+
+// Фоновая задача: перенос наступивших изменений цен в каталог
+func (s *CatalogService) ApplyDuePriceChanges() error {
+	applied, err := s.catalogRepo.ApplyDuePriceChanges(s.now())
+	if err != nil {
+		s.log.Errorf("Error applying price changes: %v", err)
+		return err
+	}
+	for _, change := range applied {
+		s.log.Infof("Price of %s changed to %d by %s", change.ItemName, change.Price, change.ChangedBy)
+	}
+	return nil
+}
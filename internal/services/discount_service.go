@@ -0,0 +1,213 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"crypto/rand"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"strings"
+	"time"
+)
+
+type DiscountService struct {
+	discountRepo repository.DiscountRepositoryInterface
+	notifier     WishlistNotifier
+	log          *logrus.Logger
+	now          func() time.Time
+}
+
+func NewDiscountService(discountRepo repository.DiscountRepositoryInterface, notifier WishlistNotifier, log *logrus.Logger) *DiscountService {
+	return &DiscountService{
+		discountRepo: discountRepo,
+		notifier:     notifier,
+		log:          log,
+		now:          time.Now,
+	}
+}
+
+// Размер скидки в монетах, не больше самой цены
+func discountAmount(price int, discountType string, value int) int {
+	var amount int
+	switch discountType {
+	case models.DiscountPercent:
+		amount = price * value / 100
+	case models.DiscountFixed:
+		amount = value
+	}
+	if amount > price {
+		return price
+	}
+	return amount
+}
+
+func appliesTo(itemNames []string, itemName string) bool {
+	if len(itemNames) == 0 {
+		return true
+	}
+	for _, name := range itemNames {
+		if name == itemName {
+			return true
+		}
+	}
+	return false
+}
+
+func validateDiscount(discountType string, value int) error {
+	switch discountType {
+	case models.DiscountPercent:
+		if value <= 0 || value > 100 {
+			return errors.New("percent discount must be between 1 and 100")
+		}
+	case models.DiscountFixed:
+		if value <= 0 {
+			return errors.New("fixed discount must be positive")
+		}
+	default:
+		return errors.New("discount type must be percent or fixed")
+	}
+	return nil
+}
+
+// Самая выгодная скидка из кампаний, действующих на товар, и id давшей её кампании
+func bestCampaignDiscount(campaigns []models.Campaign, itemName string, listPrice int) (int, *int) {
+	var discount int
+	var campaignID *int
+	for _, campaign := range campaigns {
+		if !appliesTo(campaign.ItemNames, itemName) {
+			continue
+		}
+		if amount := discountAmount(listPrice, campaign.DiscountType, campaign.DiscountValue); amount > discount {
+			id := campaign.ID
+			discount = amount
+			campaignID = &id
+		}
+	}
+	return discount, campaignID
+}
+
+// Расчёт цены: сначала лучшая из действующих кампаний, затем промокод на оставшуюся сумму
+func quotePrice(discountRepo repository.DiscountRepositoryInterface, itemName string, listPrice int, promoCode string, now time.Time) (models.PriceQuote, error) {
+	quote := models.PriceQuote{ListPrice: listPrice, Price: listPrice}
+
+	campaigns, err := discountRepo.GetActiveCampaigns(itemName, now)
+	if err != nil {
+		return quote, err
+	}
+	quote.Discount, quote.CampaignID = bestCampaignDiscount(campaigns, itemName, listPrice)
+
+	if promoCode != "" {
+		promo, err := discountRepo.GetPromoCode(strings.ToUpper(strings.TrimSpace(promoCode)))
+		if err != nil {
+			return quote, err
+		}
+		if promo.Uses >= promo.MaxUses || (promo.ExpiresAt != nil && !promo.ExpiresAt.After(now)) {
+			return quote, models.ErrPromoCodeExpired
+		}
+		if !appliesTo(promo.ItemNames, itemName) {
+			return quote, models.ErrPromoCodeNotApplicable
+		}
+		id := promo.ID
+		quote.Discount += discountAmount(listPrice-quote.Discount, promo.DiscountType, promo.DiscountValue)
+		quote.PromoCodeID = &id
+	}
+
+	quote.Price = listPrice - quote.Discount
+	return quote, nil
+}
+
+// Предварительный расчёт цены со скидками
+func (s *DiscountService) Quote(itemName string, listPrice int, promoCode string) (models.PriceQuote, error) {
+	return quotePrice(s.discountRepo, itemName, listPrice, promoCode, s.now())
+}
+
+func (s *DiscountService) CreateCampaign(campaign models.Campaign) (*models.Campaign, error) {
+	campaign.Name = strings.TrimSpace(campaign.Name)
+	if campaign.Name == "" {
+		return nil, errors.New("campaign name is required")
+	}
+	if err := validateDiscount(campaign.DiscountType, campaign.DiscountValue); err != nil {
+		return nil, err
+	}
+	if !campaign.EndsAt.After(campaign.StartsAt) {
+		return nil, errors.New("campaign must end after it starts")
+	}
+	if campaign.ItemNames == nil {
+		campaign.ItemNames = []string{}
+	}
+
+	id, err := s.discountRepo.CreateCampaign(campaign)
+	if err != nil {
+		s.log.Errorf("Error creating campaign: %v", err)
+		return nil, err
+	}
+	campaign.ID = id
+
+	if s.notifier != nil && campaign.EndsAt.After(s.now()) {
+		s.notifier.ItemDiscounted(campaign)
+	}
+	return &campaign, nil
+}
+
+func (s *DiscountService) ListCampaigns() ([]models.Campaign, error) {
+	return s.discountRepo.ListCampaigns()
+}
+
+const promoCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generatePromoCode() (string, error) {
+	code := make([]byte, 10)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(promoCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = promoCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func (s *DiscountService) CreatePromoCode(req models.CreatePromoCodeRequest) (*models.PromoCode, error) {
+	if err := validateDiscount(req.DiscountType, req.DiscountValue); err != nil {
+		return nil, err
+	}
+	if req.MaxUses < 0 {
+		return nil, errors.New("max uses must be positive")
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+
+	promo := models.PromoCode{
+		Code:          strings.ToUpper(strings.TrimSpace(req.Code)),
+		ItemNames:     req.ItemNames,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		MaxUses:       req.MaxUses,
+		ExpiresAt:     req.ExpiresAt,
+	}
+	if promo.ItemNames == nil {
+		promo.ItemNames = []string{}
+	}
+	if promo.Code == "" {
+		code, err := generatePromoCode()
+		if err != nil {
+			s.log.Errorf("Error generating promo code: %v", err)
+			return nil, err
+		}
+		promo.Code = code
+	}
+
+	id, err := s.discountRepo.CreatePromoCode(promo)
+	if err != nil {
+		s.log.Errorf("Error creating promo code: %v", err)
+		return nil, err
+	}
+	promo.ID = id
+	return &promo, nil
+}
+
+func (s *DiscountService) ListPromoCodes() ([]models.PromoCode, error) {
+	return s.discountRepo.ListPromoCodes()
+}
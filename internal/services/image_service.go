@@ -0,0 +1,172 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/storage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Ограничения на загружаемые изображения
+const (
+	MaxImageSize      = 5 << 20
+	maxImageDimension = 4096
+	thumbnailSize     = 256
+)
+
+// Поддерживаемые форматы и расширения ключей в хранилище
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+type ImageService struct {
+	catalogRepo repository.CatalogRepositoryInterface
+	store       storage.BlobStore
+	log         *logrus.Logger
+}
+
+func NewImageService(catalogRepo repository.CatalogRepositoryInterface, store storage.BlobStore, log *logrus.Logger) *ImageService {
+	return &ImageService{
+		catalogRepo: catalogRepo,
+		store:       store,
+		log:         log,
+	}
+}
+
+// Загрузка изображения товара: проверка, миниатюра, сохранение в хранилище.
+// Ключ строится по хэшу названия товара и содержимого, поэтому файлы можно кэшировать бессрочно.
+func (s *ImageService) UploadItemImage(itemName string, data []byte) (*models.CatalogItem, error) {
+	img, contentType, ext, err := decodeUploadedImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.catalogRepo.GetItem(itemName)
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, err := encodeImage(Thumbnail(img, thumbnailSize), contentType)
+	if err != nil {
+		s.log.Errorf("Error encoding thumbnail of %s: %v", itemName, err)
+		return nil, err
+	}
+
+	// В ключ входит название товара: прежние файлы удаляются при замене, и общий файл у двух товаров пропал бы у обоих
+	sum := sha256.Sum256(append([]byte(itemName+"\x00"), data...))
+	hash := hex.EncodeToString(sum[:16])
+	imageKey := "items/" + hash + ext
+	thumbKey := "items/" + hash + "_thumb" + ext
+	if err = s.store.Put(imageKey, data, contentType); err != nil {
+		s.log.Errorf("Error storing image of %s: %v", itemName, err)
+		return nil, err
+	}
+	if err = s.store.Put(thumbKey, thumb, contentType); err != nil {
+		s.log.Errorf("Error storing thumbnail of %s: %v", itemName, err)
+		return nil, err
+	}
+	if err = s.catalogRepo.SetItemImage(itemName, imageKey, thumbKey); err != nil {
+		return nil, err
+	}
+
+	// Прежние файлы больше не нужны, ошибка удаления не мешает загрузке
+	if item.ImageKey != "" && item.ImageKey != imageKey {
+		for _, key := range []string{item.ImageKey, item.ThumbKey} {
+			if err = s.store.Delete(key); err != nil {
+				s.log.Warnf("Failed to delete old image %s: %v", key, err)
+			}
+		}
+	}
+
+	item.ImageKey, item.ThumbKey = imageKey, thumbKey
+	item.ImageURL, item.ThumbURL = models.ImagesPath+imageKey, models.ImagesPath+thumbKey
+	return item, nil
+}
+
+func (s *ImageService) GetImage(key string) ([]byte, string, error) {
+	return s.store.Get(key)
+}
+
+// Проверка загруженного файла: размер, формат (JPEG или PNG) и размеры изображения.
+// Возвращает декодированное изображение, его тип и расширение ключа в хранилище.
+func decodeUploadedImage(data []byte) (image.Image, string, string, error) {
+	if len(data) == 0 || len(data) > MaxImageSize {
+		return nil, "", "", fmt.Errorf("%w: size must be up to %d bytes", models.ErrInvalidImage, MaxImageSize)
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, "", "", fmt.Errorf("%w: only JPEG and PNG are supported", models.ErrInvalidImage)
+	}
+
+	// Размеры проверяются до полного декодирования, чтобы не распаковывать огромные картинки
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %v", models.ErrInvalidImage, err)
+	}
+	if config.Width > maxImageDimension || config.Height > maxImageDimension {
+		return nil, "", "", fmt.Errorf("%w: image must be at most %dx%d", models.ErrInvalidImage, maxImageDimension, maxImageDimension)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %v", models.ErrInvalidImage, err)
+	}
+	return img, contentType, ext, nil
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	}
+	return buf.Bytes(), err
+}
+
+// Thumbnail уменьшает изображение так, чтобы большая сторона была не больше maxSide.
+// Каждый пиксель миниатюры - среднее по соответствующей области исходника.
+func Thumbnail(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return src
+	}
+	dstWidth, dstHeight := maxSide, height*maxSide/width
+	if height > width {
+		dstWidth, dstHeight = width*maxSide/height, maxSide
+	}
+	dstWidth, dstHeight = max(dstWidth, 1), max(dstHeight, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := y*height/dstHeight, max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := x*width/dstWidth, max((x+1)*width/dstWidth, x*width/dstWidth+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
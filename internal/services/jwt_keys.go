@@ -0,0 +1,208 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SigningKey - ключ подписи JWT. Для HS256 ключ подписи и проверки совпадают,
+// для RS256 и EdDSA проверяется открытым ключом, который публикуется в JWKS.
+// RetiredAt - момент вывода ключа из использования, после него ключ только проверяет токены.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	RetiredAt *time.Time
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey - симметричный ключ HS256 из секрета. Такой ключ не публикуется в JWKS.
+func NewHMACKey(id, secret string) SigningKey {
+	return SigningKey{ID: id, Method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+}
+
+// NewPrivateKey - асимметричный ключ: *rsa.PrivateKey (RS256) или ed25519.PrivateKey (EdDSA)
+func NewPrivateKey(id string, key interface{}) (SigningKey, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return SigningKey{ID: id, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	}
+	return SigningKey{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+// LoadSigningKey читает закрытый ключ RSA или Ed25519 из PEM-файла (PKCS#8 или PKCS#1)
+func LoadSigningKey(id, path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("key %s: no PEM data in %s", id, path)
+	}
+
+	var key interface{}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return SigningKey{}, fmt.Errorf("key %s: %v", id, err)
+		}
+	}
+	return NewPrivateKey(id, key)
+}
+
+// KeySet - ключи подписи JWT с идентификаторами kid. Новые токены подписываются активным ключом,
+// выведенные ключи ещё grace проверяют ранее выданные токены, затем перестают приниматься.
+type KeySet struct {
+	mu     sync.RWMutex
+	active SigningKey
+	keys   map[string]SigningKey
+	grace  time.Duration
+}
+
+func NewKeySet(active SigningKey, grace time.Duration, retired ...SigningKey) (*KeySet, error) {
+	set := &KeySet{active: active, keys: map[string]SigningKey{active.ID: active}, grace: grace}
+	for _, key := range retired {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// Ключ, которым подписываются новые токены
+func (k *KeySet) Active() SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Смена активного ключа: прежний выводится из использования в момент now и проверяет токены ещё grace
+func (k *KeySet) Rotate(next SigningKey, now time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[next.ID]; ok {
+		return fmt.Errorf("duplicate key id %q", next.ID)
+	}
+	previous := k.active
+	previous.RetiredAt = &now
+	k.keys[previous.ID] = previous
+	k.keys[next.ID] = next
+	k.active = next
+	return nil
+}
+
+// Ключ проверки по kid. Токены без kid проверяются активным ключом - так выдавались токены до ротации.
+func (k *KeySet) verificationKey(kid string, now time.Time) (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if kid == "" {
+		return k.active, nil
+	}
+	key, ok := k.keys[kid]
+	if !ok || !k.usable(key, now) {
+		return SigningKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (k *KeySet) usable(key SigningKey, now time.Time) bool {
+	return key.RetiredAt == nil || now.Before(key.RetiredAt.Add(k.grace))
+}
+
+// Алгоритмы, которые могут встретиться в наших токенах
+func (k *KeySet) algorithms() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range k.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// Открытые ключи для проверки токенов другими сервисами. Симметричные ключи не публикуются.
+func (k *KeySet) JWKS(now time.Time) models.JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	jwks := models.JWKS{Keys: []models.JWK{}}
+	for _, key := range k.keys {
+		if !k.usable(key, now) {
+			continue
+		}
+		jwk := models.JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// Выбор ключа проверки для jwt.Parser. Алгоритм токена должен совпадать с алгоритмом ключа,
+// иначе, например, открытый RSA-ключ можно было бы подсунуть как секрет HS256.
+func (k *KeySet) keyFunc(now time.Time) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := k.verificationKey(kid, now)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method: " + token.Method.Alg())
+		}
+		return key.verifyKey, nil
+	}
+}
+
+// Открытый ключ из JWK и алгоритм, которым им проверяются подписи. Обратное к JWKS преобразование,
+// нужно для проверки токенов чужих сервисов, например ID-токенов провайдера OpenID Connect.
+func publicKeyFromJWK(jwk models.JWK) (jwt.SigningMethod, interface{}, error) {
+	switch {
+	case jwk.Kty == "RSA" && (jwk.Alg == "" || jwk.Alg == jwt.SigningMethodRS256.Alg()):
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %s: invalid modulus: %v", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, nil, fmt.Errorf("key %s: invalid exponent", jwk.Kid)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return jwt.SigningMethodRS256, key, nil
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("key %s: invalid Ed25519 key", jwk.Kid)
+		}
+		return jwt.SigningMethodEdDSA, ed25519.PublicKey(x), nil
+	}
+	return nil, nil, fmt.Errorf("key %s: unsupported key type %s %s", jwk.Kid, jwk.Kty, jwk.Alg)
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"strings"
+	"time"
+)
+
+// LoginThrottlePolicy - защита входа от перебора. После MaxAttempts неудач подряд для имени
+// (MaxIPAttempts - для IP-адреса) каждая следующая попытка возможна только через BaseDelay,
+// удваивающуюся с каждой неудачей, но не дольше MaxLockout. Неудачи забываются через Window без новых неудач.
+type LoginThrottlePolicy struct {
+	MaxAttempts   int
+	MaxIPAttempts int
+	BaseDelay     time.Duration
+	MaxLockout    time.Duration
+	Window        time.Duration
+}
+
+// Значения по умолчанию для незаданных полей политики
+var DefaultLoginThrottlePolicy = LoginThrottlePolicy{
+	MaxAttempts:   5,
+	MaxIPAttempts: 20,
+	BaseDelay:     30 * time.Second,
+	MaxLockout:    15 * time.Minute,
+	Window:        time.Hour,
+}
+
+// LoginThrottle считает неудачные попытки входа по имени пользователя и по IP-адресу.
+// Счётчик по IP нужен против перебора многих имён с одного адреса, поэтому его порог выше:
+// за одним адресом может быть много пользователей.
+type LoginThrottle struct {
+	repo   repository.LoginAttemptRepositoryInterface
+	policy LoginThrottlePolicy
+	now    func() time.Time
+}
+
+func NewLoginThrottle(repo repository.LoginAttemptRepositoryInterface, policy LoginThrottlePolicy) *LoginThrottle {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultLoginThrottlePolicy.MaxAttempts
+	}
+	if policy.MaxIPAttempts <= 0 {
+		policy.MaxIPAttempts = DefaultLoginThrottlePolicy.MaxIPAttempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultLoginThrottlePolicy.BaseDelay
+	}
+	if policy.MaxLockout <= 0 {
+		policy.MaxLockout = DefaultLoginThrottlePolicy.MaxLockout
+	}
+	if policy.Window <= 0 {
+		policy.Window = DefaultLoginThrottlePolicy.Window
+	}
+	// Счётчик не должен обнуляться раньше, чем закончится блокировка
+	if policy.Window < policy.MaxLockout {
+		policy.Window = policy.MaxLockout
+	}
+	return &LoginThrottle{repo: repo, policy: policy, now: time.Now}
+}
+
+// LoginAttempt - попытка входа, зарезервированная Reserve. Пока пароль или код не проверен, она
+// считается неудачной; если проверка прошла, резерв снимается Release.
+type LoginAttempt struct {
+	throttle *LoginThrottle
+	at       time.Time
+	previous map[string]*models.LoginAttempts
+}
+
+// Проверка перед входом и учёт попытки одним шагом: попытка заранее считается неудачной, а блокировка
+// определяется по счётчикам до неё. Иначе параллельные попытки проходили бы проверку раньше,
+// чем учтена хотя бы одна неудача, и задержка не действовала бы. Если имя или адрес заблокированы,
+// возвращается *models.LoginLockedError с наибольшим из оставшихся времён ожидания, а попытка не учитывается.
+func (t *LoginThrottle) Reserve(username, ip string) (*LoginAttempt, error) {
+	attempt := &LoginAttempt{throttle: t, at: t.now(), previous: map[string]*models.LoginAttempts{}}
+	var retryAfter time.Duration
+	for key, limit := range t.keys(username, ip) {
+		previous, err := t.repo.ReserveLoginAttempt(key, attempt.at, attempt.at.Add(-t.policy.Window))
+		if err != nil {
+			attempt.Release()
+			return nil, err
+		}
+		attempt.previous[key] = previous
+		retryAfter = max(retryAfter, t.wait(previous, limit, attempt.at))
+	}
+	if retryAfter > 0 {
+		attempt.Release()
+		return nil, &models.LoginLockedError{RetryAfter: retryAfter}
+	}
+	return attempt, nil
+}
+
+// Снятие резерва: пароль или код оказался верным. Для nil ничего не делает.
+func (a *LoginAttempt) Release() {
+	if a == nil {
+		return
+	}
+	for key, previous := range a.previous {
+		// Ошибка оставляет лишнюю неудачу в счётчике, вход от этого не должен падать
+		_ = a.throttle.repo.ReleaseLoginAttempt(key, a.at, previous)
+	}
+	a.previous = nil
+}
+
+// Успешный вход сбрасывает счётчик имени. Счётчик адреса не сбрасывается,
+// иначе вход в свой аккаунт позволял бы продолжать перебор чужих.
+func (t *LoginThrottle) RecordSuccess(username string) error {
+	return t.repo.ResetLoginAttempts(usernameAttemptKey(username))
+}
+
+// Снятие блокировки администратором
+func (t *LoginThrottle) Unlock(username, ip string) error {
+	for key := range t.keys(username, ip) {
+		if err := t.repo.ResetLoginAttempts(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Удаление счётчиков, по которым не было неудач дольше Window. Запускается планировщиком.
+func (t *LoginThrottle) PurgeStale() (int64, error) {
+	return t.repo.DeleteStaleLoginAttempts(t.now().Add(-t.policy.Window))
+}
+
+// Сколько ещё ждать следующей попытки после неудач attempts (nil - неудач не было)
+func (t *LoginThrottle) wait(attempts *models.LoginAttempts, limit int, now time.Time) time.Duration {
+	if attempts == nil || attempts.LastFailureAt.Before(now.Add(-t.policy.Window)) {
+		return 0
+	}
+	return max(attempts.LastFailureAt.Add(t.delay(attempts.Failures, limit)).Sub(now), 0)
+}
+
+// Задержка после failures неудач: до порога её нет, дальше BaseDelay удваивается до MaxLockout
+func (t *LoginThrottle) delay(failures, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+	delay := t.policy.BaseDelay
+	for i := limit; i < failures && delay < t.policy.MaxLockout; i++ {
+		delay *= 2
+	}
+	return min(delay, t.policy.MaxLockout)
+}
+
+// Ключи счётчиков с их порогами. Пустые имя или адрес не учитываются.
+func (t *LoginThrottle) keys(username, ip string) map[string]int {
+	keys := map[string]int{}
+	if username != "" {
+		keys[usernameAttemptKey(username)] = t.policy.MaxAttempts
+	}
+	if ip != "" {
+		keys["ip:"+ip] = t.policy.MaxIPAttempts
+	}
+	return keys
+}
+
+// Имена уникальны без учёта регистра, поэтому и счётчик один на все варианты написания
+func usernameAttemptKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
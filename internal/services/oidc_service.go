@@ -0,0 +1,440 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig - настройки входа через провайдера OpenID Connect. Адреса провайдера читаются из
+// Issuer/.well-known/openid-configuration, RedirectURL должен вести на /api/auth/oidc/callback.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Сколько времени у пользователя есть на вход у провайдера
+const OIDCStateTTL = 10 * time.Minute
+
+// Допустимое расхождение часов с провайдером при проверке срока действия ID-токена
+const oidcClockSkew = time.Minute
+
+// Сколько раз подбирается свободное имя для нового пользователя
+const oidcUsernameAttempts = 5
+
+// Ответ провайдера на /.well-known/openid-configuration, нужные нам поля
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCService - вход через провайдера по authorization code flow с PKCE. Учётная запись провайдера
+// (issuer и subject из ID-токена) привязывается к пользователю, при первом входе пользователь создаётся.
+type OIDCService struct {
+	config OIDCConfig
+	repo   repository.OIDCRepositoryInterface
+	auth   *AuthService
+	client *http.Client
+	log    *logrus.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	provider *oidcProvider
+	keys     map[string]SigningKey
+}
+
+func NewOIDCService(config OIDCConfig, repo repository.OIDCRepositoryInterface, auth *AuthService, client *http.Client, log *logrus.Logger) *OIDCService {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &OIDCService{
+		config: config,
+		repo:   repo,
+		auth:   auth,
+		client: client,
+		log:    log,
+		now:    time.Now,
+	}
+}
+
+// Начало входа: адрес страницы входа провайдера и state, который вернётся в callback
+func (s *OIDCService) LoginURL() (string, string, error) {
+	provider, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState := models.OIDCLoginState{ExpiresAt: s.now().Add(OIDCStateTTL)}
+	for _, value := range []*string{&loginState.State, &loginState.Nonce, &loginState.CodeVerifier} {
+		if *value, err = randomURLToken(); err != nil {
+			return "", "", err
+		}
+	}
+	if err = s.repo.SaveOIDCState(loginState); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(loginState.CodeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", s.config.ClientID)
+	query.Set("redirect_uri", s.config.RedirectURL)
+	query.Set("scope", strings.Join(s.config.Scopes, " "))
+	query.Set("state", loginState.State)
+	query.Set("nonce", loginState.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), loginState.State, nil
+}
+
+// Завершение входа: обмен кода на ID-токен, его проверка и выдача наших токенов.
+// Неизвестный или истёкший state - ErrInvalidOIDCState, отказ провайдера или негодный ID-токен - ErrOIDCLoginFailed.
+func (s *OIDCService) Callback(state, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	loginState, err := s.repo.ConsumeOIDCState(state, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(code, loginState.CodeVerifier)
+	if err != nil {
+		s.log.Errorf("OIDC code exchange failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", models.ErrOIDCLoginFailed, err)
+	}
+	claims, err := s.verifyIDToken(rawIDToken, loginState.Nonce)
+	if err != nil {
+		s.log.Errorf("OIDC ID token rejected: %v", err)
+		return nil, fmt.Errorf("%w: %v", models.ErrOIDCLoginFailed, err)
+	}
+
+	username, err := s.userForIdentity(claims)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Infof("User %s logged in via OIDC", username)
+	return s.auth.ExternalLogin(username, client)
+}
+
+// Удаление незавершённых входов с истёкшим сроком. Запускается планировщиком.
+func (s *OIDCService) PurgeExpiredStates() error {
+	deleted, err := s.repo.DeleteExpiredOIDCStates(s.now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.log.Infof("Deleted %d expired OIDC login states", deleted)
+	}
+	return nil
+}
+
+// Обмен кода авторизации на токены провайдера, нужен только ID-токен
+func (s *OIDCService) exchangeCode(code, codeVerifier string) (string, error) {
+	provider, err := s.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.RedirectURL},
+		"client_id":     {s.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// Утверждения ID-токена, которые мы проверяем и используем
+type idTokenClaims struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          oidcAudience `json:"aud"`
+	AuthorizedParty   string       `json:"azp"`
+	ExpiresAt         int64        `json:"exp"`
+	Nonce             string       `json:"nonce"`
+	PreferredUsername string       `json:"preferred_username"`
+	Email             string       `json:"email"`
+}
+
+// Сроки и остальные утверждения проверяет verifyIDToken по часам сервиса
+func (c *idTokenClaims) Valid() error {
+	return nil
+}
+
+// aud в ID-токене - строка или массив строк
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a oidcAudience) contains(clientID string) bool {
+	for _, audience := range a {
+		if audience == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверка ID-токена: подпись ключом провайдера, издатель, получатель, срок действия и nonce
+func (s *OIDCService) verifyIDToken(rawIDToken, nonce string) (*idTokenClaims, error) {
+	provider, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &idTokenClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}}
+	if _, err = parser.ParseWithClaims(rawIDToken, claims, s.keyFunc); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claims.Issuer != provider.Issuer:
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case !claims.Audience.contains(s.config.ClientID):
+		return nil, fmt.Errorf("token is issued for %v", []string(claims.Audience))
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != s.config.ClientID:
+		return nil, fmt.Errorf("token is issued to party %q", claims.AuthorizedParty)
+	case !s.now().Before(time.Unix(claims.ExpiresAt, 0).Add(oidcClockSkew)):
+		return nil, errors.New("token is expired")
+	case claims.Nonce != nonce:
+		return nil, errors.New("nonce mismatch")
+	case claims.Subject == "":
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+// Ключ проверки ID-токена по kid. Незнакомый kid означает, что провайдер сменил ключи, -
+// тогда JWKS перечитывается. Алгоритм токена должен совпадать с алгоритмом ключа.
+func (s *OIDCService) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.signingKey(kid)
+	if !ok {
+		if err := s.refreshKeys(); err != nil {
+			return nil, err
+		}
+		if key, ok = s.signingKey(kid); !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method: " + token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// Токен без kid проверяется единственным ключом провайдера
+func (s *OIDCService) signingKey(kid string) (SigningKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *OIDCService) refreshKeys() error {
+	provider, err := s.discover()
+	if err != nil {
+		return err
+	}
+	var jwks models.JWKS
+	if err = s.getJSON(provider.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := make(map[string]SigningKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		method, verifyKey, err := publicKeyFromJWK(jwk)
+		if err != nil {
+			s.log.Warnf("Skipping OIDC provider key: %v", err)
+			continue
+		}
+		keys[jwk.Kid] = SigningKey{ID: jwk.Kid, Method: method, verifyKey: verifyKey}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Настройки провайдера читаются при первом входе и кэшируются: сервис запускается и без провайдера
+func (s *OIDCService) discover() (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	var provider oidcProvider
+	if err := s.getJSON(strings.TrimSuffix(s.config.Issuer, "/")+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if provider.Issuer != s.config.Issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", provider.Issuer, s.config.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	s.provider = &provider
+	return s.provider, nil
+}
+
+func (s *OIDCService) getJSON(address string, v interface{}) error {
+	resp, err := s.client.Get(address)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", address, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// Пользователь, привязанный к учётной записи провайдера. При первом входе он создаётся с именем
+// из preferred_username или email. Существующий пользователь с таким именем не привязывается:
+// иначе владелец учётной записи у провайдера мог бы войти в чужой аккаунт, - берётся другое имя.
+func (s *OIDCService) userForIdentity(claims *idTokenClaims) (string, error) {
+	username, err := s.repo.GetUsernameByIdentity(claims.Issuer, claims.Subject)
+	if err != nil || username != "" {
+		return username, err
+	}
+
+	base := oidcUsername(claims)
+	candidate := base
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		if attempt > 0 {
+			suffix := make([]byte, 2)
+			if _, err = rand.Read(suffix); err != nil {
+				return "", err
+			}
+			candidate = truncateUsername(base, UsernameMaxLength-5) + "-" + hex.EncodeToString(suffix)
+		}
+
+		// Пароля у такого пользователя нет, войти по паролю можно после сброса администратором
+		err = s.repo.CreateUserWithIdentity(models.User{Username: candidate, Balance: 1000}, claims.Issuer, claims.Subject)
+		if err == nil {
+			s.log.Infof("Created user %s for OIDC subject %s", candidate, claims.Subject)
+			return candidate, nil
+		}
+		if !errors.Is(err, models.ErrUserAlreadyExists) {
+			return "", err
+		}
+		// Учётную запись мог уже привязать параллельный первый вход
+		if username, err = s.repo.GetUsernameByIdentity(claims.Issuer, claims.Subject); err != nil || username != "" {
+			return username, err
+		}
+	}
+	return "", fmt.Errorf("no free username for OIDC subject %s", claims.Subject)
+}
+
+// Имя нового пользователя из утверждений ID-токена, приведённое к правилам ValidateUsername
+func oidcUsername(claims *idTokenClaims) string {
+	for _, source := range []string{claims.PreferredUsername, strings.Split(claims.Email, "@")[0]} {
+		username := strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("._-", r) {
+				return r
+			}
+			return -1
+		}, source)
+		username = strings.TrimLeft(username, "._-")
+		username = truncateUsername(username, UsernameMaxLength)
+		if len(ValidateUsername(username)) == 0 {
+			return username
+		}
+	}
+	return "user"
+}
+
+func truncateUsername(username string, maxLength int) string {
+	if len(username) > maxLength {
+		return username[:maxLength]
+	}
+	return username
+}
+
+// Случайное значение для state, nonce и code_verifier
+func randomURLToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
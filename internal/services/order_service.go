@@ -0,0 +1,95 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Допустимые переходы между статусами заказа
+var orderTransitions = map[string][]string{
+	models.OrderStatusPlaced:         {models.OrderStatusPrepared, models.OrderStatusCancelled},
+	models.OrderStatusPrepared:       {models.OrderStatusReadyForPickup, models.OrderStatusCancelled},
+	models.OrderStatusReadyForPickup: {models.OrderStatusDelivered, models.OrderStatusCancelled},
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderService struct {
+	orderRepo    repository.OrderRepositoryInterface
+	purchaseRepo repository.PurchaseRepositoryInterface
+	log          *logrus.Logger
+}
+
+func NewOrderService(orderRepo repository.OrderRepositoryInterface, purchaseRepo repository.PurchaseRepositoryInterface, log *logrus.Logger) *OrderService {
+	return &OrderService{
+		orderRepo:    orderRepo,
+		purchaseRepo: purchaseRepo,
+		log:          log,
+	}
+}
+
+func (s *OrderService) GetUserOrders(username string) ([]models.Order, error) {
+	return s.orderRepo.GetUserOrders(username)
+}
+
+// Заказ пользователя вместе с историей статусов. Чужие заказы не видны.
+func (s *OrderService) GetUserOrder(username string, orderID int) (*models.Order, []models.OrderStatusChange, error) {
+	order, err := s.orderRepo.GetOrder(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Username != username {
+		return nil, nil, models.ErrOrderNotFound
+	}
+
+	history, err := s.orderRepo.GetStatusHistory(orderID)
+	if err != nil {
+		s.log.Errorf("Error getting status history for order %d: %v", orderID, err)
+		return nil, nil, err
+	}
+	return order, history, nil
+}
+
+// Пользователь может сам отменить заказ, пока его не начали собирать
+func (s *OrderService) CancelUserOrder(username string, orderID int) error {
+	order, err := s.orderRepo.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Username != username {
+		return models.ErrOrderNotFound
+	}
+	if order.Status != models.OrderStatusPlaced {
+		return models.ErrInvalidOrderTransition
+	}
+	return s.purchaseRepo.CancelOrder(orderID, order.Status, username)
+}
+
+func (s *OrderService) ListOrders(status string) ([]models.Order, error) {
+	return s.orderRepo.ListOrders(status)
+}
+
+// Смена статуса администратором. Отмена идёт через возврат покупки.
+func (s *OrderService) UpdateOrderStatus(orderID int, status, changedBy string) error {
+	order, err := s.orderRepo.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if !canTransition(order.Status, status) {
+		s.log.Infof("Rejected order %d transition %s -> %s", orderID, order.Status, status)
+		return models.ErrInvalidOrderTransition
+	}
+
+	if status == models.OrderStatusCancelled {
+		return s.purchaseRepo.CancelOrder(orderID, order.Status, changedBy)
+	}
+	return s.orderRepo.UpdateOrderStatus(orderID, order.Status, status, changedBy)
+}
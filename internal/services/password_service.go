@@ -0,0 +1,128 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// Сколько действует токен сброса пароля, выданный администратором
+const PasswordResetTokenTTL = 24 * time.Hour
+
+type PasswordService struct {
+	userRepo    repository.UserRepositoryInterface
+	resetRepo   repository.PasswordResetRepositoryInterface
+	revocations *RevocationStore
+	passwords   PasswordPolicy
+	log         *logrus.Logger
+	now         func() time.Time
+}
+
+func NewPasswordService(userRepo repository.UserRepositoryInterface, resetRepo repository.PasswordResetRepositoryInterface, revocations *RevocationStore, passwords PasswordPolicy, log *logrus.Logger) *PasswordService {
+	return &PasswordService{
+		userRepo:    userRepo,
+		resetRepo:   resetRepo,
+		revocations: revocations,
+		passwords:   passwords,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+// Смена пароля пользователем. Нужен текущий пароль; после смены все выданные токены отзываются,
+// и войти нужно заново.
+func (s *PasswordService) ChangePassword(username, currentPassword, newPassword string) error {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		s.log.Errorf("Error getting user %s: %v", username, err)
+		return models.ErrUserNotFound
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return models.ErrInvalidPassword
+	}
+	if err = s.validateNewPassword(newPassword, user.Username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.hash(newPassword)
+	if err != nil {
+		s.log.Errorf("Error hashing password: %v", err)
+		return err
+	}
+	if err = s.userRepo.UpdatePassword(user.Username, hashedPassword); err != nil {
+		return err
+	}
+	s.log.Infof("User %s changed password", user.Username)
+	return s.revokeSessions(user.Username)
+}
+
+// Выдача администратором одноразового токена сброса пароля. Прежние неиспользованные токены
+// пользователя перестают действовать.
+func (s *PasswordService) IssueResetToken(username, issuedBy string) (*models.PasswordResetToken, error) {
+	token, tokenHash, err := newRefreshToken()
+	if err != nil {
+		s.log.Errorf("Error generating password reset token: %v", err)
+		return nil, err
+	}
+	expiresAt := s.now().Add(PasswordResetTokenTTL)
+	if err = s.resetRepo.CreatePasswordResetToken(username, tokenHash, issuedBy, expiresAt); err != nil {
+		return nil, err
+	}
+	s.log.Infof("Password reset token for user %s issued by %s", username, issuedBy)
+	return &models.PasswordResetToken{ResetToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// Установка нового пароля по токену сброса. Все выданные токены пользователя отзываются.
+func (s *PasswordService) ResetPassword(token, newPassword string) error {
+	tokenHash := hashRefreshToken(token)
+	username, err := s.resetRepo.GetPasswordResetTokenOwner(tokenHash, s.now())
+	if err != nil {
+		return err
+	}
+	if err = s.validateNewPassword(newPassword, username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.hash(newPassword)
+	if err != nil {
+		s.log.Errorf("Error hashing password: %v", err)
+		return err
+	}
+	if username, err = s.resetRepo.ResetPassword(tokenHash, hashedPassword, s.now()); err != nil {
+		return err
+	}
+	s.log.Infof("User %s reset password", username)
+	return s.revokeSessions(username)
+}
+
+// Удаление истёкших и использованных токенов сброса. Запускается планировщиком.
+func (s *PasswordService) PurgeExpiredResetTokens() error {
+	deleted, err := s.resetRepo.DeleteExpiredPasswordResetTokens(s.now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.log.Infof("Deleted %d expired password reset tokens", deleted)
+	}
+	return nil
+}
+
+// Новый пароль проверяется по тем же правилам, что и при регистрации, ошибки относятся к полю new_password
+func (s *PasswordService) validateNewPassword(password, username string) error {
+	errs := s.passwords.Validate(password, username)
+	for i := range errs {
+		errs[i].Field = "new_password"
+	}
+	return validationError(errs)
+}
+
+// Отзыв всех токенов пользователя, выданных до смены пароля, включая refresh-токены
+func (s *PasswordService) revokeSessions(username string) error {
+	if s.revocations == nil {
+		return errors.New("token revocation is not configured")
+	}
+	return s.revocations.RevokeUserTokens(username, s.now())
+}
@@ -0,0 +1,71 @@
+package services
+
+// Роли пользователей. Роль хранится в users.role и определяет области действия JWT входа.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Области действия (scopes). Каждый защищённый эндпоинт требует одну из них, требования
+// объявлены в handlers.RegisterRoutes.
+const (
+	ScopeInfoRead      = "info:read"
+	ScopeCatalogRead   = "catalog:read"
+	ScopeTransferSend  = "transfer:send"
+	ScopePurchaseWrite = "purchase:write"
+	ScopeReviewsWrite  = "reviews:write"
+	ScopeWishlistWrite = "wishlist:write"
+	// Пароль, 2FA и API-токены: только по JWT входа, API-токену не выдаётся
+	ScopeAccountManage = "account:manage"
+
+	ScopeAdminUsers      = "admin:users"
+	ScopeAdminCatalog    = "admin:catalog"
+	ScopeAdminReviews    = "admin:reviews"
+	ScopeAdminPromotions = "admin:promotions"
+	ScopeAdminOrders     = "admin:orders"
+	ScopeAdminAuctions   = "admin:auctions"
+)
+
+// Области действия, которые можно выдать персональному API-токену
+var apiTokenScopes = []string{
+	ScopeInfoRead,
+	ScopeCatalogRead,
+	ScopeTransferSend,
+	ScopePurchaseWrite,
+	ScopeReviewsWrite,
+	ScopeWishlistWrite,
+}
+
+var userScopes = append([]string{ScopeAccountManage}, apiTokenScopes...)
+
+var roleScopes = map[string][]string{
+	RoleUser:      userScopes,
+	RoleModerator: append(append([]string{}, userScopes...), ScopeAdminReviews),
+	RoleAdmin: append(append([]string{}, userScopes...),
+		ScopeAdminUsers, ScopeAdminCatalog, ScopeAdminReviews, ScopeAdminPromotions, ScopeAdminOrders, ScopeAdminAuctions),
+}
+
+// Области действия роли. У неизвестной роли их нет.
+func RoleScopes(role string) []string {
+	return append([]string(nil), roleScopes[role]...)
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// Сотрудники - роли с доступом к /api/admin
+func IsStaffRole(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
+func isAPITokenScope(scope string) bool {
+	for _, s := range apiTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type PreOrderService struct {
+	preOrderRepo repository.PreOrderRepositoryInterface
+	catalogRepo  repository.CatalogRepositoryInterface
+	discountRepo repository.DiscountRepositoryInterface
+	log          *logrus.Logger
+	now          func() time.Time
+}
+
+func NewPreOrderService(preOrderRepo repository.PreOrderRepositoryInterface, catalogRepo repository.CatalogRepositoryInterface, discountRepo repository.DiscountRepositoryInterface, log *logrus.Logger) *PreOrderService {
+	return &PreOrderService{
+		preOrderRepo: preOrderRepo,
+		catalogRepo:  catalogRepo,
+		discountRepo: discountRepo,
+		log:          log,
+		now:          time.Now,
+	}
+}
+
+// Предзаказ товара, которого нет в наличии. Цена со скидками кампаний фиксируется сразу,
+// монеты резервируются и списываются, когда товар поступит на склад.
+func (s *PreOrderService) CreatePreOrder(username, itemName, variant string) (*models.PreOrder, error) {
+	item, err := s.catalogRepo.GetItem(itemName)
+	if err != nil {
+		return nil, err
+	}
+	if len(item.Components) > 0 {
+		return nil, errors.New("bundles cannot be pre-ordered")
+	}
+
+	pricing := func(listPrice int) (models.PriceQuote, error) {
+		return quotePrice(s.discountRepo, itemName, listPrice, "", s.now())
+	}
+	preOrder, err := s.preOrderRepo.CreatePreOrder(username, itemName, variant, pricing)
+	if err != nil {
+		s.log.Errorf("Error creating pre-order: %v", err)
+		return nil, err
+	}
+	return preOrder, nil
+}
+
+func (s *PreOrderService) GetUserPreOrders(username string) ([]models.PreOrder, error) {
+	return s.preOrderRepo.GetUserPreOrders(username)
+}
+
+func (s *PreOrderService) CancelPreOrder(username string, preOrderID int) error {
+	return s.preOrderRepo.CancelPreOrder(username, preOrderID)
+}
+
+// Снятие товара с предзаказа администратором, резервы всех покупателей возвращаются
+func (s *PreOrderService) ReleaseItemPreOrders(itemName string) (int, error) {
+	if _, err := s.catalogRepo.GetItem(itemName); err != nil {
+		return 0, err
+	}
+	released, err := s.preOrderRepo.ReleaseItemPreOrders(itemName)
+	if err != nil {
+		return 0, err
+	}
+	s.log.Infof("Released %d pre-orders of %s", released, itemName)
+	return released, nil
+}
+
+// Выкуп предзаказов, товар для которых поступил на склад. Запускается планировщиком.
+// Предзаказы выкупаются по очереди оформления, пока товар не закончится.
+func (s *PreOrderService) CaptureDuePreOrders() error {
+	ids, err := s.preOrderRepo.GetCapturablePreOrderIDs()
+	if err != nil {
+		s.log.Errorf("Error fetching capturable pre-orders: %v", err)
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		err = s.preOrderRepo.CapturePreOrder(id)
+		if errors.Is(err, models.ErrOutOfStock) {
+			continue
+		}
+		if err != nil {
+			s.log.Errorf("Error capturing pre-order %d: %v", id, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
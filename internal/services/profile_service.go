@@ -0,0 +1,132 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения полей профиля
+const (
+	profileFieldMaxLength = 64
+	avatarSize            = 256
+)
+
+type ProfileService struct {
+	repo  repository.ProfileRepositoryInterface
+	store storage.BlobStore
+	log   *logrus.Logger
+}
+
+func NewProfileService(repo repository.ProfileRepositoryInterface, store storage.BlobStore, log *logrus.Logger) *ProfileService {
+	return &ProfileService{
+		repo:  repo,
+		store: store,
+		log:   log,
+	}
+}
+
+func (s *ProfileService) Get(username string) (*models.UserProfile, error) {
+	profile, err := s.repo.GetProfile(username)
+	if err != nil {
+		return nil, err
+	}
+	return withAvatarURL(profile), nil
+}
+
+// Изменение профиля: пробелы по краям отбрасываются, пустая строка очищает поле
+func (s *ProfileService) Update(username string, req models.UpdateProfileRequest) (*models.UserProfile, error) {
+	var errs []models.FieldError
+	for _, field := range []struct {
+		name  string
+		value **string
+	}{
+		{"display_name", &req.DisplayName},
+		{"department", &req.Department},
+		{"title", &req.Title},
+	} {
+		if *field.value == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(**field.value)
+		*field.value = &trimmed
+		errs = append(errs, validateProfileField(field.name, trimmed)...)
+	}
+	if err := validationError(errs); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.repo.UpdateProfile(username, req)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Infof("User %s updated profile", username)
+	return withAvatarURL(profile), nil
+}
+
+// Загрузка аватара: изображение уменьшается до avatarSize по большей стороне, исходник не хранится.
+// В ключ входит имя пользователя, чтобы одинаковые картинки разных пользователей не делили файл.
+func (s *ProfileService) UploadAvatar(username string, data []byte) (*models.UserProfile, error) {
+	img, contentType, ext, err := decodeUploadedImage(data)
+	if err != nil {
+		return nil, err
+	}
+	avatar, err := encodeImage(Thumbnail(img, avatarSize), contentType)
+	if err != nil {
+		s.log.Errorf("Error encoding avatar of %s: %v", username, err)
+		return nil, err
+	}
+
+	sum := sha256.Sum256(append([]byte(username+"\x00"), avatar...))
+	key := "avatars/" + hex.EncodeToString(sum[:16]) + ext
+	if err = s.store.Put(key, avatar, contentType); err != nil {
+		s.log.Errorf("Error storing avatar of %s: %v", username, err)
+		return nil, err
+	}
+	if err = s.setAvatar(username, key); err != nil {
+		return nil, err
+	}
+	return s.Get(username)
+}
+
+func (s *ProfileService) DeleteAvatar(username string) error {
+	return s.setAvatar(username, "")
+}
+
+func (s *ProfileService) setAvatar(username, key string) error {
+	oldKey, err := s.repo.SetAvatar(username, key)
+	if err != nil {
+		return err
+	}
+	// Прежний файл больше не нужен, ошибка удаления не мешает запросу
+	if oldKey != "" && oldKey != key {
+		if err = s.store.Delete(oldKey); err != nil {
+			s.log.Warnf("Failed to delete old avatar %s: %v", oldKey, err)
+		}
+	}
+	return nil
+}
+
+func validateProfileField(name, value string) []models.FieldError {
+	if utf8.RuneCountInString(value) > profileFieldMaxLength {
+		return []models.FieldError{fieldError(name, "too_long", fmt.Sprintf("%s must be at most %d characters", name, profileFieldMaxLength))}
+	}
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return []models.FieldError{fieldError(name, "invalid_characters", name+" must not contain control characters")}
+	}
+	return nil
+}
+
+func withAvatarURL(profile *models.UserProfile) *models.UserProfile {
+	if profile.AvatarKey != "" {
+		profile.AvatarURL = models.ImagesPath + profile.AvatarKey
+	}
+	return profile
+}
@@ -3,52 +3,43 @@ package services
 import (
 	"ShopAvito/internal/models"
 	"ShopAvito/internal/repository"
-	"errors"
 	"github.com/sirupsen/logrus"
+	"time"
 )
 
 type PurchaseService struct {
 	purchaseRepo  repository.PurchaseRepositoryInterface
 	userRepo      repository.UserRepositoryInterface
 	inventoryRepo repository.InventoryRepositoryInterface
+	discountRepo  repository.DiscountRepositoryInterface
 	log           *logrus.Logger
 }
 
-func NewPurchaseService(purchaseRepo repository.PurchaseRepositoryInterface, userRepo repository.UserRepositoryInterface, inventoryRepo repository.InventoryRepositoryInterface, log *logrus.Logger) *PurchaseService {
+func NewPurchaseService(purchaseRepo repository.PurchaseRepositoryInterface, userRepo repository.UserRepositoryInterface, inventoryRepo repository.InventoryRepositoryInterface, discountRepo repository.DiscountRepositoryInterface, log *logrus.Logger) *PurchaseService {
 	return &PurchaseService{
 		purchaseRepo:  purchaseRepo,
 		userRepo:      userRepo,
 		inventoryRepo: inventoryRepo,
+		discountRepo:  discountRepo,
 		log:           log,
 	}
 }
 
-// Покупка товара
-func (s *PurchaseService) BuyItem(username, itemName string, price int) error {
-	// Проверяем баланс
-	balance, err := s.userRepo.GetUserBalance(username)
-	if err != nil {
-		s.log.Errorf("Error getting user balance: %v", err)
-		return err
-	}
-	if balance < price {
-		return errors.New("insufficient funds")
+// Покупка товара со скидками кампаний и промокодом, возвращает ID заказа и итоговую цену.
+// Цена по каталогу и баланс проверяются в транзакции покупки, поэтому списывается цена, действующая в момент покупки.
+func (s *PurchaseService) BuyItem(username, itemName, variant, promoCode string) (int, models.PriceQuote, error) {
+	pricing := func(listPrice int) (models.PriceQuote, error) {
+		return quotePrice(s.discountRepo, itemName, listPrice, promoCode, time.Now())
 	}
 
-	// Покупаем предмет и обновляем инвентарь
-	err = s.purchaseRepo.BuyItem(username, itemName, price)
+	// Покупаем предмет, создаём заказ и обновляем инвентарь
+	orderID, quote, err := s.purchaseRepo.BuyItem(username, itemName, variant, pricing)
 	if err != nil {
 		s.log.Errorf("Error buying item: %v", err)
+		return 0, quote, err
 	}
 
-	s.log.Infof("Adding item to inventory: username=%s, item=%s", username, itemName)
-	err = s.inventoryRepo.AddToInventory(username, itemName, 0)
-	if err != nil {
-		s.log.Errorf("Error adding item to inventory: %v", err)
-		return err
-	}
-
-	return err
+	return orderID, quote, nil
 }
 
 // Получение списка купленных товаров
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"unicode/utf8"
+)
+
+// Максимальная длина текста отзыва в символах
+const maxReviewLength = 2000
+
+type ReviewService struct {
+	reviewRepo    repository.ReviewRepositoryInterface
+	inventoryRepo repository.InventoryRepositoryInterface
+	catalogRepo   repository.CatalogRepositoryInterface
+	log           *logrus.Logger
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepositoryInterface, inventoryRepo repository.InventoryRepositoryInterface, catalogRepo repository.CatalogRepositoryInterface, log *logrus.Logger) *ReviewService {
+	return &ReviewService{
+		reviewRepo:    reviewRepo,
+		inventoryRepo: inventoryRepo,
+		catalogRepo:   catalogRepo,
+		log:           log,
+	}
+}
+
+// Товар со средней оценкой и его видимые отзывы
+func (s *ReviewService) GetItemReviews(itemName string) (*models.CatalogItem, []models.Review, error) {
+	item, err := s.catalogRepo.GetItem(itemName)
+	if err != nil {
+		return nil, nil, err
+	}
+	reviews, err := s.reviewRepo.GetItemReviews(itemName)
+	if err != nil {
+		s.log.Errorf("Error fetching reviews of %s: %v", itemName, err)
+		return nil, nil, err
+	}
+	return item, reviews, nil
+}
+
+// Отзыв может оставить только владелец товара: он должен быть в инвентаре пользователя
+func (s *ReviewService) SaveReview(username, itemName string, req models.ReviewRequest) (*models.Review, error) {
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+	text := strings.TrimSpace(req.Text)
+	if utf8.RuneCountInString(text) > maxReviewLength {
+		return nil, errors.New("review text is too long")
+	}
+
+	if _, err := s.catalogRepo.GetItem(itemName); err != nil {
+		return nil, err
+	}
+	inventory, err := s.inventoryRepo.GetInventory(username)
+	if err != nil {
+		s.log.Errorf("Error fetching inventory of %s: %v", username, err)
+		return nil, err
+	}
+	owned := false
+	for _, item := range inventory {
+		if item.Type == itemName && item.Quantity > 0 {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return nil, models.ErrItemNotOwned
+	}
+
+	return s.reviewRepo.UpsertReview(models.Review{
+		Username: username,
+		ItemName: itemName,
+		Rating:   req.Rating,
+		Text:     text,
+	})
+}
+
+// Скрытие или возврат отзыва модератором
+func (s *ReviewService) ModerateReview(reviewID int, hidden bool) error {
+	if err := s.reviewRepo.SetHidden(reviewID, hidden); err != nil {
+		return err
+	}
+	s.log.Infof("Review %d hidden=%t", reviewID, hidden)
+	return nil
+}
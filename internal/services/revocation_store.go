@@ -0,0 +1,159 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Сколько хранится результат проверки в кэше. Отзыв на другом экземпляре сервиса
+// начинает действовать здесь не позже, чем через это время.
+const revocationCacheTTL = 30 * time.Second
+
+// Размер кэша, после которого из него удаляются устаревшие записи
+const revocationCacheCleanupSize = 10000
+
+type revokedEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+type cutoffEntry struct {
+	userID    int
+	before    *time.Time
+	checkedAt time.Time
+}
+
+// RevocationStore - список отозванных токенов в Postgres с кэшем в памяти.
+// Проверка выполняется на каждый запрос, поэтому ответы базы кэшируются на revocationCacheTTL,
+// а отзывы через этот экземпляр попадают в кэш сразу.
+type RevocationStore struct {
+	repo    repository.TokenRevocationRepositoryInterface
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	tokens  map[string]revokedEntry
+	cutoffs map[string]cutoffEntry
+}
+
+func NewRevocationStore(repo repository.TokenRevocationRepositoryInterface) *RevocationStore {
+	return &RevocationStore{
+		repo:    repo,
+		ttl:     revocationCacheTTL,
+		now:     time.Now,
+		tokens:  map[string]revokedEntry{},
+		cutoffs: map[string]cutoffEntry{},
+	}
+}
+
+// Отозван ли токен: по своему jti или отзывом всех токенов пользователя, выданных не позже границы отзыва.
+// Токены удалённого пользователя тоже считаются отозванными, в том числе если его имя занял другой:
+// userID из токена должен совпадать с id владельца имени. userID = 0 - токен выдан до появления uid.
+func (s *RevocationStore) IsRevoked(jti, username string, userID int, issuedAt time.Time) (bool, error) {
+	ownerID, before, err := s.revokedBefore(username)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if userID != 0 && userID != ownerID {
+		return true, nil
+	}
+	// Токен, выданный в момент отзыва, тоже отозван: при совпадении времени безопаснее отказать
+	if before != nil && !issuedAt.After(*before) {
+		return true, nil
+	}
+	if jti == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+	if ok && (entry.revoked || s.now().Sub(entry.checkedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.repo.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.tokens[jti] = revokedEntry{revoked: revoked, checkedAt: s.now()}
+	s.cleanup()
+	s.mu.Unlock()
+	return revoked, nil
+}
+
+func (s *RevocationStore) RevokeToken(jti, username string, expiresAt time.Time) error {
+	if err := s.repo.RevokeToken(jti, username, expiresAt); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.tokens[jti] = revokedEntry{revoked: true, checkedAt: s.now()}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RevocationStore) RevokeUserTokens(username string, before time.Time) error {
+	if err := s.repo.RevokeUserTokens(username, before); err != nil {
+		return err
+	}
+	// Граница могла оказаться позже переданной, поэтому кэш сбрасывается и читается заново
+	s.mu.Lock()
+	delete(s.cutoffs, username)
+	s.mu.Unlock()
+	return nil
+}
+
+// Сброс кэша после удаления аккаунта: следующая проверка не найдёт пользователя и отклонит его токены
+func (s *RevocationStore) ForgetUser(username string) {
+	s.mu.Lock()
+	delete(s.cutoffs, username)
+	s.mu.Unlock()
+}
+
+// Удаление истёкших записей об отозванных токенах
+func (s *RevocationStore) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpiredRevokedTokens(s.now())
+}
+
+func (s *RevocationStore) revokedBefore(username string) (int, *time.Time, error) {
+	s.mu.Lock()
+	entry, ok := s.cutoffs[username]
+	s.mu.Unlock()
+	if ok && s.now().Sub(entry.checkedAt) < s.ttl {
+		return entry.userID, entry.before, nil
+	}
+
+	userID, before, err := s.repo.GetTokensRevokedBefore(username)
+	if err != nil {
+		return 0, nil, err
+	}
+	s.mu.Lock()
+	s.cutoffs[username] = cutoffEntry{userID: userID, before: before, checkedAt: s.now()}
+	s.cleanup()
+	s.mu.Unlock()
+	return userID, before, nil
+}
+
+// Удаление устаревших записей кэша. Вызывается под s.mu.
+func (s *RevocationStore) cleanup() {
+	if len(s.tokens)+len(s.cutoffs) < revocationCacheCleanupSize {
+		return
+	}
+	now := s.now()
+	for jti, entry := range s.tokens {
+		if now.Sub(entry.checkedAt) >= s.ttl {
+			delete(s.tokens, jti)
+		}
+	}
+	for username, entry := range s.cutoffs {
+		if now.Sub(entry.checkedAt) >= s.ttl {
+			delete(s.cutoffs, username)
+		}
+	}
+}
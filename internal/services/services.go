@@ -1,9 +1,12 @@
 package services
 
-import "ShopAvito/internal/models"
+import (
+	"ShopAvito/internal/models"
+	"time"
+)
 
 type PurchaseServiceInterface interface {
-	BuyItem(username, itemName string, price int) error
+	BuyItem(username, itemName, variant, promoCode string) (int, models.PriceQuote, error)
 	GetUserPurchases(username string) ([]models.Purchase, error)
 }
 
@@ -23,9 +26,123 @@ type UserServiceInterface interface {
 	GetBalance(username string) (int, error)
 }
 
+type RoleServiceInterface interface {
+	SetRole(admin, username, role string) error
+}
+
+type AuctionServiceInterface interface {
+	CreateAuction(req models.CreateAuctionRequest) (*models.Auction, error)
+	GetAuction(id int) (*models.Auction, error)
+	ListOpenAuctions() ([]models.Auction, error)
+	GetBids(auctionID int) ([]models.AuctionBid, error)
+	PlaceBid(auctionID int, username string, amount int) error
+}
+
 type AuthServiceInterface interface {
 	GenerateToken(username string) (string, error)
 	ValidateToken(tokenString string) (*Claims, error)
-	Login(username, password string) (string, error)
-	Register(username, password string) (string, error)
+	Login(username, password string, client models.ClientInfo) (*models.AuthResponse, error)
+	Register(username, password string, client models.ClientInfo) (*models.AuthResponse, error)
+	VerifyTwoFactor(challengeToken, code string, client models.ClientInfo) (*models.AuthResponse, error)
+	Refresh(refreshToken string) (*models.AuthResponse, error)
+	Logout(refreshToken, accessToken string) error
+	IsTokenRevoked(claims *Claims) (bool, error)
+	RevokeUserTokens(username string, before *time.Time) (time.Time, error)
+	UnlockLogin(username, ip string) error
+	JWKS() models.JWKS
+}
+
+type ProfileServiceInterface interface {
+	Get(username string) (*models.UserProfile, error)
+	Update(username string, req models.UpdateProfileRequest) (*models.UserProfile, error)
+	UploadAvatar(username string, data []byte) (*models.UserProfile, error)
+	DeleteAvatar(username string) error
+}
+
+type AccountServiceInterface interface {
+	Export(username string) (*models.AccountExport, error)
+	Delete(username, password string) error
+}
+
+type SessionServiceInterface interface {
+	List(username, current string) ([]models.Session, error)
+	Revoke(username string, id int) error
+}
+
+type PasswordServiceInterface interface {
+	ChangePassword(username, currentPassword, newPassword string) error
+	IssueResetToken(username, issuedBy string) (*models.PasswordResetToken, error)
+	ResetPassword(token, newPassword string) error
+}
+
+type TwoFactorServiceInterface interface {
+	Enroll(username string) (*models.TwoFactorEnrollment, error)
+	Confirm(username, code string) ([]string, error)
+	Disable(username, code string) error
+	AuthorizeTransfer(username string, amount int, code string) error
+}
+
+type APITokenServiceInterface interface {
+	Create(username string, req models.CreateAPITokenRequest) (*models.CreatedAPIToken, error)
+	List(username string) ([]models.APIToken, error)
+	Revoke(username string, id int) error
+}
+
+type OIDCServiceInterface interface {
+	LoginURL() (string, string, error)
+	Callback(state, code string, client models.ClientInfo) (*models.AuthResponse, error)
+}
+
+type OrderServiceInterface interface {
+	GetUserOrders(username string) ([]models.Order, error)
+	GetUserOrder(username string, orderID int) (*models.Order, []models.OrderStatusChange, error)
+	CancelUserOrder(username string, orderID int) error
+	ListOrders(status string) ([]models.Order, error)
+	UpdateOrderStatus(orderID int, status, changedBy string) error
+}
+
+type CatalogServiceInterface interface {
+	SearchItems(query models.CatalogQuery, balance int) (*models.CatalogPage, error)
+	ListCategories() ([]models.Category, error)
+	GetItem(name string) (*models.CatalogItem, error)
+	ResolveVariant(item *models.CatalogItem, attributes map[string]string) (string, error)
+	UpsertVariant(itemName string, req models.UpsertVariantRequest) (*models.CatalogVariant, error)
+	CreateBundle(req models.CreateBundleRequest) (*models.CatalogItem, error)
+	SchedulePriceChange(itemName string, req models.SchedulePriceRequest, changedBy string) (*models.PriceChange, error)
+	GetPriceHistory(itemName string) ([]models.PriceChange, error)
+	CancelPriceChange(id int) error
+}
+
+type DiscountServiceInterface interface {
+	Quote(itemName string, listPrice int, promoCode string) (models.PriceQuote, error)
+	CreateCampaign(campaign models.Campaign) (*models.Campaign, error)
+	ListCampaigns() ([]models.Campaign, error)
+	CreatePromoCode(req models.CreatePromoCodeRequest) (*models.PromoCode, error)
+	ListPromoCodes() ([]models.PromoCode, error)
+}
+
+type WishlistServiceInterface interface {
+	GetWishlist(username string) ([]models.WishlistItem, int, error)
+	AddItem(username, itemName string) error
+	RemoveItem(username, itemName string) error
+	GetNotifications(username string, unreadOnly bool) ([]models.Notification, error)
+	MarkNotificationsRead(username string) error
+}
+
+type ReviewServiceInterface interface {
+	GetItemReviews(itemName string) (*models.CatalogItem, []models.Review, error)
+	SaveReview(username, itemName string, req models.ReviewRequest) (*models.Review, error)
+	ModerateReview(reviewID int, hidden bool) error
+}
+
+type ImageServiceInterface interface {
+	UploadItemImage(itemName string, data []byte) (*models.CatalogItem, error)
+	GetImage(key string) ([]byte, string, error)
+}
+
+type PreOrderServiceInterface interface {
+	CreatePreOrder(username, itemName, variant string) (*models.PreOrder, error)
+	GetUserPreOrders(username string) ([]models.PreOrder, error)
+	CancelPreOrder(username string, preOrderID int) error
+	ReleaseItemPreOrders(itemName string) (int, error)
 }
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// SessionService - сеансы входа пользователя: список, завершение и проверка JWT на каждый запрос.
+// Как и в RevocationStore, ответы базы кэшируются на revocationCacheTTL, поэтому сеанс, завершённый
+// на другом экземпляре сервиса или выходом, перестаёт действовать здесь не позже, чем через это время.
+type SessionService struct {
+	repo  repository.SessionRepositoryInterface
+	log   *logrus.Logger
+	ttl   time.Duration
+	now   func() time.Time
+	mu    sync.Mutex
+	ended map[string]revokedEntry
+}
+
+func NewSessionService(repo repository.SessionRepositoryInterface, log *logrus.Logger) *SessionService {
+	return &SessionService{
+		repo:  repo,
+		log:   log,
+		ttl:   revocationCacheTTL,
+		now:   time.Now,
+		ended: map[string]revokedEntry{},
+	}
+}
+
+// Активные сеансы пользователя. current - сеанс текущего запроса, он отмечается в списке.
+func (s *SessionService) List(username, current string) ([]models.Session, error) {
+	sessions, err := s.repo.ListSessions(username, s.now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = current != "" && sessions[i].FamilyID == current
+	}
+	return sessions, nil
+}
+
+// Завершение сеанса: его refresh-токены отзываются, выданные в нём JWT перестают приниматься
+func (s *SessionService) Revoke(username string, id int) error {
+	familyID, err := s.repo.RevokeSession(username, id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.ended[familyID] = revokedEntry{revoked: true, checkedAt: s.now()}
+	s.mu.Unlock()
+	s.log.Infof("User %s revoked session %d", username, id)
+	return nil
+}
+
+// Завершён ли сеанс JWT. У JWT без сеанса проверять нечего.
+func (s *SessionService) IsEnded(familyID string) (bool, error) {
+	if familyID == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	entry, ok := s.ended[familyID]
+	s.mu.Unlock()
+	if ok && (entry.revoked || s.now().Sub(entry.checkedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	active, err := s.repo.IsSessionActive(familyID, s.now())
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.ended[familyID] = revokedEntry{revoked: !active, checkedAt: s.now()}
+	s.cleanup()
+	s.mu.Unlock()
+	return !active, nil
+}
+
+// Удаление устаревших записей кэша. Вызывается под s.mu.
+func (s *SessionService) cleanup() {
+	if len(s.ended) < revocationCacheCleanupSize {
+		return
+	}
+	now := s.now()
+	for familyID, entry := range s.ended {
+		if now.Sub(entry.checkedAt) >= s.ttl {
+			delete(s.ended, familyID)
+		}
+	}
+}
@@ -10,19 +10,25 @@ import (
 type TransactionService struct {
 	transactionRepo repository.TransactionRepositoryInterface
 	userRepo        repository.UserRepositoryInterface
+	notifier        WishlistNotifier
 	log             *logrus.Logger
 }
 
-func NewTransactionService(transactionRepo repository.TransactionRepositoryInterface, userRepo repository.UserRepositoryInterface, log *logrus.Logger) *TransactionService {
+func NewTransactionService(transactionRepo repository.TransactionRepositoryInterface, userRepo repository.UserRepositoryInterface, notifier WishlistNotifier, log *logrus.Logger) *TransactionService {
 	return &TransactionService{
 		transactionRepo: transactionRepo,
 		userRepo:        userRepo,
+		notifier:        notifier,
 		log:             log,
 	}
 }
 
 // Перевод монет между пользователями
 func (s *TransactionService) TransferCoins(fromUser, toUser string, amount int) error {
+	// Отрицательная сумма списала бы монеты у получателя
+	if amount <= 0 {
+		return errors.New("amount must be positive")
+	}
 	balance, err := s.userRepo.GetUserBalance(fromUser)
 	if err != nil {
 		s.log.Errorf("Error getting user's balance: %v", err)
@@ -36,6 +42,9 @@ func (s *TransactionService) TransferCoins(fromUser, toUser string, amount int)
 		s.log.Errorf("Error sending coins: %v", err)
 		return err
 	}
+	if s.notifier != nil {
+		s.notifier.CoinsReceived(toUser, amount)
+	}
 	return nil
 }
 
@@ -51,6 +60,7 @@ func (s *TransactionService) GetReceivedTransactions(username string) ([]models.
 	for _, t := range received {
 		details = append(details, models.TransactionDetail{
 			FromUser: t.FromUser,
+			FromName: t.FromName,
 			Amount:   t.Amount,
 		})
 	}
@@ -69,6 +79,7 @@ func (s *TransactionService) GetSentTransactions(username string) ([]models.Tran
 	for _, t := range sent {
 		details = append(details, models.TransactionDetail{
 			ToUser: t.ToUser,
+			ToName: t.ToName,
 			Amount: t.Amount,
 		})
 	}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"crypto/rand"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// Сколько кодов восстановления выдаётся при включении двухфакторной аутентификации
+const recoveryCodeCount = 10
+
+// TwoFactorPolicy - где двухфакторная аутентификация обязательна. TransferThreshold - переводы
+// больше этой суммы требуют кода TOTP, 0 - без ограничения.
+type TwoFactorPolicy struct {
+	Issuer            string
+	RequireForAdmins  bool
+	TransferThreshold int
+}
+
+type TwoFactorService struct {
+	repo   repository.TwoFactorRepositoryInterface
+	policy TwoFactorPolicy
+	log    *logrus.Logger
+	now    func() time.Time
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepositoryInterface, policy TwoFactorPolicy, log *logrus.Logger) *TwoFactorService {
+	if policy.Issuer == "" {
+		policy.Issuer = "Avito Shop"
+	}
+	return &TwoFactorService{
+		repo:   repo,
+		policy: policy,
+		log:    log,
+		now:    time.Now,
+	}
+}
+
+// Начало подключения: новый секрет, который нужно добавить в приложение-аутентификатор
+// и подтвердить кодом. До подтверждения вход не меняется.
+func (s *TwoFactorService) Enroll(username string) (*models.TwoFactorEnrollment, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		s.log.Errorf("Error generating TOTP secret: %v", err)
+		return nil, err
+	}
+	if err = s.repo.SaveTOTPSecret(username, secret); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrollment{Secret: secret, URI: totpURI(s.policy.Issuer, username, secret)}, nil
+}
+
+// Подтверждение кодом из приложения. Возвращает коды восстановления - они показываются один раз.
+func (s *TwoFactorService) Confirm(username, code string) ([]string, error) {
+	secret, err := s.repo.GetTOTPSecret(username)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, models.ErrTwoFactorNotEnabled
+	}
+	if secret.Enabled {
+		return nil, models.ErrTwoFactorAlreadyEnabled
+	}
+	step, ok := matchTOTP(secret.Secret, code, s.now())
+	if !ok {
+		return nil, models.ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := newRecoveryCode()
+		if err != nil {
+			s.log.Errorf("Error generating recovery code: %v", err)
+			return nil, err
+		}
+		codes = append(codes, recoveryCode)
+		hashes = append(hashes, hashRecoveryCode(recoveryCode))
+	}
+	if err = s.repo.EnableTOTP(username, step, hashes); err != nil {
+		return nil, err
+	}
+	s.log.Infof("User %s enabled two-factor authentication", username)
+	return codes, nil
+}
+
+// Отключение: нужен действующий код TOTP или код восстановления
+func (s *TwoFactorService) Disable(username, code string) error {
+	if err := s.Verify(username, code); err != nil {
+		return err
+	}
+	if err := s.repo.DisableTOTP(username); err != nil {
+		return err
+	}
+	s.log.Infof("User %s disabled two-factor authentication", username)
+	return nil
+}
+
+func (s *TwoFactorService) IsEnabled(username string) (bool, error) {
+	secret, err := s.repo.GetTOTPSecret(username)
+	if err != nil {
+		return false, err
+	}
+	return secret != nil && secret.Enabled, nil
+}
+
+// Проверка кода TOTP или кода восстановления. Каждый код принимается один раз.
+func (s *TwoFactorService) Verify(username, code string) error {
+	secret, err := s.repo.GetTOTPSecret(username)
+	if err != nil {
+		return err
+	}
+	if secret == nil || !secret.Enabled {
+		return models.ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(secret.Secret, code, s.now()); ok {
+		used, err := s.repo.UseTOTPStep(username, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return models.ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(username, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrInvalidTwoFactorCode
+	}
+	s.log.Infof("User %s used a recovery code", username)
+	return nil
+}
+
+// Требование политики для администраторов: без включённой двухфакторной аутентификации - ErrTwoFactorRequired
+func (s *TwoFactorService) CheckAdmin(username string) error {
+	if !s.policy.RequireForAdmins {
+		return nil
+	}
+	enabled, err := s.IsEnabled(username)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return models.ErrTwoFactorRequired
+	}
+	return nil
+}
+
+// Перевод выше порога подтверждается кодом. Без кода - ErrTwoFactorRequired,
+// без включённой двухфакторной аутентификации - ErrTwoFactorNotEnabled.
+func (s *TwoFactorService) AuthorizeTransfer(username string, amount int, code string) error {
+	if s.policy.TransferThreshold <= 0 || amount <= s.policy.TransferThreshold {
+		return nil
+	}
+	if code == "" {
+		return models.ErrTwoFactorRequired
+	}
+	return s.Verify(username, code)
+}
+
+// Код восстановления вида "abcde-fghij"
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(totpEncoding.EncodeToString(raw))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// Коды восстановления сравниваются без учёта регистра, дефисов и пробелов
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return hashRefreshToken(normalized)
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"ShopAvito/internal/models"
 	"ShopAvito/internal/repository"
+	"fmt"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,3 +32,25 @@ func (s *UserService) UserExists(username string) (bool, error) {
 func (s *UserService) GetBalance(username string) (int, error) {
 	return s.userRepo.GetUserBalance(username)
 }
+
+// Текущая роль пользователя
+func (s *UserService) GetRole(username string) (string, error) {
+	return s.userRepo.GetUserRole(username)
+}
+
+// Назначение роли администратором. Свою роль менять нельзя, чтобы не остаться без администраторов.
+// Уже выданные JWT сохраняют старые области действия до обновления, но доступ к /api/admin
+// AdminMiddleware проверяет по текущей роли.
+func (s *UserService) SetRole(admin, username, role string) error {
+	if !IsValidRole(role) {
+		return validationError([]models.FieldError{fieldError("role", "unknown_role", fmt.Sprintf("unknown role %q", role))})
+	}
+	if admin == username {
+		return models.ErrCannotChangeOwnRole
+	}
+	if err := s.userRepo.SetUserRole(username, role); err != nil {
+		return err
+	}
+	s.log.Infof("Admin %s set role of user %s to %s", admin, username, role)
+	return nil
+}
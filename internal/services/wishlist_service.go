@@ -0,0 +1,216 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// WishlistNotifier получает события, о которых нужно сообщить ожидающим товар пользователям
+type WishlistNotifier interface {
+	ItemDiscounted(campaign models.Campaign)
+	ItemRestocked(itemName, variant string)
+	CoinsReceived(username string, amount int)
+}
+
+type WishlistService struct {
+	wishlistRepo     repository.WishlistRepositoryInterface
+	notificationRepo repository.NotificationRepositoryInterface
+	catalogRepo      repository.CatalogRepositoryInterface
+	discountRepo     repository.DiscountRepositoryInterface
+	userService      UserServiceInterface
+	log              *logrus.Logger
+	now              func() time.Time
+}
+
+func NewWishlistService(wishlistRepo repository.WishlistRepositoryInterface, notificationRepo repository.NotificationRepositoryInterface, catalogRepo repository.CatalogRepositoryInterface, discountRepo repository.DiscountRepositoryInterface, userService UserServiceInterface, log *logrus.Logger) *WishlistService {
+	return &WishlistService{
+		wishlistRepo:     wishlistRepo,
+		notificationRepo: notificationRepo,
+		catalogRepo:      catalogRepo,
+		discountRepo:     discountRepo,
+		userService:      userService,
+		log:              log,
+		now:              time.Now,
+	}
+}
+
+// Вишлист с текущими ценами (с учётом кампаний) и недостающей до покупки суммой
+func (s *WishlistService) GetWishlist(username string) ([]models.WishlistItem, int, error) {
+	balance, err := s.userService.GetBalance(username)
+	if err != nil {
+		s.log.Errorf("Error getting balance of %s: %v", username, err)
+		return nil, 0, err
+	}
+
+	items, err := s.wishlistRepo.GetWishlist(username)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = s.fillPrices(items, balance); err != nil {
+		s.log.Errorf("Error pricing wishlist of %s: %v", username, err)
+		return nil, 0, err
+	}
+	return items, balance, nil
+}
+
+func (s *WishlistService) fillPrices(items []models.WishlistItem, balance int) error {
+	for i := range items {
+		quote, err := quotePrice(s.discountRepo, items[i].ItemName, items[i].ListPrice, "", s.now())
+		if err != nil {
+			return err
+		}
+		items[i].Price = quote.Price
+		items[i].CoinsNeeded = 0
+		if quote.Price > balance {
+			items[i].CoinsNeeded = quote.Price - balance
+		}
+	}
+	return nil
+}
+
+func (s *WishlistService) AddItem(username, itemName string) error {
+	itemName = strings.TrimSpace(itemName)
+	if itemName == "" {
+		return errors.New("item is required")
+	}
+	if _, err := s.catalogRepo.GetItem(itemName); err != nil {
+		return err
+	}
+	return s.wishlistRepo.AddItem(username, itemName)
+}
+
+func (s *WishlistService) RemoveItem(username, itemName string) error {
+	return s.wishlistRepo.RemoveItem(username, itemName)
+}
+
+func (s *WishlistService) GetNotifications(username string, unreadOnly bool) ([]models.Notification, error) {
+	return s.notificationRepo.GetNotifications(username, unreadOnly)
+}
+
+func (s *WishlistService) MarkNotificationsRead(username string) error {
+	return s.notificationRepo.MarkAllRead(username)
+}
+
+// Ошибки уведомлений не должны ломать операцию, которая их вызвала, поэтому только логируются
+func (s *WishlistService) notify(notifications []models.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	if err := s.notificationRepo.CreateNotifications(notifications); err != nil {
+		s.log.Errorf("Error creating notifications: %v", err)
+	}
+}
+
+// Новая кампания снижает цену товаров из вишлистов. Уведомление отправляется, только если цена
+// со скидками действительно падает: кампания не выгоднее уже действующих её не меняет.
+func (s *WishlistService) ItemDiscounted(campaign models.Campaign) {
+	items, err := s.wishlistRepo.GetWishlistsByItems(campaign.ItemNames)
+	if err != nil {
+		s.log.Errorf("Error fetching wishlists for campaign %d: %v", campaign.ID, err)
+		return
+	}
+
+	at := campaign.StartsAt
+	if at.Before(s.now()) {
+		at = s.now()
+	}
+	var notifications []models.Notification
+	for _, item := range items {
+		previous, err := s.priceWithoutCampaign(item, campaign.ID, at)
+		if err != nil {
+			s.log.Errorf("Error pricing %s for campaign %d: %v", item.ItemName, campaign.ID, err)
+			continue
+		}
+		price := item.ListPrice - discountAmount(item.ListPrice, campaign.DiscountType, campaign.DiscountValue)
+		if price >= previous {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			Username: item.Username,
+			Type:     models.NotificationPriceDrop,
+			ItemName: item.ItemName,
+			Message: fmt.Sprintf("%s costs %d coins instead of %d from %s to %s", item.ItemName, price, previous,
+				campaign.StartsAt.Format(time.RFC3339), campaign.EndsAt.Format(time.RFC3339)),
+		})
+	}
+	s.notify(notifications)
+}
+
+// Цена со скидками других кампаний, действующих в момент at
+func (s *WishlistService) priceWithoutCampaign(item models.WishlistItem, campaignID int, at time.Time) (int, error) {
+	campaigns, err := s.discountRepo.GetActiveCampaigns(item.ItemName, at)
+	if err != nil {
+		return 0, err
+	}
+	best := 0
+	for _, other := range campaigns {
+		if other.ID == campaignID {
+			continue
+		}
+		if amount := discountAmount(item.ListPrice, other.DiscountType, other.DiscountValue); amount > best {
+			best = amount
+		}
+	}
+	return item.ListPrice - best, nil
+}
+
+// Вариант товара снова появился на складе
+func (s *WishlistService) ItemRestocked(itemName, variant string) {
+	items, err := s.wishlistRepo.GetWishlistsByItems([]string{itemName})
+	if err != nil {
+		s.log.Errorf("Error fetching wishlists for %s: %v", itemName, err)
+		return
+	}
+
+	message := fmt.Sprintf("%s is back in stock", itemName)
+	if variant != models.DefaultVariant {
+		message = fmt.Sprintf("%s (%s) is back in stock", itemName, variant)
+	}
+	var notifications []models.Notification
+	for _, item := range items {
+		notifications = append(notifications, models.Notification{
+			Username: item.Username,
+			Type:     models.NotificationRestock,
+			ItemName: item.ItemName,
+			Message:  message,
+		})
+	}
+	s.notify(notifications)
+}
+
+// Входящий перевод: уведомляем о товарах, на которые монет не хватало, а теперь хватает
+func (s *WishlistService) CoinsReceived(username string, amount int) {
+	balance, err := s.userService.GetBalance(username)
+	if err != nil {
+		s.log.Errorf("Error getting balance of %s: %v", username, err)
+		return
+	}
+	items, err := s.wishlistRepo.GetWishlist(username)
+	if err != nil {
+		s.log.Errorf("Error fetching wishlist of %s: %v", username, err)
+		return
+	}
+	if err = s.fillPrices(items, balance); err != nil {
+		s.log.Errorf("Error pricing wishlist of %s: %v", username, err)
+		return
+	}
+
+	previous := balance - amount
+	var notifications []models.Notification
+	for _, item := range items {
+		if item.Price > previous && item.CoinsNeeded == 0 {
+			notifications = append(notifications, models.Notification{
+				Username: username,
+				Type:     models.NotificationAffordable,
+				ItemName: item.ItemName,
+				Message:  fmt.Sprintf("You can now afford %s for %d coins", item.ItemName, item.Price),
+			})
+		}
+	}
+	s.notify(notifications)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import "errors"
+
+// ErrBlobNotFound - объекта с таким ключом нет в хранилище
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore - хранилище файлов по ключу вида "items/abc.png"
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, string, error)
+	Delete(key string) error
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS auction_bids;
+
+DROP TABLE IF EXISTS auctions;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,32 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS auctions (
+    id SERIAL PRIMARY KEY,
+    item_name TEXT NOT NULL,
+    reserve_price INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    winner_id INT,
+    final_price INT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (winner_id) REFERENCES users(id),
+    CHECK (ends_at > starts_at),
+    CHECK (reserve_price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auctions_status_ends_at ON auctions (status, ends_at);
+
+CREATE TABLE IF NOT EXISTS auction_bids (
+    id SERIAL PRIMARY KEY,
+    auction_id INT NOT NULL,
+    user_id INT NOT NULL,
+    amount INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'held',
+    timestamp TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (auction_id) REFERENCES auctions(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_bids_auction_status ON auction_bids (auction_id, status);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_status_history;
+
+DROP TABLE IF EXISTS orders;
+
+ALTER TABLE purchases DROP COLUMN IF EXISTS refunded;
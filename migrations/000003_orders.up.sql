@@ -0,0 +1,36 @@
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS refunded BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    purchase_id INT NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    item_name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'placed',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    status TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    timestamp TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+-- Существующие покупки уже выданы: заводим для них заказы в конечном статусе delivered,
+-- иначе их можно было бы отменить и вернуть монеты
+INSERT INTO orders (purchase_id, user_id, item_name, status, created_at, updated_at)
+SELECT p.id, p.user_id, p.item_name, 'delivered', p.timestamp, p.timestamp
+FROM purchases p
+ON CONFLICT (purchase_id) DO NOTHING;
+
+INSERT INTO order_status_history (order_id, status, changed_by, timestamp)
+SELECT o.id, o.status, 'migration', o.created_at
+FROM orders o;
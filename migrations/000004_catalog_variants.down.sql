@@ -0,0 +1,17 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS variant;
+
+-- Перед возвратом старого ограничения сворачиваем варианты в одну строку на товар
+UPDATE inventory a
+SET quantity = s.total
+FROM (SELECT MIN(id) AS id, SUM(quantity) AS total FROM inventory GROUP BY user_id, item_type) s
+WHERE a.id = s.id;
+DELETE FROM inventory a
+USING inventory b
+WHERE a.user_id = b.user_id AND a.item_type = b.item_type AND a.id > b.id;
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_user_id_item_type_variant_key;
+ALTER TABLE inventory DROP COLUMN IF EXISTS variant;
+ALTER TABLE inventory ADD CONSTRAINT inventory_user_id_item_type_key UNIQUE (user_id, item_type);
+
+DROP TABLE IF EXISTS catalog_variants;
+
+DROP TABLE IF EXISTS catalog_items;
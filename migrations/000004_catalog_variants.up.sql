@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS catalog_items (
+    name TEXT PRIMARY KEY,
+    price INT NOT NULL,
+    variant_axes TEXT[] NOT NULL DEFAULT '{}',
+    CHECK (price >= 0)
+);
+
+-- stock = NULL означает, что остаток не ограничен
+CREATE TABLE IF NOT EXISTS catalog_variants (
+    id SERIAL PRIMARY KEY,
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    stock INT,
+    FOREIGN KEY (item_name) REFERENCES catalog_items(name) ON DELETE CASCADE,
+    UNIQUE (item_name, variant),
+    CHECK (stock >= 0)
+);
+
+INSERT INTO catalog_items (name, price, variant_axes) VALUES
+    ('t-shirt', 80, '{size}'),
+    ('cup', 20, '{}'),
+    ('book', 50, '{}'),
+    ('pen', 10, '{}'),
+    ('powerbank', 200, '{}'),
+    ('hoody', 300, '{size}'),
+    ('umbrella', 200, '{}'),
+    ('socks', 10, '{}'),
+    ('wallet', 50, '{}'),
+    ('pink-hoody', 500, '{}')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO catalog_variants (item_name, variant, attributes)
+SELECT name, 'default', '{}' FROM catalog_items WHERE variant_axes = '{}'
+ON CONFLICT (item_name, variant) DO NOTHING;
+
+INSERT INTO catalog_variants (item_name, variant, attributes)
+SELECT i.name, 'size=' || s.size, jsonb_build_object('size', s.size)
+FROM catalog_items i
+CROSS JOIN (VALUES ('S'), ('M'), ('L'), ('XL')) AS s(size)
+WHERE i.variant_axes = '{size}'
+ON CONFLICT (item_name, variant) DO NOTHING;
+
+-- Существующие записи относятся к варианту по умолчанию
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_user_id_item_type_key;
+ALTER TABLE inventory ADD CONSTRAINT inventory_user_id_item_type_variant_key UNIQUE (user_id, item_type, variant);
+
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT 'default';
+
+-- Размер покупок, сделанных до появления вариантов, неизвестен, поэтому у товаров с размерами вариант
+-- default остаётся для старых записей. Купить его нельзя: без размера вариант не определяется, а остаток 0.
+INSERT INTO catalog_variants (item_name, variant, attributes, stock)
+SELECT name, 'default', '{}', 0 FROM catalog_items WHERE variant_axes <> '{}'
+ON CONFLICT (item_name, variant) DO NOTHING;
@@ -0,0 +1,10 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS promo_code_id;
+ALTER TABLE purchases DROP COLUMN IF EXISTS campaign_id;
+ALTER TABLE purchases DROP COLUMN IF EXISTS discount;
+ALTER TABLE purchases DROP COLUMN IF EXISTS list_price;
+
+DROP TABLE IF EXISTS promo_code_redemptions;
+
+DROP TABLE IF EXISTS promo_codes;
+
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,55 @@
+-- item_names = '{}' означает, что скидка действует на весь каталог
+CREATE TABLE IF NOT EXISTS campaigns (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    item_names TEXT[] NOT NULL DEFAULT '{}',
+    discount_type TEXT NOT NULL,
+    discount_value INT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (discount_type IN ('percent', 'fixed')),
+    CHECK (discount_value > 0),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_period ON campaigns (starts_at, ends_at);
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    item_names TEXT[] NOT NULL DEFAULT '{}',
+    discount_type TEXT NOT NULL,
+    discount_value INT NOT NULL,
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (discount_type IN ('percent', 'fixed')),
+    CHECK (discount_value > 0),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (max_uses > 0),
+    CHECK (uses <= max_uses)
+);
+
+-- Один пользователь может применить промокод только один раз
+CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INT NOT NULL,
+    user_id INT NOT NULL,
+    purchase_id INT NOT NULL,
+    timestamp TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(id),
+    UNIQUE (promo_code_id, user_id)
+);
+
+-- price остаётся фактически уплаченной суммой
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS list_price INT;
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS discount INT NOT NULL DEFAULT 0;
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS campaign_id INT REFERENCES campaigns(id);
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS promo_code_id INT REFERENCES promo_codes(id);
+UPDATE purchases SET list_price = price WHERE list_price IS NULL;
+ALTER TABLE purchases ALTER COLUMN list_price SET NOT NULL;
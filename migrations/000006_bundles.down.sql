@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS purchase_components;
+
+DROP TABLE IF EXISTS catalog_bundle_items;
+
+DELETE FROM catalog_items WHERE name = 'welcome-pack';
@@ -0,0 +1,38 @@
+-- Состав набора. variant = NULL означает, что вариант компонента совпадает с вариантом набора
+CREATE TABLE IF NOT EXISTS catalog_bundle_items (
+    id SERIAL PRIMARY KEY,
+    bundle_name TEXT NOT NULL,
+    item_name TEXT NOT NULL,
+    variant TEXT,
+    quantity INT NOT NULL DEFAULT 1,
+    FOREIGN KEY (bundle_name) REFERENCES catalog_items(name) ON DELETE CASCADE,
+    FOREIGN KEY (item_name) REFERENCES catalog_items(name),
+    UNIQUE (bundle_name, item_name),
+    CHECK (quantity > 0),
+    CHECK (bundle_name <> item_name)
+);
+
+-- Что фактически попало в инвентарь при покупке набора, чтобы возврат не зависел от последующих правок состава
+CREATE TABLE IF NOT EXISTS purchase_components (
+    id SERIAL PRIMARY KEY,
+    purchase_id INT NOT NULL,
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    quantity INT NOT NULL,
+    FOREIGN KEY (purchase_id) REFERENCES purchases(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_components_purchase_id ON purchase_components (purchase_id);
+
+INSERT INTO catalog_items (name, price, variant_axes) VALUES ('welcome-pack', 90, '{size}')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO catalog_variants (item_name, variant, attributes)
+SELECT 'welcome-pack', variant, attributes FROM catalog_variants WHERE item_name = 't-shirt' AND variant <> 'default'
+ON CONFLICT (item_name, variant) DO NOTHING;
+
+INSERT INTO catalog_bundle_items (bundle_name, item_name, variant, quantity) VALUES
+    ('welcome-pack', 't-shirt', NULL, 1),
+    ('welcome-pack', 'cup', 'default', 1),
+    ('welcome-pack', 'pen', 'default', 1)
+ON CONFLICT (bundle_name, item_name) DO NOTHING;
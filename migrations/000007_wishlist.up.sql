@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS wishlist (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    item_name TEXT NOT NULL,
+    added_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_name) REFERENCES catalog_items(name) ON DELETE CASCADE,
+    UNIQUE (user_id, item_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_item_name ON wishlist (item_name);
+
+-- Уведомления пользователю: снижение цены, поступление на склад, хватает монет на товар
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    type TEXT NOT NULL,
+    item_name TEXT NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (type IN ('price_drop', 'restock', 'affordable'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, created_at);
@@ -0,0 +1,17 @@
+-- Отзыв о товаре, один на пользователя. hidden - скрыт модератором
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    item_name TEXT NOT NULL,
+    rating INT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_name) REFERENCES catalog_items(name) ON DELETE CASCADE,
+    UNIQUE (user_id, item_name),
+    CHECK (rating BETWEEN 1 AND 5)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_item_name ON reviews (item_name);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_catalog_items_tags;
+DROP INDEX IF EXISTS idx_catalog_items_category;
+DROP INDEX IF EXISTS idx_catalog_items_search;
+
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS tags;
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS category;
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS description;
@@ -0,0 +1,27 @@
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'other';
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Полнотекстовый поиск по названию и описанию. Конфигурация simple не зависит от языка описания.
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || description)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_catalog_items_search ON catalog_items USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_catalog_items_category ON catalog_items (category);
+CREATE INDEX IF NOT EXISTS idx_catalog_items_tags ON catalog_items USING GIN (tags);
+
+UPDATE catalog_items SET category = v.category, description = v.description, tags = v.tags
+FROM (VALUES
+    ('t-shirt', 'clothing', 'Хлопковая футболка с логотипом Авито', '{cotton,logo}'::TEXT[]),
+    ('hoody', 'clothing', 'Тёплое худи с капюшоном и логотипом Авито', '{cotton,logo,warm}'::TEXT[]),
+    ('pink-hoody', 'clothing', 'Розовое худи ограниченной серии', '{limited,warm}'::TEXT[]),
+    ('socks', 'clothing', 'Носки с фирменным узором', '{cotton}'::TEXT[]),
+    ('cup', 'kitchen', 'Керамическая кружка для чая и кофе', '{ceramic,logo}'::TEXT[]),
+    ('book', 'stationery', 'Записная книжка в твёрдой обложке', '{paper}'::TEXT[]),
+    ('pen', 'stationery', 'Шариковая ручка с логотипом', '{logo}'::TEXT[]),
+    ('powerbank', 'electronics', 'Внешний аккумулятор для телефона', '{gadget}'::TEXT[]),
+    ('umbrella', 'accessories', 'Складной зонт от дождя', '{travel}'::TEXT[]),
+    ('wallet', 'accessories', 'Кожаный кошелёк', '{leather}'::TEXT[]),
+    ('welcome-pack', 'bundles', 'Набор новичка: футболка, кружка и ручка', '{logo}'::TEXT[])
+) AS v(name, category, description, tags)
+WHERE catalog_items.name = v.name;
@@ -0,0 +1,2 @@
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS thumbnail_key;
+ALTER TABLE catalog_items DROP COLUMN IF EXISTS image_key;
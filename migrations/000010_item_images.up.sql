@@ -0,0 +1,3 @@
+-- Ключи изображения товара и его миниатюры в хранилище файлов
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS image_key TEXT;
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS thumbnail_key TEXT;
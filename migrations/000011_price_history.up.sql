@@ -0,0 +1,19 @@
+-- История цен и запланированные изменения. applied = FALSE - изменение ещё не перенесено в catalog_items.
+CREATE TABLE IF NOT EXISTS catalog_price_history (
+    id SERIAL PRIMARY KEY,
+    item_name TEXT NOT NULL,
+    price INT NOT NULL,
+    effective_at TIMESTAMP NOT NULL,
+    changed_by TEXT NOT NULL,
+    applied BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (item_name) REFERENCES catalog_items(name) ON DELETE CASCADE,
+    CHECK (price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_price_history_item ON catalog_price_history (item_name, effective_at);
+CREATE INDEX IF NOT EXISTS idx_catalog_price_history_pending ON catalog_price_history (effective_at) WHERE NOT applied;
+
+-- Текущие цены - начальная точка истории
+INSERT INTO catalog_price_history (item_name, price, effective_at, changed_by, applied)
+SELECT name, price, NOW(), 'system', TRUE FROM catalog_items;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pre_orders;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_reserved_check;
+ALTER TABLE users DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,27 @@
+-- Монеты, зарезервированные под предзаказы. Доступный баланс - balance - reserved.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reserved INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD CONSTRAINT users_reserved_check CHECK (reserved >= 0 AND reserved <= balance);
+
+-- Предзаказы товаров, которых нет в наличии. Цена фиксируется при оформлении,
+-- монеты списываются при поступлении товара (captured) или возвращаются (cancelled, released).
+CREATE TABLE IF NOT EXISTS pre_orders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    list_price INT NOT NULL,
+    discount INT NOT NULL DEFAULT 0,
+    price INT NOT NULL,
+    campaign_id INT REFERENCES campaigns(id),
+    status TEXT NOT NULL DEFAULT 'reserved',
+    order_id INT REFERENCES orders(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (item_name, variant) REFERENCES catalog_variants(item_name, variant),
+    CHECK (status IN ('reserved', 'captured', 'cancelled', 'released')),
+    CHECK (price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pre_orders_user ON pre_orders (user_id);
+CREATE INDEX IF NOT EXISTS idx_pre_orders_reserved ON pre_orders (item_name, variant, created_at) WHERE status = 'reserved';
@@ -0,0 +1,3 @@
+-- Имена пользователей уникальны без учёта регистра: "Alice" и "alice" - один аккаунт.
+-- Если в базе уже есть такие пары, их нужно разрешить до применения миграции.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
@@ -0,0 +1,4 @@
+-- Роль пользователя вместо флага администратора: user, moderator или admin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+UPDATE users SET role = 'admin' WHERE is_admin;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,3 @@
+-- Удаление аккаунта: строка пользователя остаётся, чтобы переводы, покупки и заказы не теряли связей,
+-- а имя заменяется на deleted#<id> и отмечается время удаления
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
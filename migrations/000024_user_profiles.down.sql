@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS department,
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS avatar_key,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,8 @@
+-- Профиль пользователя: отображаемое имя, аватар, отдел, должность и дата регистрации.
+-- Дата регистрации существующих пользователей неизвестна, им ставится время миграции.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS department TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT NOW();
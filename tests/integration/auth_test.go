@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestAuthAPI(t *testing.T) {
@@ -29,9 +30,12 @@ func TestAuthAPI(t *testing.T) {
 
 	// Инициализация сервисов и обработчиков
 	userRepo := repository.NewUserRepository(db, logrus.New())
-	authService := services.NewAuthService(userRepo, "secret-key", logrus.New())
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db, logrus.New())
+	keys, err := services.NewKeySet(services.NewHMACKey("", "secret-key"), time.Minute)
+	assert.NoError(t, err)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, nil, keys, services.PasswordPolicy{}, nil, nil, logrus.New())
 	userService := services.NewUserService(userRepo, logrus.New())
-	authHandler := handlers.NewAuthHandler(authService, userService, logrus.New())
+	authHandler := handlers.NewAuthHandler(authService, userService, true, logrus.New())
 
 	// Инициализация роутера
 	router := gin.Default()
@@ -58,6 +62,7 @@ func TestAuthAPI(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 		assert.NotEmpty(t, response.Token)
+		assert.NotEmpty(t, response.RefreshToken)
 	})
 
 	// Тест 2: Неверный пароль
@@ -0,0 +1,53 @@
+package integration
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/services"
+	"ShopAvito/tests/testutils"
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestBackfilledOrderCannotBeCancelled(t *testing.T) {
+	db, err := testutils.InitTestDB()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer cleanupTestDB(db)
+
+	senderID, _, err := testutils.CreateTestUsers(db)
+	assert.NoError(t, err)
+
+	// Покупка, сделанная до появления заказов
+	_, err = db.Exec(context.Background(),
+		"INSERT INTO purchases (user_id, item_name, list_price, price) VALUES ($1, 'cup', 20, 20)", senderID)
+	assert.NoError(t, err)
+
+	// Миграция заказов заводит для неё заказ
+	migration, err := os.ReadFile("../../migrations/000003_orders.up.sql")
+	assert.NoError(t, err)
+	_, err = db.Exec(context.Background(), string(migration))
+	assert.NoError(t, err)
+
+	orderRepo := repository.NewOrderRepository(db, logrus.New())
+	purchaseRepo := repository.NewPurchaseRepository(db, logrus.New())
+	orderService := services.NewOrderService(orderRepo, purchaseRepo, logrus.New())
+
+	orders, err := orderService.GetUserOrders("sender")
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, models.OrderStatusDelivered, orders[0].Status)
+
+	// Выданный заказ нельзя отменить ни пользователю, ни администратору, монеты не возвращаются
+	err = orderService.CancelUserOrder("sender", orders[0].ID)
+	assert.ErrorIs(t, err, models.ErrInvalidOrderTransition)
+	err = orderService.UpdateOrderStatus(orders[0].ID, models.OrderStatusCancelled, "admin")
+	assert.ErrorIs(t, err, models.ErrInvalidOrderTransition)
+
+	balance, err := repository.NewUserRepository(db, logrus.New()).GetUserBalance("sender")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, balance)
+}
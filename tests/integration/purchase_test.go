@@ -38,8 +38,10 @@ func TestBuyItemAPI(t *testing.T) {
 	purchaseRepo := repository.NewPurchaseRepository(db, logrus.New())
 	inventoryRepo := repository.NewInventoryRepository(db, logrus.New())
 	inventoryService := services.NewInventoryService(inventoryRepo)
-	purchaseService := services.NewPurchaseService(purchaseRepo, userRepo, inventoryRepo, logrus.New())
-	purchaseHandler := handlers.NewPurchaseHandler(purchaseService, inventoryService, logrus.New())
+	discountRepo := repository.NewDiscountRepository(db, logrus.New())
+	purchaseService := services.NewPurchaseService(purchaseRepo, userRepo, inventoryRepo, discountRepo, logrus.New())
+	catalogService := services.NewCatalogService(repository.NewCatalogRepository(db, logrus.New()), repository.NewDiscountRepository(db, logrus.New()), nil, logrus.New())
+	purchaseHandler := handlers.NewPurchaseHandler(purchaseService, inventoryService, catalogService, logrus.New())
 
 	// Инициализация роутера
 	router := gin.Default()
@@ -47,7 +49,7 @@ func TestBuyItemAPI(t *testing.T) {
 	router.GET("/api/buy/:item", purchaseHandler.BuyItem)
 
 	// Отправляем запрос на покупку товара
-	req, _ := http.NewRequest("GET", "/api/buy/t-shirt", nil)
+	req, _ := http.NewRequest("GET", "/api/buy/t-shirt?size=M", nil)
 	req.Header.Set("username", "sender") // Устанавливаем покупателя в заголовке
 
 	w := httptest.NewRecorder()
@@ -55,7 +57,7 @@ func TestBuyItemAPI(t *testing.T) {
 
 	// Проверяем ответ
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response map[string]string
+	var response map[string]interface{}
 	if err = json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("failed to unmarshal response: %v", err)
 	}
@@ -71,4 +73,5 @@ func TestBuyItemAPI(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(inventory))            // Должен быть один предмет
 	assert.Equal(t, "t-shirt", inventory[0].Type) // Проверяем тип предмета
+	assert.Equal(t, "size=M", inventory[0].Variant)
 }
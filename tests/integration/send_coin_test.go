@@ -21,7 +21,29 @@ import (
 
 func cleanupTestDB(db *pgxpool.Pool) {
 	_, err := db.Exec(context.Background(), `
+		DROP TABLE IF EXISTS api_tokens;
+		DROP TABLE IF EXISTS sessions;
+		DROP TABLE IF EXISTS oidc_login_states;
+		DROP TABLE IF EXISTS user_identities;
+		DROP TABLE IF EXISTS recovery_codes;
+		DROP TABLE IF EXISTS user_totp;
+		DROP TABLE IF EXISTS login_attempts;
+		DROP TABLE IF EXISTS password_reset_tokens;
+		DROP TABLE IF EXISTS revoked_tokens;
+		DROP TABLE IF EXISTS refresh_tokens;
+		DROP TABLE IF EXISTS pre_orders;
 		DROP TABLE IF EXISTS inventory;
+		DROP TABLE IF EXISTS catalog_price_history;
+		DROP TABLE IF EXISTS reviews;
+		DROP TABLE IF EXISTS purchase_components;
+		DROP TABLE IF EXISTS catalog_bundle_items;
+		DROP TABLE IF EXISTS catalog_variants;
+		DROP TABLE IF EXISTS catalog_items;
+		DROP TABLE IF EXISTS order_status_history;
+		DROP TABLE IF EXISTS orders;
+		DROP TABLE IF EXISTS promo_code_redemptions;
+		DROP TABLE IF EXISTS promo_codes;
+		DROP TABLE IF EXISTS campaigns;
 		DROP TABLE IF EXISTS purchases;
 		DROP TABLE IF EXISTS transactions;
 		DROP TABLE IF EXISTS users;
@@ -44,8 +66,8 @@ func TestSendCoinAPI(t *testing.T) {
 	// Инициализация сервисов и обработчиков
 	userRepo := repository.NewUserRepository(db, logrus.New())
 	transactionRepo := repository.NewTransactionRepository(db, logrus.New())
-	transactionService := services.NewTransactionService(transactionRepo, userRepo, logrus.New())
-	transactionHandler := handlers.NewTransactionHandler(transactionService, logrus.New())
+	transactionService := services.NewTransactionService(transactionRepo, userRepo, nil, logrus.New())
+	transactionHandler := handlers.NewTransactionHandler(transactionService, nil, logrus.New())
 
 	// Инициализация роутера
 	router := gin.Default()
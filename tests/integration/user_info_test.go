@@ -30,7 +30,7 @@ func TestGetUserInfoAPI(t *testing.T) {
 	userRepo := repository.NewUserRepository(db, logrus.New())
 	transactionRepo := repository.NewTransactionRepository(db, logrus.New())
 	inventoryRepo := repository.NewInventoryRepository(db, logrus.New())
-	transactionService := services.NewTransactionService(transactionRepo, userRepo, logrus.New())
+	transactionService := services.NewTransactionService(transactionRepo, userRepo, nil, logrus.New())
 	inventoryService := services.NewInventoryService(inventoryRepo)
 	userService := services.NewUserService(userRepo, logrus.New())
 	userHandler := handlers.NewUserHandler(userService, transactionService, inventoryService, logrus.New())
@@ -31,8 +31,18 @@ func applyMigrations(db *pgxpool.Pool) error {
 			id SERIAL PRIMARY KEY,
 			username TEXT UNIQUE NOT NULL,
 			password TEXT NOT NULL,
-			balance INTEGER DEFAULT 1000
+			balance INTEGER DEFAULT 1000,
+			reserved INTEGER NOT NULL DEFAULT 0,
+			tokens_revoked_before TIMESTAMP,
+			role TEXT NOT NULL DEFAULT 'user',
+			deleted_at TIMESTAMP,
+			display_name TEXT NOT NULL DEFAULT '',
+			department TEXT NOT NULL DEFAULT '',
+			title TEXT NOT NULL DEFAULT '',
+			avatar_key TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
 		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
 
 		CREATE TABLE IF NOT EXISTS transactions (
 			id SERIAL PRIMARY KEY,
@@ -48,19 +58,251 @@ func applyMigrations(db *pgxpool.Pool) error {
 			id SERIAL PRIMARY KEY,
 			user_id INTEGER NOT NULL,
 			item_name TEXT NOT NULL,
+			variant TEXT NOT NULL DEFAULT 'default',
+			list_price INTEGER NOT NULL,
+			discount INTEGER NOT NULL DEFAULT 0,
 			price INTEGER NOT NULL,
+			campaign_id INTEGER,
+			promo_code_id INTEGER,
 			timestamp TIMESTAMP DEFAULT NOW(),
+			refunded BOOLEAN NOT NULL DEFAULT FALSE,
 			FOREIGN KEY (user_id) REFERENCES users(id)
 		);
 
+		CREATE TABLE IF NOT EXISTS campaigns (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			item_names TEXT[] NOT NULL DEFAULT '{}',
+			discount_type TEXT NOT NULL,
+			discount_value INTEGER NOT NULL,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS promo_codes (
+			id SERIAL PRIMARY KEY,
+			code TEXT UNIQUE NOT NULL,
+			item_names TEXT[] NOT NULL DEFAULT '{}',
+			discount_type TEXT NOT NULL,
+			discount_value INTEGER NOT NULL,
+			max_uses INTEGER NOT NULL DEFAULT 1,
+			uses INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+			id SERIAL PRIMARY KEY,
+			promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id),
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			purchase_id INTEGER NOT NULL REFERENCES purchases(id),
+			timestamp TIMESTAMP DEFAULT NOW(),
+			UNIQUE (promo_code_id, user_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS orders (
+			id SERIAL PRIMARY KEY,
+			purchase_id INTEGER NOT NULL UNIQUE,
+			user_id INTEGER NOT NULL,
+			item_name TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'placed',
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			FOREIGN KEY (purchase_id) REFERENCES purchases(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS order_status_history (
+			id SERIAL PRIMARY KEY,
+			order_id INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			changed_by TEXT NOT NULL,
+			timestamp TIMESTAMP DEFAULT NOW(),
+			FOREIGN KEY (order_id) REFERENCES orders(id)
+		);
+
 		CREATE TABLE IF NOT EXISTS inventory (
 			id SERIAL PRIMARY KEY,
 			user_id INTEGER NOT NULL,
 			item_type TEXT NOT NULL,
+			variant TEXT NOT NULL DEFAULT 'default',
 			quantity INTEGER NOT NULL DEFAULT 1,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		    UNIQUE (user_id, item_type)
+		    UNIQUE (user_id, item_type, variant)
+		);
+
+		CREATE TABLE IF NOT EXISTS catalog_items (
+			name TEXT PRIMARY KEY,
+			price INTEGER NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT 'other',
+			tags TEXT[] NOT NULL DEFAULT '{}',
+			image_key TEXT,
+			thumbnail_key TEXT,
+			variant_axes TEXT[] NOT NULL DEFAULT '{}',
+			search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || description)) STORED
+		);
+
+		CREATE TABLE IF NOT EXISTS catalog_variants (
+			id SERIAL PRIMARY KEY,
+			item_name TEXT NOT NULL REFERENCES catalog_items(name) ON DELETE CASCADE,
+			variant TEXT NOT NULL,
+			attributes JSONB NOT NULL DEFAULT '{}',
+			stock INTEGER,
+			UNIQUE (item_name, variant)
+		);
+
+		CREATE TABLE IF NOT EXISTS catalog_bundle_items (
+			id SERIAL PRIMARY KEY,
+			bundle_name TEXT NOT NULL REFERENCES catalog_items(name) ON DELETE CASCADE,
+			item_name TEXT NOT NULL REFERENCES catalog_items(name),
+			variant TEXT,
+			quantity INTEGER NOT NULL DEFAULT 1,
+			UNIQUE (bundle_name, item_name)
+		);
+
+		CREATE TABLE IF NOT EXISTS catalog_price_history (
+			id SERIAL PRIMARY KEY,
+			item_name TEXT NOT NULL REFERENCES catalog_items(name) ON DELETE CASCADE,
+			price INTEGER NOT NULL,
+			effective_at TIMESTAMP NOT NULL,
+			changed_by TEXT NOT NULL,
+			applied BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS reviews (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			item_name TEXT NOT NULL REFERENCES catalog_items(name) ON DELETE CASCADE,
+			rating INTEGER NOT NULL,
+			text TEXT NOT NULL DEFAULT '',
+			hidden BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (user_id, item_name)
 		);
+
+		CREATE TABLE IF NOT EXISTS purchase_components (
+			id SERIAL PRIMARY KEY,
+			purchase_id INTEGER NOT NULL REFERENCES purchases(id),
+			item_name TEXT NOT NULL,
+			variant TEXT NOT NULL,
+			quantity INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS revoked_tokens (
+			jti TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_by TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS login_attempts (
+			key TEXT PRIMARY KEY,
+			failures INT NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret TEXT NOT NULL,
+			enabled_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash TEXT NOT NULL,
+			used_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (issuer, subject)
+		);
+
+		CREATE TABLE IF NOT EXISTS oidc_login_states (
+			state TEXT PRIMARY KEY,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			last_used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (user_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS sessions (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id TEXT NOT NULL UNIQUE,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_used_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS pre_orders (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			item_name TEXT NOT NULL,
+			variant TEXT NOT NULL,
+			list_price INTEGER NOT NULL,
+			discount INTEGER NOT NULL DEFAULT 0,
+			price INTEGER NOT NULL,
+			campaign_id INTEGER,
+			status TEXT NOT NULL DEFAULT 'reserved',
+			order_id INTEGER,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);
+
+		INSERT INTO catalog_items (name, price, variant_axes) VALUES
+			('t-shirt', 80, '{size}'),
+			('cup', 20, '{}')
+		ON CONFLICT (name) DO NOTHING;
+
+		INSERT INTO catalog_variants (item_name, variant, attributes) VALUES
+			('t-shirt', 'size=M', '{"size": "M"}'),
+			('cup', 'default', '{}')
+		ON CONFLICT (item_name, variant) DO NOTHING;
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"archive/zip"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockAccountService struct {
+	mock.Mock
+}
+
+func (m *MockAccountService) Export(username string) (*models.AccountExport, error) {
+	args := m.Called(username)
+	export, _ := args.Get(0).(*models.AccountExport)
+	return export, args.Error(1)
+}
+
+func (m *MockAccountService) Delete(username, password string) error {
+	args := m.Called(username, password)
+	return args.Error(0)
+}
+
+func newAccountRouter(accountService *MockAccountService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := handlers.NewAccountHandler(accountService, logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Next()
+	})
+	router.GET("/api/me/export", handler.ExportAccount)
+	router.DELETE("/api/me", handler.DeleteAccount)
+	return router
+}
+
+func testAccountExport() *models.AccountExport {
+	return &models.AccountExport{
+		Profile: models.AccountProfile{
+			UserProfile: models.UserProfile{Username: "alice", DisplayName: "Alice Smith", Department: "Backend"},
+			Role:        "user",
+			Coins:       900,
+		},
+		CoinHistory: []models.Transaction{{ID: 1, FromUser: "alice", ToUser: "bob", Amount: 100}},
+		Purchases:   []models.Purchase{{ID: 2, ItemName: "t-shirt", Price: 80}},
+		Inventory:   []models.InventoryItem{{Type: "t-shirt", Quantity: 1}},
+	}
+}
+
+func TestExportAccount_JSON(t *testing.T) {
+	mockService := new(MockAccountService)
+	mockService.On("Export", "alice").Return(testAccountExport(), nil)
+	router := newAccountRouter(mockService)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/me/export?format=json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "alice-export.json")
+	assert.Contains(t, w.Body.String(), `"coin_history"`)
+	assert.Contains(t, w.Body.String(), `"t-shirt"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/me/export?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNumberOfCalls(t, "Export", 1)
+}
+
+func TestExportAccount_Zip(t *testing.T) {
+	mockService := new(MockAccountService)
+	mockService.On("Export", "alice").Return(testAccountExport(), nil)
+
+	w := httptest.NewRecorder()
+	newAccountRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/me/export", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "alice-export.zip")
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		assert.NoError(t, err)
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		files[file.Name] = string(data)
+	}
+	assert.Len(t, files, 4)
+	assert.Contains(t, files["profile.json"], `"username": "alice"`)
+	assert.Contains(t, files["profile.json"], `"display_name": "Alice Smith"`)
+	assert.Contains(t, files["coin_history.json"], `"bob"`)
+	assert.Contains(t, files["purchases.json"], `"t-shirt"`)
+	assert.Contains(t, files["inventory.json"], `"quantity": 1`)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		password string
+		err      error
+		expected int
+	}{
+		{"success", `{"password":"secret"}`, "secret", nil, http.StatusOK},
+		{"no password for sso user", ``, "", nil, http.StatusOK},
+		{"wrong password", `{"password":"wrong"}`, "wrong", models.ErrInvalidPassword, http.StatusForbidden},
+		{"pending operations", `{"password":"secret"}`, "secret", models.ErrAccountHasPendingOperations, http.StatusConflict},
+		{"not found", `{"password":"secret"}`, "secret", models.ErrUserNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAccountService)
+			mockService.On("Delete", "alice", tt.password).Return(tt.err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/api/me", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			newAccountRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
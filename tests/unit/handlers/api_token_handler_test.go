@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/middleware"
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockAPITokenService struct {
+	mock.Mock
+}
+
+func (m *MockAPITokenService) Create(username string, req models.CreateAPITokenRequest) (*models.CreatedAPIToken, error) {
+	args := m.Called(username, req)
+	token, _ := args.Get(0).(*models.CreatedAPIToken)
+	return token, args.Error(1)
+}
+
+func (m *MockAPITokenService) List(username string) ([]models.APIToken, error) {
+	args := m.Called(username)
+	tokens, _ := args.Get(0).([]models.APIToken)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAPITokenService) Revoke(username string, id int) error {
+	return m.Called(username, id).Error(0)
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mockService := new(MockAPITokenService)
+	handler := handlers.NewAPITokenHandler(mockService, logger)
+
+	req := models.CreateAPITokenRequest{Name: "slack-bot", Scopes: []string{"transfer:send"}}
+	mockService.On("Create", "alice", req).Return(&models.CreatedAPIToken{
+		APIToken: models.APIToken{ID: 1, Name: "slack-bot", Scopes: req.Scopes},
+		Token:    "shop_pat_secret",
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/tokens", bytes.NewBufferString(`{"name":"slack-bot","scopes":["transfer:send"]}`))
+	c.Set("username", "alice")
+	handler.CreateToken(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"token":"shop_pat_secret"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestScopeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Вместо AuthMiddleware: API-токен только на чтение, JWT обычного пользователя или токен без областей
+	router.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-Test-Token") {
+		case "read":
+			c.Set("scopes", []string{services.ScopeInfoRead})
+		case "user":
+			c.Set("scopes", services.RoleScopes(services.RoleUser))
+		}
+		c.Next()
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/info", middleware.RequireScope(services.ScopeInfoRead), ok)
+	router.POST("/sendCoin", middleware.RequireScope(services.ScopeTransferSend), ok)
+	router.GET("/buy/:item", middleware.RequireScope(services.ScopePurchaseWrite), ok)
+	router.GET("/tokens", middleware.RequireScope(services.ScopeAccountManage), ok)
+	router.GET("/admin/orders", middleware.RequireScope(services.ScopeAdminOrders), ok)
+
+	tests := []struct {
+		method, path, token string
+		expected            int
+	}{
+		{http.MethodGet, "/info", "read", http.StatusOK},
+		{http.MethodPost, "/sendCoin", "read", http.StatusForbidden},
+		{http.MethodGet, "/buy/pen", "read", http.StatusForbidden},
+		{http.MethodGet, "/tokens", "read", http.StatusForbidden},
+		{http.MethodPost, "/sendCoin", "user", http.StatusOK},
+		{http.MethodGet, "/tokens", "user", http.StatusOK},
+		{http.MethodGet, "/admin/orders", "user", http.StatusForbidden},
+		{http.MethodGet, "/info", "none", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-Test-Token", tt.token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.expected, w.Code, "%s %s with %s token", tt.method, tt.path, tt.token)
+	}
+}
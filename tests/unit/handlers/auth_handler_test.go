@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) GenerateToken(username string) (string, error) {
+	args := m.Called(username)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) ValidateToken(tokenString string) (*services.Claims, error) {
+	args := m.Called(tokenString)
+	claims, _ := args.Get(0).(*services.Claims)
+	return claims, args.Error(1)
+}
+
+func (m *MockAuthService) Login(username, password string, client models.ClientInfo) (*models.AuthResponse, error) {
+	args := m.Called(username, password, client)
+	tokens, _ := args.Get(0).(*models.AuthResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAuthService) Register(username, password string, client models.ClientInfo) (*models.AuthResponse, error) {
+	args := m.Called(username, password, client)
+	tokens, _ := args.Get(0).(*models.AuthResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAuthService) VerifyTwoFactor(challengeToken, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	args := m.Called(challengeToken, code, client)
+	tokens, _ := args.Get(0).(*models.AuthResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAuthService) Refresh(refreshToken string) (*models.AuthResponse, error) {
+	args := m.Called(refreshToken)
+	tokens, _ := args.Get(0).(*models.AuthResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockAuthService) Logout(refreshToken, accessToken string) error {
+	return m.Called(refreshToken, accessToken).Error(0)
+}
+
+func (m *MockAuthService) IsTokenRevoked(claims *services.Claims) (bool, error) {
+	args := m.Called(claims)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeUserTokens(username string, before *time.Time) (time.Time, error) {
+	args := m.Called(username, before)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockAuthService) UnlockLogin(username, ip string) error {
+	return m.Called(username, ip).Error(0)
+}
+
+func (m *MockAuthService) JWKS() models.JWKS {
+	return m.Called().Get(0).(models.JWKS)
+}
+
+func newAuthRouter(authService *MockAuthService, userService *MockUserService, autoRegister bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authHandler := handlers.NewAuthHandler(authService, userService, autoRegister, logger)
+
+	router := gin.New()
+	router.POST("/api/auth", authHandler.Authenticate)
+	router.POST("/api/auth/register", authHandler.Register)
+	router.POST("/api/auth/login", authHandler.Login)
+	return router
+}
+
+func postAuth(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler_Register(t *testing.T) {
+	authService := new(MockAuthService)
+	authService.On("Register", "alice", "secret", mock.Anything).Return(&models.AuthResponse{Token: "token"}, nil)
+	authService.On("Register", "bob", "secret", mock.Anything).Return(nil, models.ErrUserAlreadyExists)
+	router := newAuthRouter(authService, new(MockUserService), true)
+
+	w := postAuth(router, "/api/auth/register", `{"username": "alice", "password": "secret"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = postAuth(router, "/api/auth/register", `{"username": "bob", "password": "secret"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = postAuth(router, "/api/auth/register", `{"username": "alice"`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuthHandler_ValidationErrors(t *testing.T) {
+	authService := new(MockAuthService)
+	authService.On("Register", "a", "", mock.Anything).Return(nil, &models.ValidationError{Fields: []models.FieldError{
+		{Field: "username", Code: "too_short", Message: "username must be at least 3 characters"},
+		{Field: "password", Code: "required", Message: "password is required"},
+	}})
+	router := newAuthRouter(authService, new(MockUserService), true)
+
+	w := postAuth(router, "/api/auth/register", `{"username": "a"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Error  string              `json:"error"`
+		Fields []models.FieldError `json:"fields"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Validation failed", response.Error)
+	assert.Len(t, response.Fields, 2)
+	assert.Equal(t, "username", response.Fields[0].Field)
+	assert.Equal(t, "too_short", response.Fields[0].Code)
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	authService := new(MockAuthService)
+	authService.On("Login", "alice", "secret", mock.Anything).Return(&models.AuthResponse{Token: "token"}, nil)
+	authService.On("Login", "alice", "wrong", mock.Anything).Return(nil, models.ErrInvalidPassword)
+	authService.On("Login", "alcie", "secret", mock.Anything).Return(nil, models.ErrUserNotFound)
+	router := newAuthRouter(authService, new(MockUserService), true)
+
+	w := postAuth(router, "/api/auth/login", `{"username": "alice", "password": "secret"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = postAuth(router, "/api/auth/login", `{"username": "alice", "password": "wrong"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Опечатка в логине не создаёт аккаунт
+	w = postAuth(router, "/api/auth/login", `{"username": "alcie", "password": "secret"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	authService.AssertNotCalled(t, "Register", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthHandler_AuthenticateAutoRegister(t *testing.T) {
+	authService := new(MockAuthService)
+	authService.On("Register", "newbie", "secret", mock.Anything).Return(&models.AuthResponse{Token: "token"}, nil)
+	authService.On("Login", "newbie", "secret", mock.Anything).Return(nil, models.ErrUserNotFound)
+	userService := new(MockUserService)
+	userService.On("UserExists", "newbie").Return(false, nil)
+
+	// Прежний режим: неизвестный пользователь регистрируется
+	w := postAuth(newAuthRouter(authService, userService, true), "/api/auth", `{"username": "newbie", "password": "secret"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	authService.AssertCalled(t, "Register", "newbie", "secret", mock.Anything)
+
+	// Автоматическая регистрация выключена
+	authService.Calls = nil
+	w = postAuth(newAuthRouter(authService, userService, false), "/api/auth", `{"username": "newbie", "password": "secret"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	authService.AssertNotCalled(t, "Register", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthHandler_LoginLocked(t *testing.T) {
+	authService := new(MockAuthService)
+	authService.On("Login", "alice", "secret", mock.Anything).Return(nil, &models.LoginLockedError{RetryAfter: 90*time.Second + time.Millisecond})
+	router := newAuthRouter(authService, new(MockUserService), true)
+
+	w := postAuth(router, "/api/auth/login", `{"username": "alice", "password": "secret"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "91", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) LoginURL() (string, string, error) {
+	args := m.Called()
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCService) Callback(state, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	args := m.Called(state, code, client)
+	resp, _ := args.Get(0).(*models.AuthResponse)
+	return resp, args.Error(1)
+}
+
+func newOIDCRouter(oidcService *MockOIDCService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := handlers.NewOIDCHandler(oidcService, logger)
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", handler.Login)
+	router.GET("/api/auth/oidc/callback", handler.Callback)
+	return router
+}
+
+func TestOIDCLogin_Redirect(t *testing.T) {
+	mockService := new(MockOIDCService)
+	mockService.On("LoginURL").Return("https://idp.example/authorize?state=abc", "abc", nil)
+
+	w := httptest.NewRecorder()
+	newOIDCRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://idp.example/authorize?state=abc", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "abc", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+	}
+}
+
+func TestOIDCCallback_State(t *testing.T) {
+	mockService := new(MockOIDCService)
+	mockService.On("Callback", "abc", "code-1", mock.Anything).Return(&models.AuthResponse{Token: "jwt", RefreshToken: "refresh"}, nil)
+	router := newOIDCRouter(mockService)
+
+	// Ответ провайдера на вход, начатый в другом браузере, не принимается
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state=abc&code=code-1", nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "other"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "Callback", mock.Anything, mock.Anything, mock.Anything)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state=abc&code=code-1", nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "abc"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"token":"jwt"`)
+
+	// Отказ пользователя на странице провайдера
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?error=access_denied&state=abc", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockPasswordService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordService) ChangePassword(username, currentPassword, newPassword string) error {
+	return m.Called(username, currentPassword, newPassword).Error(0)
+}
+
+func (m *MockPasswordService) IssueResetToken(username, issuedBy string) (*models.PasswordResetToken, error) {
+	args := m.Called(username, issuedBy)
+	token, _ := args.Get(0).(*models.PasswordResetToken)
+	return token, args.Error(1)
+}
+
+func (m *MockPasswordService) ResetPassword(token, newPassword string) error {
+	return m.Called(token, newPassword).Error(0)
+}
+
+func newPasswordHandler(passwordService *MockPasswordService) *handlers.PasswordHandler {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return handlers.NewPasswordHandler(passwordService, logger)
+}
+
+func TestPasswordHandler_ChangePassword(t *testing.T) {
+	passwordService := new(MockPasswordService)
+	passwordService.On("ChangePassword", "alice", "old-password", "new-password").Return(nil)
+	passwordService.On("ChangePassword", "alice", "wrong", "new-password").Return(models.ErrInvalidPassword)
+	handler := newPasswordHandler(passwordService)
+
+	for body, code := range map[string]int{
+		`{"current_password": "old-password", "new_password": "new-password"}`: http.StatusOK,
+		`{"current_password": "wrong", "new_password": "new-password"}`:        http.StatusForbidden,
+		`{"current_password": `: http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/password", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("username", "alice")
+
+		handler.ChangePassword(c)
+		assert.Equal(t, code, w.Code, body)
+	}
+}
+
+func TestPasswordHandler_ResetPassword(t *testing.T) {
+	passwordService := new(MockPasswordService)
+	passwordService.On("IssueResetToken", "alice", "admin").Return(&models.PasswordResetToken{ResetToken: "reset"}, nil)
+	passwordService.On("ResetPassword", "reset", "new-password").Return(nil)
+	passwordService.On("ResetPassword", "used", "new-password").Return(models.ErrInvalidResetToken)
+	handler := newPasswordHandler(passwordService)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/users/alice/password-reset", nil)
+	c.Params = []gin.Param{{Key: "username", Value: "alice"}}
+	c.Set("username", "admin")
+	handler.IssueResetToken(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	for token, code := range map[string]int{"reset": http.StatusOK, "used": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/password/reset",
+			bytes.NewBufferString(`{"token": "`+token+`", "new_password": "new-password"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.ResetPassword(c)
+		assert.Equal(t, code, w.Code, token)
+	}
+}
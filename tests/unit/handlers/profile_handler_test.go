@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) Get(username string) (*models.UserProfile, error) {
+	args := m.Called(username)
+	profile, _ := args.Get(0).(*models.UserProfile)
+	return profile, args.Error(1)
+}
+
+func (m *MockProfileService) Update(username string, req models.UpdateProfileRequest) (*models.UserProfile, error) {
+	args := m.Called(username, req)
+	profile, _ := args.Get(0).(*models.UserProfile)
+	return profile, args.Error(1)
+}
+
+func (m *MockProfileService) UploadAvatar(username string, data []byte) (*models.UserProfile, error) {
+	args := m.Called(username, data)
+	profile, _ := args.Get(0).(*models.UserProfile)
+	return profile, args.Error(1)
+}
+
+func (m *MockProfileService) DeleteAvatar(username string) error {
+	args := m.Called(username)
+	return args.Error(0)
+}
+
+func newProfileRouter(profileService *MockProfileService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := handlers.NewProfileHandler(profileService, logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "anna")
+		c.Next()
+	})
+	router.GET("/api/me", handler.GetProfile)
+	router.PATCH("/api/me", handler.UpdateProfile)
+	router.PUT("/api/me/avatar", handler.UploadAvatar)
+	router.DELETE("/api/me/avatar", handler.DeleteAvatar)
+	return router
+}
+
+func TestGetProfile(t *testing.T) {
+	mockService := new(MockProfileService)
+	mockService.On("Get", "anna").Return(&models.UserProfile{
+		Username: "anna", DisplayName: "Anna Petrova", Department: "Backend", AvatarKey: "avatars/a.png", AvatarURL: "/images/avatars/a.png",
+	}, nil)
+
+	w := httptest.NewRecorder()
+	newProfileRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/me", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"display_name":"Anna Petrova"`)
+	assert.Contains(t, w.Body.String(), `"avatar_url":"/images/avatars/a.png"`)
+	assert.NotContains(t, w.Body.String(), "avatar_key")
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"success", `{"display_name":"Anna Petrova","department":"Backend"}`, nil, http.StatusOK},
+		{"too long", `{"title":"x"}`, &models.ValidationError{Fields: []models.FieldError{{Field: "title", Code: "too_long"}}}, http.StatusBadRequest},
+		{"no user", `{"title":"x"}`, models.ErrUserNotFound, http.StatusNotFound},
+		{"invalid json", `{"title":`, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			var profile *models.UserProfile
+			if tt.err == nil {
+				profile = &models.UserProfile{Username: "anna", DisplayName: "Anna Petrova"}
+			}
+			mockService.On("Update", "anna", mock.Anything).Return(profile, tt.err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			newProfileRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+
+	// Переданные поля доходят до сервиса, остальные остаются nil
+	mockService := new(MockProfileService)
+	mockService.On("Update", "anna", mock.MatchedBy(func(req models.UpdateProfileRequest) bool {
+		return req.DisplayName != nil && *req.DisplayName == "Anna" && req.Department == nil && req.Title == nil
+	})).Return(&models.UserProfile{Username: "anna"}, nil)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(`{"display_name":"Anna"}`))
+	newProfileRouter(mockService).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUploadAvatar(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "avatar.png")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("png-bytes"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	mockService := new(MockProfileService)
+	mockService.On("UploadAvatar", "anna", []byte("png-bytes")).Return(nil, models.ErrInvalidImage)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/me/avatar", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	newProfileRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+
+	w = httptest.NewRecorder()
+	newProfileRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/me/avatar", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteAvatar(t *testing.T) {
+	mockService := new(MockProfileService)
+	mockService.On("DeleteAvatar", "anna").Return(nil)
+
+	w := httptest.NewRecorder()
+	newProfileRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/me/avatar", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
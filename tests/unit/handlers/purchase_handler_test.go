@@ -3,6 +3,7 @@ package handlers
 import (
 	"ShopAvito/internal/handlers"
 	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -16,9 +17,9 @@ type MockPurchaseService struct {
 	mock.Mock
 }
 
-func (m *MockPurchaseService) BuyItem(username, item string, price int) error {
-	args := m.Called(username, item, price)
-	return args.Error(0)
+func (m *MockPurchaseService) BuyItem(username, item, variant, promoCode string) (int, models.PriceQuote, error) {
+	args := m.Called(username, item, variant, promoCode)
+	return args.Int(0), args.Get(1).(models.PriceQuote), args.Error(2)
 }
 
 func (m *MockPurchaseService) GetUserPurchases(username string) ([]models.Purchase, error) {
@@ -26,24 +27,73 @@ func (m *MockPurchaseService) GetUserPurchases(username string) ([]models.Purcha
 	return args.Get(0).([]models.Purchase), args.Error(1)
 }
 
+// StubCatalogService - каталог из двух товаров: футболка с размерами и кружка без вариантов
+type StubCatalogService struct{}
+
+func (s *StubCatalogService) SearchItems(query models.CatalogQuery, balance int) (*models.CatalogPage, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) ListCategories() ([]models.Category, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) GetItem(name string) (*models.CatalogItem, error) {
+	switch name {
+	case "t-shirt":
+		return &models.CatalogItem{Name: "t-shirt", Price: 80, VariantAxes: []string{"size"},
+			Variants: []models.CatalogVariant{{Key: "size=M"}, {Key: "size=L"}}}, nil
+	case "cup":
+		return &models.CatalogItem{Name: "cup", Price: 20,
+			Variants: []models.CatalogVariant{{Key: models.DefaultVariant}}}, nil
+	}
+	return nil, models.ErrItemNotFound
+}
+
+func (s *StubCatalogService) ResolveVariant(item *models.CatalogItem, attributes map[string]string) (string, error) {
+	return services.NewCatalogService(nil, nil, nil, nil).ResolveVariant(item, attributes)
+}
+
+func (s *StubCatalogService) UpsertVariant(itemName string, req models.UpsertVariantRequest) (*models.CatalogVariant, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) CreateBundle(req models.CreateBundleRequest) (*models.CatalogItem, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) SchedulePriceChange(itemName string, req models.SchedulePriceRequest, changedBy string) (*models.PriceChange, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) GetPriceHistory(itemName string) ([]models.PriceChange, error) {
+	return nil, nil
+}
+
+func (s *StubCatalogService) CancelPriceChange(id int) error {
+	return nil
+}
+
 func TestBuyItem_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockService := new(MockPurchaseService)
-	handler := handlers.NewPurchaseHandler(mockService, nil, logrus.New())
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/t-shirt?size=M", nil)
 	c.Params = []gin.Param{{Key: "item", Value: "t-shirt"}}
 	c.Set("username", "testuser")
 
-	mockService.On("BuyItem", "testuser", "t-shirt", 80).Return(nil)
+	mockService.On("BuyItem", "testuser", "t-shirt", "size=M", "").
+		Return(42, models.PriceQuote{ListPrice: 80, Price: 80}, nil)
 
 	handler.BuyItem(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.JSONEq(t, `{"message": "Purchase successful"}`, w.Body.String())
+	assert.JSONEq(t, `{"message": "Purchase successful", "order_id": 42, "list_price": 80, "discount": 0, "price": 80}`, w.Body.String())
 
 	mockService.AssertExpectations(t)
 }
@@ -52,11 +102,12 @@ func TestBuyItem_InvalidItem(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockService := new(MockPurchaseService)
-	handler := handlers.NewPurchaseHandler(mockService, nil, logrus.New())
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/unknown", nil)
 	c.Params = []gin.Param{{Key: "item", Value: "unknown"}}
 	c.Set("username", "testuser")
 
@@ -70,17 +121,106 @@ func TestBuyItem_ServiceError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockService := new(MockPurchaseService)
-	handler := handlers.NewPurchaseHandler(mockService, nil, logrus.New())
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/t-shirt?size=M", nil)
 	c.Params = []gin.Param{{Key: "item", Value: "t-shirt"}}
 	c.Set("username", "testuser")
 
-	mockService.On("BuyItem", "testuser", "t-shirt", 80).Return(assert.AnError)
+	mockService.On("BuyItem", "testuser", "t-shirt", "size=M", "").Return(0, models.PriceQuote{}, assert.AnError)
 
 	handler.BuyItem(c)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestBuyItem_DefaultVariant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPurchaseService)
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/cup", nil)
+	c.Params = []gin.Param{{Key: "item", Value: "cup"}}
+	c.Set("username", "testuser")
+
+	mockService.On("BuyItem", "testuser", "cup", models.DefaultVariant, "").
+		Return(1, models.PriceQuote{ListPrice: 20, Price: 20}, nil)
+
+	handler.BuyItem(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestBuyItem_InvalidVariant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, url := range []string{"/api/buy/t-shirt", "/api/buy/t-shirt?size=XXL"} {
+		mockService := new(MockPurchaseService)
+		handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest(http.MethodGet, url, nil)
+		c.Params = []gin.Param{{Key: "item", Value: "t-shirt"}}
+		c.Set("username", "testuser")
+
+		handler.BuyItem(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		mockService.AssertNotCalled(t, "BuyItem")
+	}
+}
+
+func TestBuyItem_OutOfStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPurchaseService)
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/t-shirt?size=L", nil)
+	c.Params = []gin.Param{{Key: "item", Value: "t-shirt"}}
+	c.Set("username", "testuser")
+
+	mockService.On("BuyItem", "testuser", "t-shirt", "size=L", "").Return(0, models.PriceQuote{}, models.ErrOutOfStock)
+
+	handler.BuyItem(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestBuyItem_PromoCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPurchaseService)
+	mockService.On("BuyItem", "testuser", "cup", models.DefaultVariant, "WINNER").
+		Return(3, models.PriceQuote{ListPrice: 20, Discount: 20, Price: 0}, nil)
+	mockService.On("BuyItem", "testuser", "cup", models.DefaultVariant, "USED").
+		Return(0, models.PriceQuote{}, models.ErrPromoCodeAlreadyUsed)
+	handler := handlers.NewPurchaseHandler(mockService, nil, &StubCatalogService{}, logrus.New())
+
+	// Промокод передаётся в сервис, ошибка промокода - это ошибка клиента
+	for code, expected := range map[string]int{"WINNER": http.StatusOK, "USED": http.StatusBadRequest} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/buy/cup?promo="+code, nil)
+		c.Params = []gin.Param{{Key: "item", Value: "cup"}}
+		c.Set("username", "testuser")
+
+		handler.BuyItem(c)
+
+		assert.Equal(t, expected, w.Code, code)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) SetRole(admin, username, role string) error {
+	args := m.Called(admin, username, role)
+	return args.Error(0)
+}
+
+func TestSetUserRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tests := []struct {
+		name, username, body string
+		err                  error
+		expected             int
+	}{
+		{"success", "alice", `{"role":"moderator"}`, nil, http.StatusOK},
+		{"unknown role", "alice", `{"role":"root"}`, &models.ValidationError{Fields: []models.FieldError{{Field: "role", Code: "unknown_role"}}}, http.StatusBadRequest},
+		{"own role", "admin", `{"role":"user"}`, models.ErrCannotChangeOwnRole, http.StatusConflict},
+		{"no user", "ghost", `{"role":"user"}`, models.ErrUserNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRoleService)
+			handler := handlers.NewRoleHandler(mockService, logger)
+			mockService.On("SetRole", "admin", tt.username, mock.Anything).Return(tt.err)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/api/admin/users/"+tt.username+"/role", bytes.NewBufferString(tt.body))
+			c.Params = gin.Params{{Key: "username", Value: tt.username}}
+			c.Set("username", "admin")
+
+			handler.SetUserRole(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"ShopAvito/internal/handlers"
+	"ShopAvito/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) List(username, current string) ([]models.Session, error) {
+	args := m.Called(username, current)
+	sessions, _ := args.Get(0).([]models.Session)
+	return sessions, args.Error(1)
+}
+
+func (m *MockSessionService) Revoke(username string, id int) error {
+	args := m.Called(username, id)
+	return args.Error(0)
+}
+
+func newSessionRouter(sessionService *MockSessionService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	handler := handlers.NewSessionHandler(sessionService, logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Set("session", "family-1")
+		c.Next()
+	})
+	router.GET("/api/sessions", handler.ListSessions)
+	router.DELETE("/api/sessions/:id", handler.RevokeSession)
+	return router
+}
+
+func TestListSessions(t *testing.T) {
+	mockService := new(MockSessionService)
+	mockService.On("List", "alice", "family-1").Return([]models.Session{
+		{ID: 1, FamilyID: "family-1", UserAgent: "Firefox", IP: "10.0.0.1", Current: true},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	newSessionRouter(mockService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_agent":"Firefox"`)
+	assert.Contains(t, w.Body.String(), `"current":true`)
+	assert.NotContains(t, w.Body.String(), "family-1")
+	mockService.AssertExpectations(t)
+}
+
+func TestRevokeSession(t *testing.T) {
+	mockService := new(MockSessionService)
+	mockService.On("Revoke", "alice", 1).Return(nil)
+	mockService.On("Revoke", "alice", 2).Return(models.ErrSessionNotFound)
+	router := newSessionRouter(mockService)
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/sessions/1", http.StatusOK},
+		{"/api/sessions/2", http.StatusNotFound},
+		{"/api/sessions/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+		assert.Equal(t, tt.expected, w.Code, tt.path)
+	}
+	mockService.AssertExpectations(t)
+}
@@ -43,7 +43,7 @@ func TestSendCoins_Success(t *testing.T) {
 	mockLogger.SetOutput(io.Discard)
 
 	mockService := new(MockTransactionService)
-	handler := handlers.NewTransactionHandler(mockService, mockLogger)
+	handler := handlers.NewTransactionHandler(mockService, nil, mockLogger)
 
 	router := gin.Default()
 	router.POST("/send", handler.SendCoins)
@@ -76,7 +76,7 @@ func TestSendCoins_Error(t *testing.T) {
 	mockLogger.SetOutput(io.Discard)
 
 	mockService := new(MockTransactionService)
-	handler := handlers.NewTransactionHandler(mockService, mockLogger)
+	handler := handlers.NewTransactionHandler(mockService, nil, mockLogger)
 
 	router := gin.Default()
 	router.POST("/send", handler.SendCoins)
@@ -101,3 +101,94 @@ func TestSendCoins_Error(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, w.Code)
 	mockService.AssertExpectations(t)
 }
+
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Enroll(username string) (*models.TwoFactorEnrollment, error) {
+	args := m.Called(username)
+	enrollment, _ := args.Get(0).(*models.TwoFactorEnrollment)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockTwoFactorService) Confirm(username, code string) ([]string, error) {
+	args := m.Called(username, code)
+	codes, _ := args.Get(0).([]string)
+	return codes, args.Error(1)
+}
+
+func (m *MockTwoFactorService) Disable(username, code string) error {
+	args := m.Called(username, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) AuthorizeTransfer(username string, amount int, code string) error {
+	args := m.Called(username, amount, code)
+	return args.Error(0)
+}
+
+// **Тестируем перевод без кода двухфакторной аутентификации**
+func TestSendCoins_TwoFactorRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLogger := logrus.New()
+	mockLogger.SetOutput(io.Discard)
+
+	mockService := new(MockTransactionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := handlers.NewTransactionHandler(mockService, mockTwoFactor, mockLogger)
+
+	requestBody, _ := json.Marshal(models.SendCoinRequest{
+		ToUser: "receiver",
+		Amount: 1000,
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/send", bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	mockTwoFactor.On("AuthorizeTransfer", "testuser", 1000, "").Return(models.ErrTwoFactorRequired)
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = req
+	ctx.Set("username", "testuser")
+
+	handler.SendCoins(ctx)
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "TransferCoins", mock.Anything, mock.Anything, mock.Anything)
+	mockTwoFactor.AssertExpectations(t)
+}
+
+// **Тестируем перевод неположительной суммы**
+func TestSendCoins_NonPositiveAmount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLogger := logrus.New()
+	mockLogger.SetOutput(io.Discard)
+
+	mockService := new(MockTransactionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := handlers.NewTransactionHandler(mockService, mockTwoFactor, mockLogger)
+
+	// Отрицательная сумма не должна обходить двухфакторную проверку и списывать монеты у получателя
+	for _, amount := range []int{-500, 0} {
+		requestBody, _ := json.Marshal(models.SendCoinRequest{
+			ToUser: "receiver",
+			Amount: amount,
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "/send", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = req
+		ctx.Set("username", "testuser")
+
+		handler.SendCoins(ctx)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	mockTwoFactor.AssertNotCalled(t, "AuthorizeTransfer", mock.Anything, mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "TransferCoins", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"testing"
+	"time"
+)
+
+// StubAccountRepository запоминает удалённые аккаунты
+type StubAccountRepository struct {
+	Export    *models.AccountExport
+	Avatars   map[string]string
+	DeleteErr error
+	Deleted   []string
+}
+
+func (s *StubAccountRepository) ExportAccount(username string) (*models.AccountExport, error) {
+	if s.Export == nil {
+		return nil, models.ErrUserNotFound
+	}
+	return s.Export, nil
+}
+
+func (s *StubAccountRepository) DeleteAccount(username string, now time.Time) (string, error) {
+	if s.DeleteErr != nil {
+		return "", s.DeleteErr
+	}
+	s.Deleted = append(s.Deleted, username)
+	return s.Avatars[username], nil
+}
+
+func newAccountService(accountRepo *StubAccountRepository, users map[string]string, revocations *services.RevocationStore, avatars *MemoryBlobStore) *services.AccountService {
+	userRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			password, ok := users[username]
+			if !ok {
+				return nil, models.ErrUserNotFound
+			}
+			return &models.User{Username: username, Password: password}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return services.NewAccountService(accountRepo, userRepo, revocations, avatars, logger)
+}
+
+func TestAccountService_Export(t *testing.T) {
+	accountRepo := &StubAccountRepository{Export: &models.AccountExport{
+		Profile: models.AccountProfile{
+			UserProfile: models.UserProfile{Username: "alice", DisplayName: "Alice Smith", AvatarKey: "avatars/alice.png"},
+			Role:        services.RoleUser,
+			Coins:       900,
+		},
+		CoinHistory: []models.Transaction{{FromUser: "alice", ToUser: "bob", Amount: 100}},
+	}}
+	service := newAccountService(accountRepo, nil, nil, nil)
+
+	export, err := service.Export("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", export.Profile.Username)
+	assert.Equal(t, "Alice Smith", export.Profile.DisplayName)
+	assert.Equal(t, models.ImagesPath+"avatars/alice.png", export.Profile.AvatarURL)
+	assert.Len(t, export.CoinHistory, 1)
+	assert.False(t, export.ExportedAt.IsZero())
+
+	_, err = newAccountService(&StubAccountRepository{}, nil, nil, nil).Export("ghost")
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+}
+
+func TestAccountService_Delete(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	users := map[string]string{"alice": string(hashedPassword), "sso-user": ""}
+
+	revocationRepo := NewMemoryTokenRevocationRepository("alice")
+	revocations := services.NewRevocationStore(revocationRepo)
+	accountRepo := &StubAccountRepository{Avatars: map[string]string{"alice": "avatars/alice.png"}}
+	avatars := &MemoryBlobStore{Blobs: map[string][]byte{"avatars/alice.png": {1}}}
+	service := newAccountService(accountRepo, users, revocations, avatars)
+
+	assert.ErrorIs(t, service.Delete("alice", "wrong"), models.ErrInvalidPassword)
+	assert.ErrorIs(t, service.Delete("ghost", "password"), models.ErrUserNotFound)
+	assert.Empty(t, accountRepo.Deleted)
+
+	// Проверка токена до удаления кэширует границу отзыва; удаление сбрасывает кэш
+	revoked, err := revocations.IsRevoked("jti-1", "alice", 1, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, service.Delete("alice", "password"))
+	assert.Empty(t, avatars.Blobs)
+	delete(revocationRepo.Users, "alice")
+	revoked, err = revocations.IsRevoked("jti-1", "alice", 1, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Пользователю без пароля подтверждение не нужно
+	assert.NoError(t, service.Delete("sso-user", ""))
+	assert.Equal(t, []string{"alice", "sso-user"}, accountRepo.Deleted)
+
+	accountRepo.DeleteErr = models.ErrAccountHasPendingOperations
+	users["bob"] = string(hashedPassword)
+	assert.ErrorIs(t, service.Delete("bob", "password"), models.ErrAccountHasPendingOperations)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MemoryAPITokenRepository хранит API-токены в памяти по хэшу
+type MemoryAPITokenRepository struct {
+	Tokens  map[string]*models.APIToken
+	Touches int
+	nextID  int
+}
+
+func NewMemoryAPITokenRepository() *MemoryAPITokenRepository {
+	return &MemoryAPITokenRepository{Tokens: map[string]*models.APIToken{}}
+}
+
+func (m *MemoryAPITokenRepository) CreateAPIToken(token models.APIToken, tokenHash string) (*models.APIToken, error) {
+	for _, existing := range m.Tokens {
+		if existing.Username == token.Username && existing.Name == token.Name {
+			return nil, models.ErrAPITokenNameTaken
+		}
+	}
+	m.nextID++
+	token.ID, token.CreatedAt = m.nextID, time.Now()
+	m.Tokens[tokenHash] = &token
+	return &token, nil
+}
+
+func (m *MemoryAPITokenRepository) ListAPITokens(username string) ([]models.APIToken, error) {
+	tokens := []models.APIToken{}
+	for _, token := range m.Tokens {
+		if token.Username == username {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *MemoryAPITokenRepository) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	token, ok := m.Tokens[tokenHash]
+	if !ok {
+		return nil, models.ErrInvalidAPIToken
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *MemoryAPITokenRepository) TouchAPIToken(id int, at time.Time) error {
+	m.Touches++
+	for _, token := range m.Tokens {
+		if token.ID == id {
+			token.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *MemoryAPITokenRepository) DeleteAPIToken(username string, id int) error {
+	for hash, token := range m.Tokens {
+		if token.ID == id && token.Username == username {
+			delete(m.Tokens, hash)
+			return nil
+		}
+	}
+	return models.ErrAPITokenNotFound
+}
+
+func newTestAPITokenService() (*services.APITokenService, *MemoryAPITokenRepository) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo := NewMemoryAPITokenRepository()
+	return services.NewAPITokenService(repo, nil, logger), repo
+}
+
+func TestAPITokenService_Create(t *testing.T) {
+	apiTokenService, repo := newTestAPITokenService()
+
+	_, err := apiTokenService.Create("alice", models.CreateAPITokenRequest{Name: " ", Scopes: []string{"coins:steal"}, ExpiresInDays: 1000})
+	var verr *models.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	var fields []string
+	for _, field := range verr.Fields {
+		fields = append(fields, field.Field+":"+field.Code)
+	}
+	assert.Equal(t, []string{"name:required", "scopes:unknown_scope", "expires_in_days:out_of_range"}, fields)
+
+	created, err := apiTokenService.Create("alice", models.CreateAPITokenRequest{
+		Name:   "slack-bot",
+		Scopes: []string{services.ScopeTransferSend, services.ScopeInfoRead, services.ScopeTransferSend},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Token, services.APITokenPrefix))
+	assert.Equal(t, []string{services.ScopeInfoRead, services.ScopeTransferSend}, created.Scopes)
+	assert.WithinDuration(t, time.Now().Add(services.APITokenDefaultTTLDays*24*time.Hour), created.ExpiresAt, time.Minute)
+
+	// Хранится только хэш
+	for hash := range repo.Tokens {
+		assert.NotContains(t, hash, created.Token)
+	}
+
+	_, err = apiTokenService.Create("alice", models.CreateAPITokenRequest{Name: "slack-bot", Scopes: []string{services.ScopeInfoRead}})
+	assert.ErrorIs(t, err, models.ErrAPITokenNameTaken)
+}
+
+func TestAPITokenService_Authenticate(t *testing.T) {
+	apiTokenService, repo := newTestAPITokenService()
+	created, err := apiTokenService.Create("alice", models.CreateAPITokenRequest{Name: "bot", Scopes: []string{services.ScopeInfoRead}, ExpiresInDays: 7})
+	assert.NoError(t, err)
+
+	token, err := apiTokenService.Authenticate(created.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", token.Username)
+	assert.Equal(t, []string{services.ScopeInfoRead}, token.Scopes)
+
+	// Время использования записывается не на каждый запрос
+	_, err = apiTokenService.Authenticate(created.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.Touches)
+	tokens, _ := apiTokenService.List("alice")
+	assert.NotNil(t, tokens[0].LastUsedAt)
+
+	_, err = apiTokenService.Authenticate(created.Token + "x")
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+	_, err = apiTokenService.Authenticate("not-an-api-token")
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+
+	// Истёкший токен не принимается
+	for _, stored := range repo.Tokens {
+		stored.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	_, err = apiTokenService.Authenticate(created.Token)
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+
+	// Отозвать можно только свой токен
+	assert.ErrorIs(t, apiTokenService.Revoke("bob", created.ID), models.ErrAPITokenNotFound)
+	assert.NoError(t, apiTokenService.Revoke("alice", created.ID))
+	_, err = apiTokenService.Authenticate(created.Token)
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+}
+
+func TestAPITokenService_RevokedByUserCutoff(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	revocations := services.NewRevocationStore(NewMemoryTokenRevocationRepository("alice"))
+	apiTokenService := services.NewAPITokenService(NewMemoryAPITokenRepository(), revocations, logger)
+
+	leaked, err := apiTokenService.Create("alice", models.CreateAPITokenRequest{Name: "bot", Scopes: []string{services.ScopeInfoRead}})
+	assert.NoError(t, err)
+	_, err = apiTokenService.Authenticate(leaked.Token)
+	assert.NoError(t, err)
+
+	// Отзыв всех токенов (потерянный ноутбук, смена или сброс пароля) затрагивает и API-токены, созданные раньше
+	assert.NoError(t, revocations.RevokeUserTokens("alice", time.Now().Add(time.Second)))
+	_, err = apiTokenService.Authenticate(leaked.Token)
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+
+	// Токены удалённого пользователя тоже не принимаются
+	apiTokenService = services.NewAPITokenService(NewMemoryAPITokenRepository(), services.NewRevocationStore(NewMemoryTokenRevocationRepository()), logger)
+	orphan, err := apiTokenService.Create("bob", models.CreateAPITokenRequest{Name: "bot", Scopes: []string{services.ScopeInfoRead}})
+	assert.NoError(t, err)
+	_, err = apiTokenService.Authenticate(orphan.Token)
+	assert.ErrorIs(t, err, models.ErrInvalidAPIToken)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type StubAuctionRepository struct {
+	CreateAuctionFunc    func(auction models.Auction) (int, error)
+	GetAuctionFunc       func(id int) (*models.Auction, error)
+	ListOpenAuctionsFunc func() ([]models.Auction, error)
+	GetBidsFunc          func(auctionID int) ([]models.AuctionBid, error)
+	PlaceBidFunc         func(auctionID int, username string, amount int, now time.Time) error
+	GetDueAuctionIDsFunc func(now time.Time) ([]int, error)
+	CloseAuctionFunc     func(auctionID int) error
+}
+
+func (s *StubAuctionRepository) CreateAuction(auction models.Auction) (int, error) {
+	return s.CreateAuctionFunc(auction)
+}
+
+func (s *StubAuctionRepository) GetAuction(id int) (*models.Auction, error) {
+	return s.GetAuctionFunc(id)
+}
+
+func (s *StubAuctionRepository) ListOpenAuctions() ([]models.Auction, error) {
+	return s.ListOpenAuctionsFunc()
+}
+
+func (s *StubAuctionRepository) GetBids(auctionID int) ([]models.AuctionBid, error) {
+	return s.GetBidsFunc(auctionID)
+}
+
+func (s *StubAuctionRepository) PlaceBid(auctionID int, username string, amount int, now time.Time) error {
+	return s.PlaceBidFunc(auctionID, username, amount, now)
+}
+
+func (s *StubAuctionRepository) GetDueAuctionIDs(now time.Time) ([]int, error) {
+	return s.GetDueAuctionIDsFunc(now)
+}
+
+func (s *StubAuctionRepository) CloseAuction(auctionID int) error {
+	return s.CloseAuctionFunc(auctionID)
+}
+
+func TestAuctionService_CreateAuction(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	stubRepo := &StubAuctionRepository{
+		CreateAuctionFunc: func(auction models.Auction) (int, error) {
+			return 7, nil
+		},
+	}
+	auctionService := services.NewAuctionService(stubRepo, logger)
+
+	now := time.Now()
+
+	// Успешное создание
+	auction, err := auctionService.CreateAuction(models.CreateAuctionRequest{
+		ItemName:     " pink-hoody ",
+		ReservePrice: 600,
+		StartsAt:     now,
+		EndsAt:       now.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, auction.ID)
+	assert.Equal(t, "pink-hoody", auction.ItemName)
+	assert.Equal(t, models.AuctionStatusOpen, auction.Status)
+
+	// Конец раньше начала
+	_, err = auctionService.CreateAuction(models.CreateAuctionRequest{
+		ItemName: "pink-hoody",
+		StartsAt: now.Add(time.Hour),
+		EndsAt:   now,
+	})
+	assert.Error(t, err)
+
+	// Аукцион уже закончился
+	_, err = auctionService.CreateAuction(models.CreateAuctionRequest{
+		ItemName: "pink-hoody",
+		StartsAt: now.Add(-2 * time.Hour),
+		EndsAt:   now.Add(-time.Hour),
+	})
+	assert.Error(t, err)
+
+	// Отрицательная резервная цена
+	_, err = auctionService.CreateAuction(models.CreateAuctionRequest{
+		ItemName:     "pink-hoody",
+		ReservePrice: -1,
+		StartsAt:     now,
+		EndsAt:       now.Add(time.Hour),
+	})
+	assert.Error(t, err)
+}
+
+func TestAuctionService_PlaceBid(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	stubRepo := &StubAuctionRepository{
+		PlaceBidFunc: func(auctionID int, username string, amount int, now time.Time) error {
+			if amount <= 500 {
+				return models.ErrBidTooLow
+			}
+			return nil
+		},
+	}
+	auctionService := services.NewAuctionService(stubRepo, logger)
+
+	assert.NoError(t, auctionService.PlaceBid(1, "bidder", 600))
+	assert.ErrorIs(t, auctionService.PlaceBid(1, "bidder", 400), models.ErrBidTooLow)
+	assert.Error(t, auctionService.PlaceBid(1, "bidder", 0))
+}
+
+func TestAuctionService_CloseDueAuctions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var closed []int
+	stubRepo := &StubAuctionRepository{
+		GetDueAuctionIDsFunc: func(now time.Time) ([]int, error) {
+			return []int{1, 2, 3}, nil
+		},
+		CloseAuctionFunc: func(auctionID int) error {
+			if auctionID == 2 {
+				return errors.New("db error")
+			}
+			closed = append(closed, auctionID)
+			return nil
+		},
+	}
+	auctionService := services.NewAuctionService(stubRepo, logger)
+
+	// Ошибка одного аукциона не мешает закрыть остальные
+	err := auctionService.CloseDueAuctions()
+	assert.Error(t, err)
+	assert.Equal(t, []int{1, 3}, closed)
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"io"
 	"testing"
+	"time"
 )
 
 type StubUserRepository struct {
@@ -16,9 +18,16 @@ type StubUserRepository struct {
 	CreateUserFunc        func(user models.User) error
 	UpdateUserBalanceFunc func(username string, newBalance int) error
 	UserExistsFunc        func(username string) (bool, error)
+	GetUserRoleFunc       func(username string) (string, error)
+	SetUserRoleFunc       func(username, role string) error
+	UpdatePasswordFunc    func(username, passwordHash string) error
 }
 
+// Без GetUserByUsernameFunc любой пользователь существует
 func (s *StubUserRepository) GetUserByUsername(username string) (*models.User, error) {
+	if s.GetUserByUsernameFunc == nil {
+		return &models.User{ID: 1, Username: username}, nil
+	}
 	return s.GetUserByUsernameFunc(username)
 }
 
@@ -38,6 +47,97 @@ func (s *StubUserRepository) UserExists(username string) (bool, error) {
 	return s.UserExistsFunc(username)
 }
 
+// Без GetUserRoleFunc у всех пользователей роль user
+func (s *StubUserRepository) GetUserRole(username string) (string, error) {
+	if s.GetUserRoleFunc == nil {
+		return services.RoleUser, nil
+	}
+	return s.GetUserRoleFunc(username)
+}
+
+func (s *StubUserRepository) SetUserRole(username, role string) error {
+	return s.SetUserRoleFunc(username, role)
+}
+
+func (s *StubUserRepository) UpdatePassword(username, passwordHash string) error {
+	if s.UpdatePasswordFunc == nil {
+		return nil
+	}
+	return s.UpdatePasswordFunc(username, passwordHash)
+}
+
+// MemoryRefreshTokenRepository хранит refresh-токены в памяти по их хэшу, а сеансы - по семейству
+type MemoryRefreshTokenRepository struct {
+	Tokens   map[string]*models.RefreshToken
+	Sessions map[string]*models.Session
+}
+
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{Tokens: map[string]*models.RefreshToken{}, Sessions: map[string]*models.Session{}}
+}
+
+func (m *MemoryRefreshTokenRepository) CreateSession(session models.Session, token models.RefreshToken) error {
+	session.ID = len(m.Sessions) + 1
+	session.CreatedAt, session.LastUsedAt = time.Now(), time.Now()
+	m.Sessions[session.FamilyID] = &session
+	m.Tokens[token.TokenHash] = &token
+	return nil
+}
+
+func (m *MemoryRefreshTokenRepository) RotateRefreshToken(tokenHash string, next models.RefreshToken, now time.Time) (*models.RefreshToken, error) {
+	current, ok := m.Tokens[tokenHash]
+	if !ok || current.RevokedAt != nil || !current.ExpiresAt.After(now) {
+		return nil, models.ErrInvalidRefreshToken
+	}
+	if current.UsedAt != nil {
+		return nil, models.ErrRefreshTokenReused
+	}
+	current.UsedAt = &now
+	next.Username, next.FamilyID = current.Username, current.FamilyID
+	m.Tokens[next.TokenHash] = &next
+	if session, ok := m.Sessions[current.FamilyID]; ok {
+		session.LastUsedAt = now
+	}
+	copied := *current
+	return &copied, nil
+}
+
+func (m *MemoryRefreshTokenRepository) RevokeRefreshTokenFamily(tokenHash string) error {
+	current, ok := m.Tokens[tokenHash]
+	if !ok {
+		return models.ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	revoked := 0
+	for _, token := range m.Tokens {
+		if token.FamilyID == current.FamilyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			revoked++
+		}
+	}
+	if revoked == 0 {
+		return models.ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+func (m *MemoryRefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	var deleted int64
+	for hash, token := range m.Tokens {
+		if !token.ExpiresAt.After(now) {
+			delete(m.Tokens, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Набор из одного ключа HS256, как при конфигурации только с JWT_SECRET
+func newTestKeySet(secret string) *services.KeySet {
+	keys, _ := services.NewKeySet(services.NewHMACKey("", secret), time.Minute)
+	return keys
+}
+
 func TestAuthService_Login(t *testing.T) {
 	// Хешируем пароль для теста
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
@@ -57,24 +157,62 @@ func TestAuthService_Login(t *testing.T) {
 	}
 
 	logger := logrus.New() // Инициализируем логгер
-	authService := services.NewAuthService(stubUserRepo, "secret", logger)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
 
 	// Тест на успешный логин
-	token, err := authService.Login("testuser", "password")
+	tokens, err := authService.Login("testuser", "password", models.ClientInfo{})
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.Token)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, int(services.AccessTokenTTL.Seconds()), tokens.ExpiresIn)
 
 	// Тест на неверный пароль
-	_, err = authService.Login("testuser", "wrongpassword")
+	_, err = authService.Login("testuser", "wrongpassword", models.ClientInfo{})
 	assert.Error(t, err)
 	assert.Equal(t, "invalid password", err.Error())
 
 	// Тест на несуществующего пользователя
-	_, err = authService.Login("nonexistent", "password")
+	_, err = authService.Login("nonexistent", "password", models.ClientInfo{})
 	assert.Error(t, err)
 	assert.Equal(t, "user not found", err.Error())
 }
 
+func TestAuthService_LoginUnknownUserComparesDummyHash(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			if username == "testuser" {
+				return &models.User{Username: "testuser", Password: string(hashedPassword)}, nil
+			}
+			return nil, models.ErrUserNotFound
+		},
+	}
+	var compared []string
+	policy := services.PasswordPolicy{
+		Cost: bcrypt.MinCost,
+		Compare: func(hashed, password []byte) error {
+			compared = append(compared, string(hashed))
+			return bcrypt.CompareHashAndPassword(hashed, password)
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), policy, nil, nil, logger)
+
+	_, err = authService.Login("testuser", "wrongpassword", models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrInvalidPassword)
+	assert.Equal(t, []string{string(hashedPassword)}, compared)
+
+	// Несуществующее имя тоже проходит сравнение с bcrypt-хэшем, поэтому отвечает так же долго, как неверный пароль
+	_, err = authService.Login("nonexistent", "password", models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+	assert.Len(t, compared, 2)
+	cost, err := bcrypt.Cost([]byte(compared[1]))
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+}
+
 func TestAuthService_Register(t *testing.T) {
 	// Создаем заглушку для UserRepository
 	stubUserRepo := &StubUserRepository{
@@ -90,15 +228,173 @@ func TestAuthService_Register(t *testing.T) {
 	}
 
 	logger := logrus.New() // Инициализируем логгер
-	authService := services.NewAuthService(stubUserRepo, "secret", logger)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
 
 	// Тест на успешную регистрацию
-	token, err := authService.Register("newuser", "password")
+	tokens, err := authService.Register("newuser", "password", models.ClientInfo{})
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.Token)
 
 	// Тест на уже существующего пользователя
-	_, err = authService.Register("existinguser", "password")
+	_, err = authService.Register("existinguser", "password", models.ClientInfo{})
 	assert.Error(t, err)
 	assert.Equal(t, "user already exists", err.Error())
 }
+
+func TestAuthService_Refresh(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+
+	login, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+
+	// Обмен выдаёт новую пару, refresh-токен меняется
+	refreshed, err := authService.Refresh(login.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	claims, err := authService.ValidateToken(refreshed.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Username)
+
+	// Повторное использование старого токена отзывает всё семейство, включая новый токен
+	_, err = authService.Refresh(login.RefreshToken)
+	assert.ErrorIs(t, err, models.ErrRefreshTokenReused)
+	_, err = authService.Refresh(refreshed.RefreshToken)
+	assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+
+	_, err = authService.Refresh("unknown")
+	assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+
+	first, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	second, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, authService.Logout(first.RefreshToken, ""))
+	_, err = authService.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+	assert.ErrorIs(t, authService.Logout(first.RefreshToken, ""), models.ErrInvalidRefreshToken)
+
+	// Другие входы пользователя не затрагиваются
+	_, err = authService.Refresh(second.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_LogoutRevokesAccessToken(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	revocations := services.NewRevocationStore(NewMemoryTokenRevocationRepository("testuser"))
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), revocations, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+
+	tokens, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	claims, err := authService.ValidateToken(tokens.Token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.Id)
+
+	revoked, err := authService.IsTokenRevoked(claims)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, authService.Logout(tokens.RefreshToken, tokens.Token))
+	revoked, err = authService.IsTokenRevoked(claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestAuthService_RevokeUserTokensSameSecond(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{ID: 1, Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	revocations := services.NewRevocationStore(NewMemoryTokenRevocationRepository("testuser"))
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), revocations, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+
+	// Вход и отзыв приходятся на начало одной секунды
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	stolen, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	cutoff, err := authService.RevokeUserTokens("testuser", nil)
+	assert.NoError(t, err)
+
+	// Токен, выданный перед отзывом в ту же секунду, отозван: граница не округляется до секунды
+	claims, err := authService.ValidateToken(stolen.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, cutoff.Unix(), claims.IssuedAt)
+	revoked, err := authService.IsTokenRevoked(claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Токен, выданный после отзыва, действует
+	tokens, err := authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	claims, err = authService.ValidateToken(tokens.Token)
+	assert.NoError(t, err)
+	revoked, err = authService.IsTokenRevoked(claims)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestAuthService_TokenScopesByRole(t *testing.T) {
+	roles := map[string]string{"alice": services.RoleUser, "mod": services.RoleModerator}
+	stubUserRepo := &StubUserRepository{
+		GetUserRoleFunc: func(username string) (string, error) {
+			return roles[username], nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+
+	token, err := authService.GenerateToken("alice")
+	assert.NoError(t, err)
+	claims, err := authService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, services.RoleScopes(services.RoleUser), claims.Scopes)
+
+	// Новая роль попадает в следующий выданный токен
+	roles["alice"] = services.RoleModerator
+	token, _ = authService.GenerateToken("alice")
+	claims, _ = authService.ValidateToken(token)
+	assert.Contains(t, claims.Scopes, services.ScopeAdminReviews)
+	assert.NotContains(t, claims.Scopes, services.ScopeAdminUsers)
+
+	stubUserRepo.GetUserRoleFunc = func(username string) (string, error) {
+		return "", models.ErrUserNotFound
+	}
+	_, err = authService.GenerateToken("ghost")
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+}
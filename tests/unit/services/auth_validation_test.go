@@ -0,0 +1,76 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func fieldCodes(errs []models.FieldError) []string {
+	codes := make([]string, 0, len(errs))
+	for _, err := range errs {
+		codes = append(codes, err.Code)
+	}
+	return codes
+}
+
+func TestValidateUsername(t *testing.T) {
+	assert.Empty(t, services.ValidateUsername("alice"))
+	assert.Empty(t, services.ValidateUsername("alice.smith-99_x"))
+
+	assert.Equal(t, []string{"required"}, fieldCodes(services.ValidateUsername("")))
+	assert.Equal(t, []string{"too_short"}, fieldCodes(services.ValidateUsername("al")))
+	assert.Equal(t, []string{"too_long"}, fieldCodes(services.ValidateUsername(strings.Repeat("a", 33))))
+	assert.Equal(t, []string{"invalid_characters"}, fieldCodes(services.ValidateUsername("   ")))
+	assert.Equal(t, []string{"invalid_characters"}, fieldCodes(services.ValidateUsername("alice smith")))
+	assert.Equal(t, []string{"invalid_characters"}, fieldCodes(services.ValidateUsername(".alice")))
+	assert.Equal(t, []string{"invalid_characters"}, fieldCodes(services.ValidateUsername("алиса")))
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	// Политика по умолчанию: только длина
+	policy := services.PasswordPolicy{}
+	assert.Empty(t, policy.Validate("password", "alice"))
+	assert.Equal(t, []string{"required"}, fieldCodes(policy.Validate("", "alice")))
+	assert.Equal(t, []string{"too_short"}, fieldCodes(policy.Validate("x", "alice")))
+	assert.Equal(t, []string{"too_long"}, fieldCodes(policy.Validate(strings.Repeat("a", 73), "alice")))
+	assert.Equal(t, []string{"contains_username"}, fieldCodes(policy.Validate("my-Alice-pass", "alice")))
+
+	strict := services.PasswordPolicy{MinLength: 12, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+	assert.Empty(t, strict.Validate("Correct-horse-1", "alice"))
+	assert.Equal(t,
+		[]string{"too_short", "missing_uppercase", "missing_digit", "missing_symbol"},
+		fieldCodes(strict.Validate("short", "alice")))
+}
+
+func TestAuthService_RegisterValidation(t *testing.T) {
+	created := false
+	stubUserRepo := &StubUserRepository{
+		UserExistsFunc: func(username string) (bool, error) { return false, nil },
+		CreateUserFunc: func(user models.User) error {
+			created = true
+			return nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{RequireDigit: true}, nil, nil, logger)
+
+	_, err := authService.Register("a", "password", models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrValidation)
+	var verr *models.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []string{"too_short", "missing_digit"}, fieldCodes(verr.Fields))
+	assert.False(t, created)
+
+	// Вход не проверяет правила регистрации, только пустые и слишком длинные значения
+	_, err = authService.Login("", strings.Repeat("a", 2000), models.ClientInfo{})
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []string{"required", "too_long"}, fieldCodes(verr.Fields))
+}
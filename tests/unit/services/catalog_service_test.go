@@ -0,0 +1,317 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type StubCatalogRepository struct {
+	SearchItemsFunc    func(query models.CatalogQuery) ([]models.CatalogItem, int, error)
+	ListCategoriesFunc func() ([]models.Category, error)
+	GetItemFunc        func(name string) (*models.CatalogItem, error)
+	UpsertVariantFunc  func(itemName string, variant models.CatalogVariant) error
+	CreateBundleFunc   func(bundle models.CatalogItem) error
+	SetItemImageFunc   func(itemName, imageKey, thumbKey string) error
+	PriceChanges       []models.PriceChange
+}
+
+func (s *StubCatalogRepository) SearchItems(query models.CatalogQuery) ([]models.CatalogItem, int, error) {
+	return s.SearchItemsFunc(query)
+}
+
+func (s *StubCatalogRepository) ListCategories() ([]models.Category, error) {
+	return s.ListCategoriesFunc()
+}
+
+func (s *StubCatalogRepository) GetItem(name string) (*models.CatalogItem, error) {
+	return s.GetItemFunc(name)
+}
+
+func (s *StubCatalogRepository) UpsertVariant(itemName string, variant models.CatalogVariant) error {
+	return s.UpsertVariantFunc(itemName, variant)
+}
+
+func (s *StubCatalogRepository) CreateBundle(bundle models.CatalogItem) error {
+	return s.CreateBundleFunc(bundle)
+}
+
+func (s *StubCatalogRepository) SetItemImage(itemName, imageKey, thumbKey string) error {
+	return s.SetItemImageFunc(itemName, imageKey, thumbKey)
+}
+
+func (s *StubCatalogRepository) AddPriceChange(change models.PriceChange) (int, error) {
+	change.ID = len(s.PriceChanges) + 1
+	s.PriceChanges = append(s.PriceChanges, change)
+	return change.ID, nil
+}
+
+func (s *StubCatalogRepository) GetPriceHistory(itemName string) ([]models.PriceChange, error) {
+	return s.PriceChanges, nil
+}
+
+func (s *StubCatalogRepository) DeletePendingPriceChange(id int) error {
+	for i, change := range s.PriceChanges {
+		if change.ID == id && !change.Applied {
+			s.PriceChanges = append(s.PriceChanges[:i], s.PriceChanges[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrPriceChangeNotFound
+}
+
+func (s *StubCatalogRepository) ApplyDuePriceChanges(now time.Time) ([]models.PriceChange, error) {
+	var applied []models.PriceChange
+	for i, change := range s.PriceChanges {
+		if !change.Applied && !change.EffectiveAt.After(now) {
+			s.PriceChanges[i].Applied = true
+			applied = append(applied, s.PriceChanges[i])
+		}
+	}
+	return applied, nil
+}
+
+func TestVariantKey(t *testing.T) {
+	// Товар без осей
+	key, err := services.VariantKey(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultVariant, key)
+
+	_, err = services.VariantKey(nil, map[string]string{"size": "M"})
+	assert.ErrorIs(t, err, models.ErrInvalidVariant)
+
+	// Порядок в ключе совпадает с порядком осей товара
+	key, err = services.VariantKey([]string{"size", "colour"}, map[string]string{"colour": "black", "size": "M"})
+	assert.NoError(t, err)
+	assert.Equal(t, "size=M,colour=black", key)
+
+	// Не указана одна из осей
+	_, err = services.VariantKey([]string{"size", "colour"}, map[string]string{"size": "M"})
+	assert.ErrorIs(t, err, models.ErrInvalidVariant)
+
+	// Лишняя ось вместо нужной
+	_, err = services.VariantKey([]string{"size"}, map[string]string{"colour": "black"})
+	assert.ErrorIs(t, err, models.ErrInvalidVariant)
+}
+
+// RecordingNotifier запоминает варианты, о поступлении которых сообщили
+type RecordingNotifier struct {
+	Restocked []string
+}
+
+func (n *RecordingNotifier) ItemDiscounted(campaign models.Campaign) {}
+
+func (n *RecordingNotifier) ItemRestocked(itemName, variant string) {
+	n.Restocked = append(n.Restocked, itemName+" "+variant)
+}
+
+func (n *RecordingNotifier) CoinsReceived(username string, amount int) {}
+
+func TestCatalogService_UpsertVariant(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	soldOut := 0
+	var saved models.CatalogVariant
+	stubRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name == "hoody" {
+				return &models.CatalogItem{Name: "hoody", Price: 300, VariantAxes: []string{"size"},
+					Variants: []models.CatalogVariant{{Key: "size=M", Stock: &soldOut}}}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+		UpsertVariantFunc: func(itemName string, variant models.CatalogVariant) error {
+			saved = variant
+			return nil
+		},
+	}
+	notifier := &RecordingNotifier{}
+	catalogService := services.NewCatalogService(stubRepo, nil, notifier, logger)
+
+	stock := 5
+	variant, err := catalogService.UpsertVariant("hoody", models.UpsertVariantRequest{
+		Attributes: map[string]string{"size": "XXL"},
+		Stock:      &stock,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "size=XXL", variant.Key)
+	assert.Equal(t, 5, *saved.Stock)
+	assert.Empty(t, notifier.Restocked)
+
+	// Закончившийся размер снова в наличии
+	_, err = catalogService.UpsertVariant("hoody", models.UpsertVariantRequest{
+		Attributes: map[string]string{"size": "M"},
+		Stock:      &stock,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hoody size=M"}, notifier.Restocked)
+
+	negative := -1
+	_, err = catalogService.UpsertVariant("hoody", models.UpsertVariantRequest{
+		Attributes: map[string]string{"size": "S"},
+		Stock:      &negative,
+	})
+	assert.Error(t, err)
+
+	_, err = catalogService.UpsertVariant("unknown", models.UpsertVariantRequest{})
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
+
+func TestCatalogService_CreateBundle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	items := map[string]*models.CatalogItem{
+		"t-shirt": {Name: "t-shirt", Price: 80, VariantAxes: []string{"size"},
+			Variants: []models.CatalogVariant{{Key: "size=S"}, {Key: "size=M"}, {Key: "size=L"}}},
+		"hoody": {Name: "hoody", Price: 300, VariantAxes: []string{"size"},
+			Variants: []models.CatalogVariant{{Key: "size=M"}, {Key: "size=L"}, {Key: "size=XL"}}},
+		"socks": {Name: "socks", Price: 10, VariantAxes: []string{"colour"},
+			Variants: []models.CatalogVariant{{Key: "colour=black"}}},
+		"cup": {Name: "cup", Price: 20, Variants: []models.CatalogVariant{{Key: models.DefaultVariant}}},
+		"welcome-pack": {Name: "welcome-pack", Price: 90, Variants: []models.CatalogVariant{{Key: models.DefaultVariant}},
+			Components: []models.BundleComponent{{ItemName: "cup", Variant: models.DefaultVariant, Quantity: 1}}},
+	}
+	var saved models.CatalogItem
+	stubRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if item, ok := items[name]; ok {
+				return item, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+		CreateBundleFunc: func(bundle models.CatalogItem) error {
+			saved = bundle
+			return nil
+		},
+	}
+	catalogService := services.NewCatalogService(stubRepo, nil, nil, logger)
+
+	// Варианты набора - общие размеры футболки и худи
+	bundle, err := catalogService.CreateBundle(models.CreateBundleRequest{
+		Name:  "winter-pack",
+		Price: 350,
+		Components: []models.BundleComponent{
+			{ItemName: "t-shirt"},
+			{ItemName: "hoody"},
+			{ItemName: "cup", Variant: models.DefaultVariant, Quantity: 2},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"size"}, bundle.VariantAxes)
+	assert.Equal(t, []models.CatalogVariant{{Key: "size=M"}, {Key: "size=L"}}, saved.Variants)
+	assert.Equal(t, 1, saved.Components[0].Quantity)
+	assert.Equal(t, 2, saved.Components[2].Quantity)
+
+	// Набор только из фиксированных вариантов получает вариант по умолчанию
+	bundle, err = catalogService.CreateBundle(models.CreateBundleRequest{
+		Name:       "cup-pack",
+		Price:      30,
+		Components: []models.BundleComponent{{ItemName: "cup", Variant: models.DefaultVariant}},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, bundle.VariantAxes)
+	assert.Equal(t, models.DefaultVariant, bundle.Variants[0].Key)
+
+	invalid := []models.CreateBundleRequest{
+		{Name: "", Price: 10, Components: []models.BundleComponent{{ItemName: "cup"}}},
+		{Name: "empty", Price: 10},
+		{Name: "dup", Price: 10, Components: []models.BundleComponent{{ItemName: "cup"}, {ItemName: "cup"}}},
+		{Name: "nested", Price: 10, Components: []models.BundleComponent{{ItemName: "welcome-pack"}}},
+		{Name: "axes", Price: 10, Components: []models.BundleComponent{{ItemName: "t-shirt"}, {ItemName: "socks"}}},
+	}
+	for _, req := range invalid {
+		_, err = catalogService.CreateBundle(req)
+		assert.ErrorIs(t, err, models.ErrInvalidBundle, req.Name)
+	}
+
+	_, err = catalogService.CreateBundle(models.CreateBundleRequest{
+		Name: "unknown", Price: 10, Components: []models.BundleComponent{{ItemName: "hoody", Variant: "size=XS"}},
+	})
+	assert.ErrorIs(t, err, models.ErrVariantNotFound)
+}
+
+func TestCatalogService_SearchItems(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var received models.CatalogQuery
+	stubRepo := &StubCatalogRepository{
+		SearchItemsFunc: func(query models.CatalogQuery) ([]models.CatalogItem, int, error) {
+			received = query
+			return []models.CatalogItem{{Name: "cup", Price: 20}, {Name: "hoody", Price: 300}, {Name: "umbrella", Price: 120}}, 12, nil
+		},
+	}
+	campaignQueries := 0
+	discountRepo := &StubDiscountRepository{
+		ListActiveCampaignsFunc: func(at time.Time) ([]models.Campaign, error) {
+			campaignQueries++
+			return []models.Campaign{{ID: 1, ItemNames: []string{"umbrella"}, DiscountType: models.DiscountFixed, DiscountValue: 30}}, nil
+		},
+	}
+	catalogService := services.NewCatalogService(stubRepo, discountRepo, nil, logger)
+
+	page, err := catalogService.SearchItems(models.CatalogQuery{Query: "  худи ", Sort: models.SortByPrice, PerPage: 500}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "худи", received.Query)
+	assert.Equal(t, 1, received.Page)
+	assert.Equal(t, 100, received.PerPage)
+	assert.Equal(t, 12, page.Total)
+	assert.True(t, *page.Items[0].Affordable)
+	assert.False(t, *page.Items[1].Affordable)
+	// По каталогу umbrella стоит 120, но со скидкой 90 - баланса хватает
+	assert.True(t, *page.Items[2].Affordable)
+	// Кампании загружаются один раз на страницу, а не на каждый товар
+	assert.Equal(t, 1, campaignQueries)
+
+	page, err = catalogService.SearchItems(models.CatalogQuery{Page: 3}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Page)
+	assert.Equal(t, 20, page.PerPage)
+
+	_, err = catalogService.SearchItems(models.CatalogQuery{Sort: "popularity"}, 0)
+	assert.Error(t, err)
+}
+
+func TestCatalogService_SchedulePriceChange(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	stubRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name == "hoody" {
+				return &models.CatalogItem{Name: "hoody", Price: 300}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+	}
+	catalogService := services.NewCatalogService(stubRepo, nil, nil, logger)
+
+	// Изменение без даты применяется сразу
+	change, err := catalogService.SchedulePriceChange("hoody", models.SchedulePriceRequest{Price: 250}, "admin")
+	assert.NoError(t, err)
+	assert.True(t, change.Applied)
+	assert.True(t, stubRepo.PriceChanges[0].Applied)
+
+	// Будущее изменение ждёт планировщика
+	future := time.Now().Add(24 * time.Hour)
+	change, err = catalogService.SchedulePriceChange("hoody", models.SchedulePriceRequest{Price: 200, EffectiveAt: &future}, "admin")
+	assert.NoError(t, err)
+	assert.False(t, change.Applied)
+	assert.NoError(t, catalogService.ApplyDuePriceChanges())
+	assert.False(t, stubRepo.PriceChanges[1].Applied)
+
+	// Применённое изменение отменить нельзя, запланированное - можно
+	assert.ErrorIs(t, catalogService.CancelPriceChange(1), models.ErrPriceChangeNotFound)
+	assert.NoError(t, catalogService.CancelPriceChange(change.ID))
+
+	_, err = catalogService.SchedulePriceChange("hoody", models.SchedulePriceRequest{Price: -1}, "admin")
+	assert.Error(t, err)
+	_, err = catalogService.SchedulePriceChange("unknown", models.SchedulePriceRequest{Price: 10}, "admin")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type StubDiscountRepository struct {
+	CreateCampaignFunc      func(campaign models.Campaign) (int, error)
+	ListCampaignsFunc       func() ([]models.Campaign, error)
+	GetActiveCampaignsFunc  func(itemName string, at time.Time) ([]models.Campaign, error)
+	ListActiveCampaignsFunc func(at time.Time) ([]models.Campaign, error)
+	CreatePromoCodeFunc     func(code models.PromoCode) (int, error)
+	GetPromoCodeFunc        func(code string) (*models.PromoCode, error)
+	ListPromoCodesFunc      func() ([]models.PromoCode, error)
+}
+
+func (s *StubDiscountRepository) CreateCampaign(campaign models.Campaign) (int, error) {
+	return s.CreateCampaignFunc(campaign)
+}
+
+func (s *StubDiscountRepository) ListCampaigns() ([]models.Campaign, error) {
+	return s.ListCampaignsFunc()
+}
+
+func (s *StubDiscountRepository) GetActiveCampaigns(itemName string, at time.Time) ([]models.Campaign, error) {
+	return s.GetActiveCampaignsFunc(itemName, at)
+}
+
+func (s *StubDiscountRepository) ListActiveCampaigns(at time.Time) ([]models.Campaign, error) {
+	return s.ListActiveCampaignsFunc(at)
+}
+
+func (s *StubDiscountRepository) CreatePromoCode(code models.PromoCode) (int, error) {
+	return s.CreatePromoCodeFunc(code)
+}
+
+func (s *StubDiscountRepository) GetPromoCode(code string) (*models.PromoCode, error) {
+	return s.GetPromoCodeFunc(code)
+}
+
+func (s *StubDiscountRepository) ListPromoCodes() ([]models.PromoCode, error) {
+	return s.ListPromoCodesFunc()
+}
+
+func TestDiscountService_Quote(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	expired := time.Now().Add(-time.Hour)
+	stubRepo := &StubDiscountRepository{
+		GetActiveCampaignsFunc: func(itemName string, at time.Time) ([]models.Campaign, error) {
+			// Применяется самая выгодная кампания
+			return []models.Campaign{
+				{ID: 1, DiscountType: models.DiscountPercent, DiscountValue: 10},
+				{ID: 2, DiscountType: models.DiscountFixed, DiscountValue: 50},
+			}, nil
+		},
+		GetPromoCodeFunc: func(code string) (*models.PromoCode, error) {
+			switch code {
+			case "WINNER":
+				return &models.PromoCode{ID: 5, Code: code, DiscountType: models.DiscountPercent, DiscountValue: 50, MaxUses: 1}, nil
+			case "USED":
+				return &models.PromoCode{ID: 6, Code: code, DiscountType: models.DiscountFixed, DiscountValue: 10, MaxUses: 1, Uses: 1}, nil
+			case "OLD":
+				return &models.PromoCode{ID: 7, Code: code, DiscountType: models.DiscountFixed, DiscountValue: 10, MaxUses: 5, ExpiresAt: &expired}, nil
+			case "CUPS":
+				return &models.PromoCode{ID: 8, Code: code, ItemNames: []string{"cup"}, DiscountType: models.DiscountFixed, DiscountValue: 10, MaxUses: 5}, nil
+			}
+			return nil, models.ErrPromoCodeNotFound
+		},
+	}
+	discountService := services.NewDiscountService(stubRepo, nil, logger)
+
+	// Только кампания
+	quote, err := discountService.Quote("hoody", 300, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.PriceQuote{ListPrice: 300, Discount: 50, Price: 250, CampaignID: intPtr(2)}, quote)
+
+	// Промокод применяется к цене после кампании, код нечувствителен к регистру
+	quote, err = discountService.Quote("hoody", 300, "winner")
+	assert.NoError(t, err)
+	assert.Equal(t, 175, quote.Discount)
+	assert.Equal(t, 125, quote.Price)
+	assert.Equal(t, 5, *quote.PromoCodeID)
+
+	_, err = discountService.Quote("hoody", 300, "USED")
+	assert.ErrorIs(t, err, models.ErrPromoCodeExpired)
+
+	_, err = discountService.Quote("hoody", 300, "OLD")
+	assert.ErrorIs(t, err, models.ErrPromoCodeExpired)
+
+	_, err = discountService.Quote("hoody", 300, "CUPS")
+	assert.ErrorIs(t, err, models.ErrPromoCodeNotApplicable)
+
+	_, err = discountService.Quote("hoody", 300, "NOPE")
+	assert.ErrorIs(t, err, models.ErrPromoCodeNotFound)
+
+	// Скидка не делает цену отрицательной
+	quote, err = discountService.Quote("pen", 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, quote.Price)
+}
+
+func TestDiscountService_CreatePromoCode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	stubRepo := &StubDiscountRepository{
+		CreatePromoCodeFunc: func(code models.PromoCode) (int, error) {
+			return 1, nil
+		},
+	}
+	discountService := services.NewDiscountService(stubRepo, nil, logger)
+
+	// Код генерируется, по умолчанию одноразовый
+	promo, err := discountService.CreatePromoCode(models.CreatePromoCodeRequest{
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 100,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, promo.Code, 10)
+	assert.Equal(t, 1, promo.MaxUses)
+
+	_, err = discountService.CreatePromoCode(models.CreatePromoCodeRequest{
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 150,
+	})
+	assert.Error(t, err)
+
+	_, err = discountService.CreatePromoCode(models.CreatePromoCodeRequest{
+		DiscountType:  "free",
+		DiscountValue: 10,
+	})
+	assert.Error(t, err)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"ShopAvito/internal/storage"
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// MemoryBlobStore - хранилище файлов в памяти
+type MemoryBlobStore struct {
+	Blobs map[string][]byte
+}
+
+func (s *MemoryBlobStore) Put(key string, data []byte, contentType string) error {
+	s.Blobs[key] = data
+	return nil
+}
+
+func (s *MemoryBlobStore) Get(key string) ([]byte, string, error) {
+	data, ok := s.Blobs[key]
+	if !ok {
+		return nil, "", storage.ErrBlobNotFound
+	}
+	return data, "image/png", nil
+}
+
+func (s *MemoryBlobStore) Delete(key string) error {
+	delete(s.Blobs, key)
+	return nil
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestImageService_UploadItemImage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	item := &models.CatalogItem{Name: "cup", ImageKey: "items/old.png", ThumbKey: "items/old_thumb.png"}
+	var savedImage, savedThumb string
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name != "cup" {
+				return nil, models.ErrItemNotFound
+			}
+			copied := *item
+			return &copied, nil
+		},
+		SetItemImageFunc: func(itemName, imageKey, thumbKey string) error {
+			savedImage, savedThumb = imageKey, thumbKey
+			return nil
+		},
+	}
+	store := &MemoryBlobStore{Blobs: map[string][]byte{"items/old.png": {1}, "items/old_thumb.png": {2}}}
+	imageService := services.NewImageService(catalogRepo, store, logger)
+
+	uploaded, err := imageService.UploadItemImage("cup", encodePNG(t, 600, 300))
+	assert.NoError(t, err)
+	assert.Equal(t, models.ImagesPath+savedImage, uploaded.ImageURL)
+	assert.Equal(t, models.ImagesPath+savedThumb, uploaded.ThumbURL)
+	assert.Contains(t, store.Blobs, savedImage)
+
+	// Миниатюра уменьшена с сохранением пропорций
+	thumb, err := png.Decode(bytes.NewReader(store.Blobs[savedThumb]))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 256, 128), thumb.Bounds())
+
+	// Прежнее изображение удалено
+	assert.NotContains(t, store.Blobs, "items/old.png")
+	assert.NotContains(t, store.Blobs, "items/old_thumb.png")
+
+	_, err = imageService.UploadItemImage("cup", []byte("not an image"))
+	assert.ErrorIs(t, err, models.ErrInvalidImage)
+
+	_, err = imageService.UploadItemImage("cup", encodePNG(t, 5000, 10))
+	assert.ErrorIs(t, err, models.ErrInvalidImage)
+
+	_, err = imageService.UploadItemImage("unknown", encodePNG(t, 10, 10))
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
+
+func TestThumbnail(t *testing.T) {
+	// Маленькие изображения не увеличиваются
+	small := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	assert.Equal(t, small.Bounds(), services.Thumbnail(small, 256).Bounds())
+
+	tall := image.NewRGBA(image.Rect(0, 0, 300, 1200))
+	assert.Equal(t, image.Rect(0, 0, 64, 256), services.Thumbnail(tall, 256).Bounds())
+}
+
+func TestImageService_SharedPictureKeptOnReplace(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	items := map[string]*models.CatalogItem{"cup": {Name: "cup"}, "mug": {Name: "mug"}}
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			item, ok := items[name]
+			if !ok {
+				return nil, models.ErrItemNotFound
+			}
+			copied := *item
+			return &copied, nil
+		},
+		SetItemImageFunc: func(itemName, imageKey, thumbKey string) error {
+			items[itemName].ImageKey, items[itemName].ThumbKey = imageKey, thumbKey
+			return nil
+		},
+	}
+	store := &MemoryBlobStore{Blobs: map[string][]byte{}}
+	imageService := services.NewImageService(catalogRepo, store, logger)
+
+	// Одна и та же картинка у двух товаров хранится под разными ключами
+	picture := encodePNG(t, 300, 300)
+	_, err := imageService.UploadItemImage("cup", picture)
+	assert.NoError(t, err)
+	_, err = imageService.UploadItemImage("mug", picture)
+	assert.NoError(t, err)
+	assert.NotEqual(t, items["cup"].ImageKey, items["mug"].ImageKey)
+
+	// Замена изображения одного товара не удаляет файлы другого
+	_, err = imageService.UploadItemImage("cup", encodePNG(t, 200, 100))
+	assert.NoError(t, err)
+	assert.Contains(t, store.Blobs, items["mug"].ImageKey)
+	assert.Contains(t, store.Blobs, items["mug"].ThumbKey)
+}
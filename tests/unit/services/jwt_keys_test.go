@@ -0,0 +1,126 @@
+package services
+
+import (
+	"ShopAvito/internal/services"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestAuthService(keys *services.KeySet) *services.AuthService {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return services.NewAuthService(&StubUserRepository{}, nil, nil, keys, services.PasswordPolicy{}, nil, nil, logger)
+}
+
+func TestAuthService_AsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for id, privateKey := range map[string]interface{}{"rsa-1": rsaKey, "ed-1": edKey} {
+		key, err := services.NewPrivateKey(id, privateKey)
+		assert.NoError(t, err)
+		keys, err := services.NewKeySet(key, time.Minute)
+		assert.NoError(t, err)
+		authService := newTestAuthService(keys)
+
+		token, err := authService.GenerateToken("alice")
+		assert.NoError(t, err)
+		claims, err := authService.ValidateToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", claims.Username)
+
+		jwks := authService.JWKS()
+		assert.Len(t, jwks.Keys, 1)
+		assert.Equal(t, id, jwks.Keys[0].Kid)
+		assert.Equal(t, key.Method.Alg(), jwks.Keys[0].Alg)
+	}
+}
+
+func TestAuthService_KeyRotation(t *testing.T) {
+	_, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	oldKey, _ := services.NewPrivateKey("old", oldPrivate)
+	newKey, _ := services.NewPrivateKey("new", newPrivate)
+
+	keys, err := services.NewKeySet(oldKey, time.Hour)
+	assert.NoError(t, err)
+	authService := newTestAuthService(keys)
+	oldToken, err := authService.GenerateToken("alice")
+	assert.NoError(t, err)
+
+	// В течение grace старый ключ проверяет выданные им токены и остаётся в JWKS
+	assert.NoError(t, keys.Rotate(newKey, time.Now()))
+	_, err = authService.ValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Len(t, authService.JWKS().Keys, 2)
+
+	newToken, err := authService.GenerateToken("alice")
+	assert.NoError(t, err)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &services.Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", parsed.Header["kid"])
+
+	// После grace токены старого ключа не принимаются
+	keys, _ = services.NewKeySet(oldKey, time.Hour)
+	authService = newTestAuthService(keys)
+	assert.NoError(t, keys.Rotate(newKey, time.Now().Add(-2*time.Hour)))
+	_, err = authService.ValidateToken(oldToken)
+	assert.Error(t, err)
+	assert.Len(t, authService.JWKS().Keys, 1)
+}
+
+func TestAuthService_RejectsUnexpectedAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, _ := services.NewPrivateKey("rsa-1", rsaKey)
+	keys, _ := services.NewKeySet(key, time.Minute)
+	authService := newTestAuthService(keys)
+
+	claims := &services.Claims{
+		Username:       "mallory",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}
+
+	// Токен HS256, подписанный открытым RSA-ключом как секретом
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "rsa-1"
+	forgedToken, err := forged.SignedString(publicPEM)
+	assert.NoError(t, err)
+	_, err = authService.ValidateToken(forgedToken)
+	assert.Error(t, err)
+
+	// Неподписанный токен
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	assert.NoError(t, err)
+	_, err = authService.ValidateToken(unsigned)
+	assert.Error(t, err)
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ed.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	key, err := services.LoadSigningKey("ed-1", path)
+	assert.NoError(t, err)
+	assert.Equal(t, "EdDSA", key.Method.Alg())
+
+	_, err = services.LoadSigningKey("missing", filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+}
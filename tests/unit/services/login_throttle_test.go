@@ -0,0 +1,207 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	var lerr *models.LoginLockedError
+	if !assert.True(t, errors.As(err, &lerr), "expected lockout, got %v", err) {
+		return 0
+	}
+	return lerr.RetryAfter
+}
+
+// Неудача, учтённая в обход блокировки: так тесты продолжают счёт, не дожидаясь конца задержки
+func recordFailure(t *testing.T, repo repository.LoginAttemptRepositoryInterface, key string) {
+	_, err := repo.ReserveLoginAttempt(key, time.Now(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+}
+
+func TestLoginThrottle_Backoff(t *testing.T) {
+	repo := repository.NewMemoryLoginAttemptRepository()
+	throttle := services.NewLoginThrottle(repo, services.LoginThrottlePolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Minute,
+		MaxLockout:  5 * time.Minute,
+	})
+
+	// До порога попытки не ограничиваются
+	for i := 0; i < 3; i++ {
+		_, err := throttle.Reserve("alice", "10.0.0.1")
+		assert.NoError(t, err)
+	}
+
+	// Дальше задержка удваивается с каждой неудачей и ограничена MaxLockout
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for _, delay := range expected {
+		_, err := throttle.Reserve("alice", "10.0.0.1")
+		wait := retryAfter(t, err)
+		assert.InDelta(t, delay.Seconds(), wait.Seconds(), 1)
+		recordFailure(t, repo, "user:alice")
+	}
+
+	// Имя не зависит от регистра, блокировка действует с любого адреса
+	_, err := throttle.Reserve("ALICE", "10.0.0.2")
+	retryAfter(t, err)
+	_, err = throttle.Reserve("bob", "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestLoginThrottle_IPLimit(t *testing.T) {
+	throttle := services.NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(), services.LoginThrottlePolicy{
+		MaxAttempts:   5,
+		MaxIPAttempts: 3,
+	})
+
+	// Перебор разных имён с одного адреса блокирует адрес
+	for _, username := range []string{"alice", "bob", "carol"} {
+		_, err := throttle.Reserve(username, "10.0.0.1")
+		assert.NoError(t, err)
+	}
+	_, err := throttle.Reserve("dave", "10.0.0.1")
+	retryAfter(t, err)
+	_, err = throttle.Reserve("dave", "10.0.0.2")
+	assert.NoError(t, err)
+
+	// Успешный вход сбрасывает только счётчик имени
+	assert.NoError(t, throttle.RecordSuccess("alice"))
+	_, err = throttle.Reserve("alice", "10.0.0.1")
+	retryAfter(t, err)
+
+	assert.NoError(t, throttle.Unlock("", "10.0.0.1"))
+	_, err = throttle.Reserve("dave", "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestLoginThrottle_ForgetsOldFailures(t *testing.T) {
+	repo := repository.NewMemoryLoginAttemptRepository()
+	throttle := services.NewLoginThrottle(repo, services.LoginThrottlePolicy{MaxAttempts: 2, Window: time.Hour})
+
+	old := time.Now().Add(-2 * time.Hour)
+	for i := 0; i < 5; i++ {
+		_, err := repo.ReserveLoginAttempt("user:alice", old, old.Add(-time.Hour))
+		assert.NoError(t, err)
+	}
+
+	// Старые неудачи забыты, новая попытка начинает счёт заново: блокировка наступает только после двух
+	for i := 0; i < 2; i++ {
+		_, err := throttle.Reserve("alice", "")
+		assert.NoError(t, err)
+	}
+	_, err := throttle.Reserve("alice", "")
+	retryAfter(t, err)
+
+	deleted, err := throttle.PurgeStale()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+}
+
+func TestLoginThrottle_Reserve(t *testing.T) {
+	repo := repository.NewMemoryLoginAttemptRepository()
+	throttle := services.NewLoginThrottle(repo, services.LoginThrottlePolicy{MaxAttempts: 3, MaxIPAttempts: 3})
+
+	// Зарезервированные, но ещё не проверенные попытки уже учитываются
+	for i := 0; i < 3; i++ {
+		_, err := throttle.Reserve("alice", "10.0.0.1")
+		assert.NoError(t, err)
+	}
+	_, err := throttle.Reserve("alice", "10.0.0.1")
+	retryAfter(t, err)
+
+	// Заблокированная попытка счётчик не увеличивает
+	previous, err := repo.ReserveLoginAttempt("user:alice", time.Now(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, previous.Failures)
+
+	// Снятый резерв не остаётся ни в счётчике имени, ни в счётчике адреса
+	attempt, err := throttle.Reserve("bob", "10.0.0.2")
+	assert.NoError(t, err)
+	attempt.Release()
+	for _, key := range []string{"user:bob", "ip:10.0.0.2"} {
+		previous, err = repo.ReserveLoginAttempt(key, time.Now(), time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Nil(t, previous)
+	}
+}
+
+func TestAuthService_ParallelLoginAttempts(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: "testuser", Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	throttle := services.NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(), services.LoginThrottlePolicy{MaxAttempts: 3})
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{}, throttle, nil, logger)
+
+	// Одновременный перебор: пароль проверяется не больше MaxAttempts раз
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := authService.Login("testuser", "wrong", models.ClientInfo{IP: "10.0.0.1"}); errors.Is(err, models.ErrInvalidPassword) {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, checked)
+}
+
+func TestAuthService_LoginLockout(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			if username == "testuser" {
+				return &models.User{Username: "testuser", Password: string(hashedPassword)}, nil
+			}
+			return nil, errors.New("user not found")
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	throttle := services.NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(), services.LoginThrottlePolicy{MaxAttempts: 2})
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{}, throttle, nil, logger)
+
+	_, err = authService.Login("testuser", "wrong", models.ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, models.ErrInvalidPassword)
+	_, err = authService.Login("testuser", "wrong", models.ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, models.ErrInvalidPassword)
+
+	// Во время блокировки не принимается и верный пароль
+	_, err = authService.Login("testuser", "password", models.ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, models.ErrLoginLocked)
+
+	// Несуществующие имена тоже блокируются
+	_, _ = authService.Login("ghost", "password", models.ClientInfo{IP: "10.0.0.2"})
+	_, _ = authService.Login("ghost", "password", models.ClientInfo{IP: "10.0.0.2"})
+	_, err = authService.Login("ghost", "password", models.ClientInfo{IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, models.ErrLoginLocked)
+
+	assert.NoError(t, authService.UnlockLogin("testuser", ""))
+	tokens, err := authService.Login("testuser", "password", models.ClientInfo{IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.Token)
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testOIDCClientID    = "shop"
+	testOIDCSecret      = "secret"
+	testOIDCRedirectURL = "http://shop.local/api/auth/oidc/callback"
+)
+
+// MemoryOIDCRepository хранит незавершённые входы, пользователей и привязки учётных записей в памяти
+type MemoryOIDCRepository struct {
+	States     map[string]models.OIDCLoginState
+	Users      map[string]models.User
+	Identities map[string]string
+}
+
+func NewMemoryOIDCRepository(usernames ...string) *MemoryOIDCRepository {
+	repo := &MemoryOIDCRepository{States: map[string]models.OIDCLoginState{}, Users: map[string]models.User{}, Identities: map[string]string{}}
+	for _, username := range usernames {
+		repo.Users[strings.ToLower(username)] = models.User{Username: username}
+	}
+	return repo
+}
+
+func (m *MemoryOIDCRepository) SaveOIDCState(state models.OIDCLoginState) error {
+	m.States[state.State] = state
+	return nil
+}
+
+func (m *MemoryOIDCRepository) ConsumeOIDCState(state string, now time.Time) (*models.OIDCLoginState, error) {
+	loginState, ok := m.States[state]
+	delete(m.States, state)
+	if !ok || !loginState.ExpiresAt.After(now) {
+		return nil, models.ErrInvalidOIDCState
+	}
+	return &loginState, nil
+}
+
+func (m *MemoryOIDCRepository) DeleteExpiredOIDCStates(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MemoryOIDCRepository) GetUsernameByIdentity(issuer, subject string) (string, error) {
+	return m.Identities[issuer+"|"+subject], nil
+}
+
+func (m *MemoryOIDCRepository) CreateUserWithIdentity(user models.User, issuer, subject string) error {
+	if _, ok := m.Users[strings.ToLower(user.Username)]; ok {
+		return models.ErrUserAlreadyExists
+	}
+	if _, ok := m.Identities[issuer+"|"+subject]; ok {
+		return models.ErrUserAlreadyExists
+	}
+	m.Users[strings.ToLower(user.Username)] = user
+	m.Identities[issuer+"|"+subject] = user.Username
+	return nil
+}
+
+// mockIdP - минимальный провайдер OpenID Connect: discovery, JWKS и обмен кода на ID-токен, подписанный RS256
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+	// Подмена утверждений ID-токена для проверки отказов
+	tamper func(claims jwt.MapClaims)
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	idp := &mockIdP{key: key, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize?prompt=login",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.JWKS{Keys: []models.JWK{{
+			Kty: "RSA", Kid: "idp-1", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if clientID, secret, _ := r.BasicAuth(); clientID != testOIDCClientID || secret != testOIDCSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	r.ParseForm()
+	idp.mu.Lock()
+	authorization, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != testOIDCRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != authorization.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"sub":   authorization.Get("subject"),
+		"aud":   []string{testOIDCClientID},
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": authorization.Get("nonce"),
+	}
+	if username := authorization.Get("preferred_username"); username != "" {
+		claims["preferred_username"] = username
+	}
+	if email := authorization.Get("email"); email != "" {
+		claims["email"] = email
+	}
+	if idp.tamper != nil {
+		idp.tamper(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "idp-1"
+	signed, _ := token.SignedString(idp.key)
+	json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "access_token": "opaque", "token_type": "Bearer"})
+}
+
+// Вход пользователя на странице провайдера: по адресу из LoginURL выдаётся код, как при возврате в callback
+func (idp *mockIdP) authorize(t *testing.T, loginURL string, profile url.Values) (string, string) {
+	parsed, err := url.Parse(loginURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, idp.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "login", query.Get("prompt"))
+	assert.Equal(t, testOIDCClientID, query.Get("client_id"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Contains(t, query.Get("scope"), "openid")
+
+	for key, values := range profile {
+		query[key] = values
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(idp.codes)+1)
+	idp.codes[code] = query
+	return query.Get("state"), code
+}
+
+func newTestOIDCService(idp *mockIdP, repo *MemoryOIDCRepository) (*services.OIDCService, *services.AuthService) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	authService := services.NewAuthService(&StubUserRepository{}, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{}, nil, nil, logger)
+	return services.NewOIDCService(services.OIDCConfig{
+		Issuer:       idp.server.URL,
+		ClientID:     testOIDCClientID,
+		ClientSecret: testOIDCSecret,
+		RedirectURL:  testOIDCRedirectURL,
+	}, repo, authService, nil, logger), authService
+}
+
+// Полный вход: LoginURL, страница провайдера и callback
+func oidcLogin(t *testing.T, idp *mockIdP, oidcService *services.OIDCService, profile url.Values) (*models.AuthResponse, error) {
+	loginURL, state, err := oidcService.LoginURL()
+	assert.NoError(t, err)
+	returnedState, code := idp.authorize(t, loginURL, profile)
+	assert.Equal(t, state, returnedState)
+	return oidcService.Callback(state, code, models.ClientInfo{})
+}
+
+func TestOIDCService_Login(t *testing.T) {
+	idp := newMockIdP(t)
+	repo := NewMemoryOIDCRepository()
+	oidcService, authService := newTestOIDCService(idp, repo)
+
+	// Первый вход создаёт пользователя без пароля и с обычным стартовым балансом
+	resp, err := oidcLogin(t, idp, oidcService, url.Values{"subject": {"sub-1"}, "preferred_username": {"alice"}})
+	assert.NoError(t, err)
+	claims, err := authService.ValidateToken(resp.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, 1000, repo.Users["alice"].Balance)
+	assert.Empty(t, repo.Users["alice"].Password)
+
+	// Повторный вход попадает в того же пользователя, даже если у провайдера сменилось имя
+	resp, err = oidcLogin(t, idp, oidcService, url.Values{"subject": {"sub-1"}, "preferred_username": {"alice.smith"}})
+	assert.NoError(t, err)
+	claims, _ = authService.ValidateToken(resp.Token)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Len(t, repo.Users, 1)
+}
+
+func TestOIDCService_NewUsername(t *testing.T) {
+	idp := newMockIdP(t)
+	repo := NewMemoryOIDCRepository("Alice")
+	oidcService, authService := newTestOIDCService(idp, repo)
+
+	// Существующий пользователь с тем же именем не привязывается, новому достаётся имя с суффиксом
+	resp, err := oidcLogin(t, idp, oidcService, url.Values{"subject": {"sub-1"}, "preferred_username": {"alice"}})
+	assert.NoError(t, err)
+	claims, _ := authService.ValidateToken(resp.Token)
+	assert.Regexp(t, `^alice-[0-9a-f]{4}$`, claims.Username)
+
+	// Негодное имя пропускается, берётся начало email
+	resp, err = oidcLogin(t, idp, oidcService, url.Values{"subject": {"sub-2"}, "preferred_username": {"Иван"}, "email": {"ivan.petrov+shop@corp.example"}})
+	assert.NoError(t, err)
+	claims, _ = authService.ValidateToken(resp.Token)
+	assert.Equal(t, "ivan.petrovshop", claims.Username)
+
+	resp, err = oidcLogin(t, idp, oidcService, url.Values{"subject": {"sub-3"}})
+	assert.NoError(t, err)
+	claims, _ = authService.ValidateToken(resp.Token)
+	assert.Equal(t, "user", claims.Username)
+}
+
+func TestOIDCService_RejectsInvalidLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	oidcService, _ := newTestOIDCService(idp, NewMemoryOIDCRepository())
+	profile := url.Values{"subject": {"sub-1"}, "preferred_username": {"alice"}}
+
+	tests := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+	}{
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "another-client" }},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example" }},
+		{"wrong nonce", func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"foreign party", func(claims jwt.MapClaims) { claims["azp"] = "another-client" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.tamper = tt.tamper
+			defer func() { idp.tamper = nil }()
+			_, err := oidcLogin(t, idp, oidcService, profile)
+			assert.ErrorIs(t, err, models.ErrOIDCLoginFailed)
+		})
+	}
+
+	// Код, который провайдер не выдавал
+	_, state, err := oidcService.LoginURL()
+	assert.NoError(t, err)
+	_, err = oidcService.Callback(state, "forged-code", models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrOIDCLoginFailed)
+
+	// state одноразовый
+	loginURL, state, _ := oidcService.LoginURL()
+	_, code := idp.authorize(t, loginURL, profile)
+	_, err = oidcService.Callback(state, code, models.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = oidcService.Callback(state, code, models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrInvalidOIDCState)
+	_, err = oidcService.Callback("unknown", code, models.ClientInfo{})
+	assert.ErrorIs(t, err, models.ErrInvalidOIDCState)
+}
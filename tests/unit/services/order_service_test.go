@@ -0,0 +1,112 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+type StubOrderRepository struct {
+	GetOrderFunc          func(id int) (*models.Order, error)
+	GetUserOrdersFunc     func(username string) ([]models.Order, error)
+	ListOrdersFunc        func(status string) ([]models.Order, error)
+	GetStatusHistoryFunc  func(orderID int) ([]models.OrderStatusChange, error)
+	UpdateOrderStatusFunc func(orderID int, from, to, changedBy string) error
+}
+
+func (s *StubOrderRepository) GetOrder(id int) (*models.Order, error) {
+	return s.GetOrderFunc(id)
+}
+
+func (s *StubOrderRepository) GetUserOrders(username string) ([]models.Order, error) {
+	return s.GetUserOrdersFunc(username)
+}
+
+func (s *StubOrderRepository) ListOrders(status string) ([]models.Order, error) {
+	return s.ListOrdersFunc(status)
+}
+
+func (s *StubOrderRepository) GetStatusHistory(orderID int) ([]models.OrderStatusChange, error) {
+	return s.GetStatusHistoryFunc(orderID)
+}
+
+func (s *StubOrderRepository) UpdateOrderStatus(orderID int, from, to, changedBy string) error {
+	return s.UpdateOrderStatusFunc(orderID, from, to, changedBy)
+}
+
+func newOrderStubs(status string) (*StubOrderRepository, *StubPurchaseRepository, *[]string) {
+	var calls []string
+	orderRepo := &StubOrderRepository{
+		GetOrderFunc: func(id int) (*models.Order, error) {
+			if id != 1 {
+				return nil, models.ErrOrderNotFound
+			}
+			return &models.Order{ID: 1, Username: "buyer", ItemName: "hoody", Price: 300, Status: status}, nil
+		},
+		UpdateOrderStatusFunc: func(orderID int, from, to, changedBy string) error {
+			calls = append(calls, "update:"+from+"->"+to)
+			return nil
+		},
+	}
+	purchaseRepo := &StubPurchaseRepository{
+		CancelOrderFunc: func(orderID int, expectedStatus, changedBy string) error {
+			calls = append(calls, "cancel:"+expectedStatus+":"+changedBy)
+			return nil
+		},
+	}
+	return orderRepo, purchaseRepo, &calls
+}
+
+func TestOrderService_UpdateOrderStatus(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Обычный переход по цепочке
+	orderRepo, purchaseRepo, calls := newOrderStubs(models.OrderStatusPlaced)
+	orderService := services.NewOrderService(orderRepo, purchaseRepo, logger)
+	assert.NoError(t, orderService.UpdateOrderStatus(1, models.OrderStatusPrepared, "admin"))
+	assert.Equal(t, []string{"update:placed->prepared"}, *calls)
+
+	// Перескочить через статус нельзя
+	err := orderService.UpdateOrderStatus(1, models.OrderStatusDelivered, "admin")
+	assert.ErrorIs(t, err, models.ErrInvalidOrderTransition)
+
+	// Отмена до выдачи идёт через возврат покупки
+	orderRepo, purchaseRepo, calls = newOrderStubs(models.OrderStatusReadyForPickup)
+	orderService = services.NewOrderService(orderRepo, purchaseRepo, logger)
+	assert.NoError(t, orderService.UpdateOrderStatus(1, models.OrderStatusCancelled, "admin"))
+	assert.Equal(t, []string{"cancel:ready_for_pickup:admin"}, *calls)
+
+	// Выданный заказ отменить нельзя
+	orderRepo, purchaseRepo, _ = newOrderStubs(models.OrderStatusDelivered)
+	orderService = services.NewOrderService(orderRepo, purchaseRepo, logger)
+	err = orderService.UpdateOrderStatus(1, models.OrderStatusCancelled, "admin")
+	assert.ErrorIs(t, err, models.ErrInvalidOrderTransition)
+
+	// Несуществующий заказ
+	err = orderService.UpdateOrderStatus(2, models.OrderStatusPrepared, "admin")
+	assert.ErrorIs(t, err, models.ErrOrderNotFound)
+}
+
+func TestOrderService_CancelUserOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	orderRepo, purchaseRepo, calls := newOrderStubs(models.OrderStatusPlaced)
+	orderService := services.NewOrderService(orderRepo, purchaseRepo, logger)
+
+	// Чужой заказ не виден
+	assert.ErrorIs(t, orderService.CancelUserOrder("stranger", 1), models.ErrOrderNotFound)
+
+	// Свой заказ до сборки отменяется с возвратом
+	assert.NoError(t, orderService.CancelUserOrder("buyer", 1))
+	assert.Equal(t, []string{"cancel:placed:buyer"}, *calls)
+
+	// После начала сборки отменить может только администратор
+	orderRepo, purchaseRepo, _ = newOrderStubs(models.OrderStatusPrepared)
+	orderService = services.NewOrderService(orderRepo, purchaseRepo, logger)
+	assert.ErrorIs(t, orderService.CancelUserOrder("buyer", 1), models.ErrInvalidOrderTransition)
+}
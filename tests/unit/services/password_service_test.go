@@ -0,0 +1,165 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"testing"
+	"time"
+)
+
+type memoryResetToken struct {
+	username  string
+	expiresAt time.Time
+	used      bool
+}
+
+// MemoryPasswordResetRepository хранит токены сброса в памяти и меняет пароли через StubUserRepository
+type MemoryPasswordResetRepository struct {
+	Tokens    map[string]*memoryResetToken
+	Passwords map[string]string
+}
+
+func NewMemoryPasswordResetRepository(passwords map[string]string) *MemoryPasswordResetRepository {
+	return &MemoryPasswordResetRepository{Tokens: map[string]*memoryResetToken{}, Passwords: passwords}
+}
+
+func (m *MemoryPasswordResetRepository) CreatePasswordResetToken(username, tokenHash, createdBy string, expiresAt time.Time) error {
+	if _, ok := m.Passwords[username]; !ok {
+		return models.ErrUserNotFound
+	}
+	for hash, token := range m.Tokens {
+		if token.username == username && !token.used {
+			delete(m.Tokens, hash)
+		}
+	}
+	m.Tokens[tokenHash] = &memoryResetToken{username: username, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MemoryPasswordResetRepository) GetPasswordResetTokenOwner(tokenHash string, now time.Time) (string, error) {
+	token, ok := m.Tokens[tokenHash]
+	if !ok || token.used || !token.expiresAt.After(now) {
+		return "", models.ErrInvalidResetToken
+	}
+	return token.username, nil
+}
+
+func (m *MemoryPasswordResetRepository) ResetPassword(tokenHash, passwordHash string, now time.Time) (string, error) {
+	username, err := m.GetPasswordResetTokenOwner(tokenHash, now)
+	if err != nil {
+		return "", err
+	}
+	m.Tokens[tokenHash].used = true
+	m.Passwords[username] = passwordHash
+	return username, nil
+}
+
+func (m *MemoryPasswordResetRepository) DeleteExpiredPasswordResetTokens(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newTestPasswordService(password string) (*services.PasswordService, map[string]string, *MemoryTokenRevocationRepository) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	passwords := map[string]string{"alice": string(hashed)}
+	userRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			if hash, ok := passwords[username]; ok {
+				return &models.User{Username: username, Password: hash}, nil
+			}
+			return nil, errors.New("user not found")
+		},
+		UpdatePasswordFunc: func(username, passwordHash string) error {
+			passwords[username] = passwordHash
+			return nil
+		},
+	}
+	revocationRepo := NewMemoryTokenRevocationRepository("alice")
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	passwordService := services.NewPasswordService(userRepo, NewMemoryPasswordResetRepository(passwords),
+		services.NewRevocationStore(revocationRepo), services.PasswordPolicy{Cost: bcrypt.MinCost}, logger)
+	return passwordService, passwords, revocationRepo
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	passwordService, passwords, revocationRepo := newTestPasswordService("old-password")
+
+	err := passwordService.ChangePassword("alice", "wrong-password", "new-password")
+	assert.ErrorIs(t, err, models.ErrInvalidPassword)
+
+	err = passwordService.ChangePassword("alice", "old-password", "short")
+	var verr *models.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, "new_password", verr.Fields[0].Field)
+
+	changedAt := time.Now()
+	assert.NoError(t, passwordService.ChangePassword("alice", "old-password", "new-password"))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwords["alice"]), []byte("new-password")))
+
+	// Выданные до смены пароля токены отозваны, граница не округляется до секунды
+	cutoff, revoked := revocationRepo.Cutoffs["alice"]
+	assert.True(t, revoked)
+	assert.False(t, cutoff.Before(changedAt))
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+	passwordService, passwords, revocationRepo := newTestPasswordService("old-password")
+
+	_, err := passwordService.IssueResetToken("bob", "admin")
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+
+	first, err := passwordService.IssueResetToken("alice", "admin")
+	assert.NoError(t, err)
+	second, err := passwordService.IssueResetToken("alice", "admin")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(services.PasswordResetTokenTTL), second.ExpiresAt, time.Minute)
+
+	// Действует только последний выданный токен
+	assert.ErrorIs(t, passwordService.ResetPassword(first.ResetToken, "new-password"), models.ErrInvalidResetToken)
+
+	assert.NoError(t, passwordService.ResetPassword(second.ResetToken, "new-password"))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwords["alice"]), []byte("new-password")))
+	_, revoked := revocationRepo.Cutoffs["alice"]
+	assert.True(t, revoked)
+
+	// Токен одноразовый
+	assert.ErrorIs(t, passwordService.ResetPassword(second.ResetToken, "another-password"), models.ErrInvalidResetToken)
+}
+
+func TestAuthService_RehashesPasswordOnCostChange(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	var updated string
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: "testuser", Password: string(hashed)}, nil
+		},
+		UpdatePasswordFunc: func(username, passwordHash string) error {
+			updated = passwordHash
+			return nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Стоимость не изменилась - хэш не трогается
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{Cost: bcrypt.MinCost}, nil, nil, logger)
+	_, err = authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	assert.Empty(t, updated)
+
+	authService = services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{Cost: bcrypt.MinCost + 1}, nil, nil, logger)
+	_, err = authService.Login("testuser", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(updated))
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated), []byte("password")))
+}
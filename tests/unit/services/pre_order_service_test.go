@@ -0,0 +1,150 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/services"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type StubPreOrderRepository struct {
+	CreatePreOrderFunc           func(username, itemName, variant string, pricing repository.PricingFunc) (*models.PreOrder, error)
+	GetUserPreOrdersFunc         func(username string) ([]models.PreOrder, error)
+	CancelPreOrderFunc           func(username string, preOrderID int) error
+	ReleaseItemPreOrdersFunc     func(itemName string) (int, error)
+	GetCapturablePreOrderIDsFunc func() ([]int, error)
+	CapturePreOrderFunc          func(preOrderID int) error
+}
+
+func (s *StubPreOrderRepository) CreatePreOrder(username, itemName, variant string, pricing repository.PricingFunc) (*models.PreOrder, error) {
+	return s.CreatePreOrderFunc(username, itemName, variant, pricing)
+}
+
+func (s *StubPreOrderRepository) GetUserPreOrders(username string) ([]models.PreOrder, error) {
+	return s.GetUserPreOrdersFunc(username)
+}
+
+func (s *StubPreOrderRepository) CancelPreOrder(username string, preOrderID int) error {
+	return s.CancelPreOrderFunc(username, preOrderID)
+}
+
+func (s *StubPreOrderRepository) ReleaseItemPreOrders(itemName string) (int, error) {
+	return s.ReleaseItemPreOrdersFunc(itemName)
+}
+
+func (s *StubPreOrderRepository) GetCapturablePreOrderIDs() ([]int, error) {
+	return s.GetCapturablePreOrderIDsFunc()
+}
+
+func (s *StubPreOrderRepository) CapturePreOrder(preOrderID int) error {
+	return s.CapturePreOrderFunc(preOrderID)
+}
+
+func TestPreOrderService_CreatePreOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			switch name {
+			case "hoody":
+				return &models.CatalogItem{Name: name, Price: 300}, nil
+			case "starter-pack":
+				return &models.CatalogItem{Name: name, Components: []models.BundleComponent{{ItemName: "cup", Quantity: 1}}}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+	}
+	discountRepo := &StubDiscountRepository{
+		GetActiveCampaignsFunc: func(itemName string, at time.Time) ([]models.Campaign, error) {
+			return []models.Campaign{{ID: 1, DiscountType: models.DiscountPercent, DiscountValue: 10}}, nil
+		},
+	}
+	preOrderRepo := &StubPreOrderRepository{
+		CreatePreOrderFunc: func(username, itemName, variant string, pricing repository.PricingFunc) (*models.PreOrder, error) {
+			// Цена рассчитывается репозиторием по цене каталога в транзакции
+			quote, err := pricing(300)
+			if err != nil {
+				return nil, err
+			}
+			return &models.PreOrder{ID: 5, Username: username, ItemName: itemName, Variant: variant,
+				ListPrice: quote.ListPrice, Discount: quote.Discount, Price: quote.Price, Status: models.PreOrderStatusReserved}, nil
+		},
+	}
+	preOrderService := services.NewPreOrderService(preOrderRepo, catalogRepo, discountRepo, logger)
+
+	preOrder, err := preOrderService.CreatePreOrder("alice", "hoody", "size=M")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, preOrder.ID)
+	assert.Equal(t, 30, preOrder.Discount)
+	assert.Equal(t, 270, preOrder.Price)
+
+	// Наборы не предзаказываются
+	_, err = preOrderService.CreatePreOrder("alice", "starter-pack", "default")
+	assert.Error(t, err)
+
+	_, err = preOrderService.CreatePreOrder("alice", "unknown", "default")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
+
+func TestPreOrderService_CaptureDuePreOrders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var captured []int
+	failure := errors.New("db is down")
+	preOrderRepo := &StubPreOrderRepository{
+		GetCapturablePreOrderIDsFunc: func() ([]int, error) {
+			return []int{1, 2, 3, 4}, nil
+		},
+		CapturePreOrderFunc: func(preOrderID int) error {
+			switch preOrderID {
+			case 2:
+				// Товар разобрали предыдущие предзаказы
+				return models.ErrOutOfStock
+			case 3:
+				return failure
+			}
+			captured = append(captured, preOrderID)
+			return nil
+		},
+	}
+	preOrderService := services.NewPreOrderService(preOrderRepo, nil, nil, logger)
+
+	err := preOrderService.CaptureDuePreOrders()
+	assert.ErrorIs(t, err, failure)
+	assert.NotErrorIs(t, err, models.ErrOutOfStock)
+	assert.Equal(t, []int{1, 4}, captured)
+}
+
+func TestPreOrderService_ReleaseItemPreOrders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name == "hoody" {
+				return &models.CatalogItem{Name: name}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+	}
+	preOrderRepo := &StubPreOrderRepository{
+		ReleaseItemPreOrdersFunc: func(itemName string) (int, error) {
+			return 3, nil
+		},
+	}
+	preOrderService := services.NewPreOrderService(preOrderRepo, catalogRepo, nil, logger)
+
+	released, err := preOrderService.ReleaseItemPreOrders("hoody")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, released)
+
+	_, err = preOrderService.ReleaseItemPreOrders("unknown")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MemoryProfileRepository хранит профили в памяти
+type MemoryProfileRepository struct {
+	Profiles map[string]*models.UserProfile
+}
+
+func (m *MemoryProfileRepository) GetProfile(username string) (*models.UserProfile, error) {
+	profile, ok := m.Profiles[username]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+func (m *MemoryProfileRepository) UpdateProfile(username string, req models.UpdateProfileRequest) (*models.UserProfile, error) {
+	profile, ok := m.Profiles[username]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	if req.DisplayName != nil {
+		profile.DisplayName = *req.DisplayName
+	}
+	if req.Department != nil {
+		profile.Department = *req.Department
+	}
+	if req.Title != nil {
+		profile.Title = *req.Title
+	}
+	return m.GetProfile(username)
+}
+
+func (m *MemoryProfileRepository) SetAvatar(username, avatarKey string) (string, error) {
+	profile, ok := m.Profiles[username]
+	if !ok {
+		return "", models.ErrUserNotFound
+	}
+	oldKey := profile.AvatarKey
+	profile.AvatarKey = avatarKey
+	return oldKey, nil
+}
+
+func newProfileService() (*services.ProfileService, *MemoryProfileRepository, *MemoryBlobStore) {
+	repo := &MemoryProfileRepository{Profiles: map[string]*models.UserProfile{
+		"anna": {Username: "anna", JoinedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+	store := &MemoryBlobStore{Blobs: map[string][]byte{}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return services.NewProfileService(repo, store, logger), repo, store
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestProfileService_Update(t *testing.T) {
+	service, _, _ := newProfileService()
+
+	profile, err := service.Update("anna", models.UpdateProfileRequest{
+		DisplayName: stringPtr("  Anna Petrova "),
+		Department:  stringPtr("Backend"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Anna Petrova", profile.DisplayName)
+	assert.Equal(t, "Backend", profile.Department)
+	assert.Equal(t, "Anna Petrova (Backend)", profile.Label())
+
+	// Непереданные поля не меняются, пустая строка очищает поле
+	profile, err = service.Update("anna", models.UpdateProfileRequest{Title: stringPtr("Engineer"), Department: stringPtr("")})
+	assert.NoError(t, err)
+	assert.Equal(t, "Anna Petrova", profile.DisplayName)
+	assert.Equal(t, "Engineer", profile.Title)
+	assert.Equal(t, "Anna Petrova", profile.Label())
+
+	_, err = service.Update("anna", models.UpdateProfileRequest{
+		DisplayName: stringPtr(strings.Repeat("a", 65)),
+		Title:       stringPtr("line\nbreak"),
+	})
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Fields, 2)
+
+	_, err = service.Update("ghost", models.UpdateProfileRequest{DisplayName: stringPtr("Ghost")})
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+}
+
+func TestProfileService_Avatar(t *testing.T) {
+	service, repo, store := newProfileService()
+
+	profile, err := service.UploadAvatar("anna", encodePNG(t, 1024, 512))
+	assert.NoError(t, err)
+	key := repo.Profiles["anna"].AvatarKey
+	assert.True(t, strings.HasPrefix(key, "avatars/"))
+	assert.Equal(t, models.ImagesPath+key, profile.AvatarURL)
+	assert.Len(t, store.Blobs, 1)
+
+	// Новый аватар заменяет прежний файл
+	_, err = service.UploadAvatar("anna", encodePNG(t, 100, 100))
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, repo.Profiles["anna"].AvatarKey)
+	assert.Len(t, store.Blobs, 1)
+
+	_, err = service.UploadAvatar("anna", []byte("not an image"))
+	assert.ErrorIs(t, err, models.ErrInvalidImage)
+
+	assert.NoError(t, service.DeleteAvatar("anna"))
+	assert.Empty(t, store.Blobs)
+	profile, err = service.Get("anna")
+	assert.NoError(t, err)
+	assert.Empty(t, profile.AvatarURL)
+}
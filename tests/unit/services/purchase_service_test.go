@@ -2,21 +2,28 @@ package services
 
 import (
 	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
 	"ShopAvito/internal/services"
 	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"testing"
 	"time"
 )
 
 type StubPurchaseRepository struct {
-	BuyItemFunc          func(username, itemName string, price int) error
+	BuyItemFunc          func(username, itemName, variant string, pricing repository.PricingFunc) (int, models.PriceQuote, error)
 	GetUserPurchasesFunc func(username string) ([]models.Purchase, error)
+	CancelOrderFunc      func(orderID int, expectedStatus, changedBy string) error
 }
 
-func (s *StubPurchaseRepository) BuyItem(username, itemName string, price int) error {
-	return s.BuyItemFunc(username, itemName, price)
+func (s *StubPurchaseRepository) BuyItem(username, itemName, variant string, pricing repository.PricingFunc) (int, models.PriceQuote, error) {
+	return s.BuyItemFunc(username, itemName, variant, pricing)
+}
+
+func (s *StubPurchaseRepository) CancelOrder(orderID int, expectedStatus, changedBy string) error {
+	return s.CancelOrderFunc(orderID, expectedStatus, changedBy)
 }
 
 func (s *StubPurchaseRepository) GetUserPurchases(username string) ([]models.Purchase, error) {
@@ -49,7 +56,7 @@ func TestPurchaseService_GetUserPurchases(t *testing.T) {
 	logger := logrus.New()
 
 	// Создаем PurchaseService с заглушкой и логгером
-	purchaseService := services.NewPurchaseService(stubPurchaseRepo, nil, nil, logger)
+	purchaseService := services.NewPurchaseService(stubPurchaseRepo, nil, nil, nil, logger)
 
 	// Тест на успешное получение списка покупок
 	purchases, err := purchaseService.GetUserPurchases("testuser")
@@ -65,3 +72,52 @@ func TestPurchaseService_GetUserPurchases(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "user not found", err.Error())
 }
+
+func TestPurchaseService_BuyItem(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Цены каталога на момент транзакции
+	prices := map[string]int{"hoody": 300, "powerbank": 300}
+	var charged models.PriceQuote
+	stubPurchaseRepo := &StubPurchaseRepository{
+		BuyItemFunc: func(username, itemName, variant string, pricing repository.PricingFunc) (int, models.PriceQuote, error) {
+			quote, err := pricing(prices[itemName])
+			if err != nil {
+				return 0, quote, err
+			}
+			if quote.Price > 250 {
+				return 0, quote, models.ErrInsufficientBalance
+			}
+			charged = quote
+			return 1, quote, nil
+		},
+	}
+	stubDiscountRepo := &StubDiscountRepository{
+		GetActiveCampaignsFunc: func(itemName string, at time.Time) ([]models.Campaign, error) {
+			if itemName == "hoody" {
+				return []models.Campaign{{ID: 1, ItemNames: []string{"hoody"}, DiscountType: models.DiscountPercent, DiscountValue: 20}}, nil
+			}
+			return nil, nil
+		},
+	}
+	purchaseService := services.NewPurchaseService(stubPurchaseRepo, nil, nil, stubDiscountRepo, logger)
+
+	// Со скидкой 20% худи за 300 стоит 240 и укладывается в баланс 250
+	orderID, quote, err := purchaseService.BuyItem("buyer", "hoody", "size=M", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, orderID)
+	assert.Equal(t, 240, quote.Price)
+	assert.Equal(t, 60, charged.Discount)
+	assert.Equal(t, 1, *charged.CampaignID)
+
+	// Без скидки баланса не хватает
+	_, _, err = purchaseService.BuyItem("buyer", "powerbank", models.DefaultVariant, "")
+	assert.ErrorIs(t, err, models.ErrInsufficientBalance)
+
+	// Скидка считается от цены, действующей в момент покупки
+	prices["hoody"] = 200
+	_, quote, err = purchaseService.BuyItem("buyer", "hoody", "size=M", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 160, quote.Price)
+}
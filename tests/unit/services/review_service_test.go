@@ -0,0 +1,97 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+type StubReviewRepository struct {
+	UpsertReviewFunc   func(review models.Review) (*models.Review, error)
+	GetItemReviewsFunc func(itemName string) ([]models.Review, error)
+	SetHiddenFunc      func(reviewID int, hidden bool) error
+}
+
+func (s *StubReviewRepository) UpsertReview(review models.Review) (*models.Review, error) {
+	return s.UpsertReviewFunc(review)
+}
+
+func (s *StubReviewRepository) GetItemReviews(itemName string) ([]models.Review, error) {
+	return s.GetItemReviewsFunc(itemName)
+}
+
+func (s *StubReviewRepository) SetHidden(reviewID int, hidden bool) error {
+	return s.SetHiddenFunc(reviewID, hidden)
+}
+
+func TestReviewService_SaveReview(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var saved models.Review
+	reviewRepo := &StubReviewRepository{
+		UpsertReviewFunc: func(review models.Review) (*models.Review, error) {
+			saved = review
+			review.ID = 1
+			return &review, nil
+		},
+	}
+	inventoryRepo := &StubInventoryRepository{
+		GetInventoryFunc: func(username string) ([]models.InventoryItem, error) {
+			return []models.InventoryItem{{Type: "t-shirt", Variant: "size=M", Quantity: 1}}, nil
+		},
+	}
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name == "t-shirt" || name == "cup" {
+				return &models.CatalogItem{Name: name}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+	}
+	reviewService := services.NewReviewService(reviewRepo, inventoryRepo, catalogRepo, logger)
+
+	review, err := reviewService.SaveReview("alice", "t-shirt", models.ReviewRequest{Rating: 5, Text: "  Отличная футболка "})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, review.ID)
+	assert.Equal(t, "Отличная футболка", saved.Text)
+	assert.Equal(t, "alice", saved.Username)
+
+	// Товара нет в инвентаре
+	_, err = reviewService.SaveReview("alice", "cup", models.ReviewRequest{Rating: 4})
+	assert.ErrorIs(t, err, models.ErrItemNotOwned)
+
+	_, err = reviewService.SaveReview("alice", "unknown", models.ReviewRequest{Rating: 4})
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+
+	_, err = reviewService.SaveReview("alice", "t-shirt", models.ReviewRequest{Rating: 6})
+	assert.Error(t, err)
+
+	_, err = reviewService.SaveReview("alice", "t-shirt", models.ReviewRequest{Rating: 3, Text: strings.Repeat("a", 2001)})
+	assert.Error(t, err)
+}
+
+func TestReviewService_ModerateReview(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	hidden := map[int]bool{}
+	reviewRepo := &StubReviewRepository{
+		SetHiddenFunc: func(reviewID int, value bool) error {
+			if reviewID != 7 {
+				return models.ErrReviewNotFound
+			}
+			hidden[reviewID] = value
+			return nil
+		},
+	}
+	reviewService := services.NewReviewService(reviewRepo, nil, nil, logger)
+
+	assert.NoError(t, reviewService.ModerateReview(7, true))
+	assert.True(t, hidden[7])
+	assert.ErrorIs(t, reviewService.ModerateReview(8, true), models.ErrReviewNotFound)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// MemoryTokenRevocationRepository хранит отзывы в памяти и считает обращения к хранилищу
+type MemoryTokenRevocationRepository struct {
+	Revoked      map[string]time.Time
+	Cutoffs      map[string]time.Time
+	Users        map[string]int
+	TokenChecks  int
+	CutoffChecks int
+}
+
+func NewMemoryTokenRevocationRepository(users ...string) *MemoryTokenRevocationRepository {
+	repo := &MemoryTokenRevocationRepository{
+		Revoked: map[string]time.Time{},
+		Cutoffs: map[string]time.Time{},
+		Users:   map[string]int{},
+	}
+	for _, username := range users {
+		repo.Users[username] = len(repo.Users) + 1
+	}
+	return repo
+}
+
+func (m *MemoryTokenRevocationRepository) RevokeToken(jti, username string, expiresAt time.Time) error {
+	if m.Users[username] == 0 {
+		return models.ErrUserNotFound
+	}
+	m.Revoked[jti] = expiresAt
+	return nil
+}
+
+func (m *MemoryTokenRevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	m.TokenChecks++
+	_, ok := m.Revoked[jti]
+	return ok, nil
+}
+
+func (m *MemoryTokenRevocationRepository) RevokeUserTokens(username string, before time.Time) error {
+	if m.Users[username] == 0 {
+		return models.ErrUserNotFound
+	}
+	if before.After(m.Cutoffs[username]) {
+		m.Cutoffs[username] = before
+	}
+	return nil
+}
+
+func (m *MemoryTokenRevocationRepository) GetTokensRevokedBefore(username string) (int, *time.Time, error) {
+	m.CutoffChecks++
+	userID := m.Users[username]
+	if userID == 0 {
+		return 0, nil, models.ErrUserNotFound
+	}
+	before, ok := m.Cutoffs[username]
+	if !ok {
+		return userID, nil, nil
+	}
+	return userID, &before, nil
+}
+
+func (m *MemoryTokenRevocationRepository) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	var deleted int64
+	for jti, expiresAt := range m.Revoked {
+		if !expiresAt.After(now) {
+			delete(m.Revoked, jti)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestRevocationStore_IsRevoked(t *testing.T) {
+	repo := NewMemoryTokenRevocationRepository("alice")
+	store := services.NewRevocationStore(repo)
+	issuedAt := time.Now().Add(-time.Hour)
+
+	revoked, err := store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Повторная проверка берётся из кэша
+	revoked, err = store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+	assert.Equal(t, 1, repo.TokenChecks)
+	assert.Equal(t, 1, repo.CutoffChecks)
+
+	// Отзыв через хранилище сразу виден, несмотря на кэш
+	assert.NoError(t, store.RevokeToken("jti-1", "alice", time.Now().Add(time.Minute)))
+	revoked, err = store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	assert.ErrorIs(t, store.RevokeToken("jti-2", "bob", time.Now()), models.ErrUserNotFound)
+}
+
+func TestRevocationStore_RevokeUserTokens(t *testing.T) {
+	repo := NewMemoryTokenRevocationRepository("alice")
+	store := services.NewRevocationStore(repo)
+	cutoff := time.Now()
+
+	revoked, err := store.IsRevoked("old", "alice", 1, cutoff.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, store.RevokeUserTokens("alice", cutoff))
+
+	// Токены, выданные до границы, отозваны, выданные после - нет
+	revoked, err = store.IsRevoked("old", "alice", 1, cutoff.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.IsRevoked("new", "alice", 1, cutoff.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.ErrorIs(t, store.RevokeUserTokens("bob", cutoff), models.ErrUserNotFound)
+}
+
+func TestRevocationStore_DeletedUser(t *testing.T) {
+	repo := NewMemoryTokenRevocationRepository("alice")
+	store := services.NewRevocationStore(repo)
+	issuedAt := time.Now().Add(-time.Minute)
+
+	revoked, err := store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// После удаления аккаунта пользователь не находится, и его токены отклоняются
+	delete(repo.Users, "alice")
+	store.ForgetUser("alice")
+	revoked, err = store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRevocationStore_UsernameTakenAfterDeletion(t *testing.T) {
+	repo := NewMemoryTokenRevocationRepository("alice")
+	store := services.NewRevocationStore(repo)
+	issuedAt := time.Now().Add(-time.Minute)
+
+	// Аккаунт удалён, и то же имя зарегистрировал другой пользователь
+	delete(repo.Users, "alice")
+	store.ForgetUser("alice")
+	repo.Users["alice"] = 2
+
+	// Токен прежнего владельца имени не подходит новому аккаунту
+	revoked, err := store.IsRevoked("jti-1", "alice", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked("jti-2", "alice", 2, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
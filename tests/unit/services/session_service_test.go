@@ -0,0 +1,113 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Сеансы в памяти поверх MemoryRefreshTokenRepository: сеанс активен, пока в семействе есть действующий токен
+func (m *MemoryRefreshTokenRepository) ListSessions(username string, now time.Time) ([]models.Session, error) {
+	sessions := []models.Session{}
+	for _, session := range m.Sessions {
+		if active, _ := m.IsSessionActive(session.FamilyID, now); active && session.Username == username {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID > sessions[j].ID })
+	return sessions, nil
+}
+
+func (m *MemoryRefreshTokenRepository) RevokeSession(username string, id int) (string, error) {
+	for _, session := range m.Sessions {
+		if session.ID != id || session.Username != username {
+			continue
+		}
+		now := time.Now()
+		for _, token := range m.Tokens {
+			if token.FamilyID == session.FamilyID && token.RevokedAt == nil {
+				token.RevokedAt = &now
+			}
+		}
+		return session.FamilyID, nil
+	}
+	return "", models.ErrSessionNotFound
+}
+
+func (m *MemoryRefreshTokenRepository) IsSessionActive(familyID string, now time.Time) (bool, error) {
+	for _, token := range m.Tokens {
+		if token.FamilyID == familyID && token.UsedAt == nil && token.RevokedAt == nil && token.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestSessionService_ListAndRevoke(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: username, Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo := NewMemoryRefreshTokenRepository()
+	authService := services.NewAuthService(stubUserRepo, repo, nil, newTestKeySet("secret"), services.PasswordPolicy{}, nil, nil, logger)
+	sessionService := services.NewSessionService(repo, logger)
+
+	laptop, err := authService.Login("alice", "password", models.ClientInfo{IP: "10.0.0.1", UserAgent: "Firefox"})
+	assert.NoError(t, err)
+	phone, err := authService.Login("alice", "password", models.ClientInfo{IP: "10.0.0.2", UserAgent: "Safari"})
+	assert.NoError(t, err)
+	_, err = authService.Login("bob", "password", models.ClientInfo{})
+	assert.NoError(t, err)
+
+	// JWT привязан к сеансу входа, и после обновления тоже
+	laptopClaims, err := authService.ValidateToken(laptop.Token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, laptopClaims.Session)
+	refreshed, err := authService.Refresh(laptop.RefreshToken)
+	assert.NoError(t, err)
+	refreshedClaims, _ := authService.ValidateToken(refreshed.Token)
+	assert.Equal(t, laptopClaims.Session, refreshedClaims.Session)
+
+	sessions, err := sessionService.List("alice", laptopClaims.Session)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "Safari", sessions[0].UserAgent)
+	assert.Equal(t, "10.0.0.2", sessions[0].IP)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+
+	// Чужой сеанс завершить нельзя
+	assert.ErrorIs(t, sessionService.Revoke("bob", sessions[0].ID), models.ErrSessionNotFound)
+
+	phoneClaims, _ := authService.ValidateToken(phone.Token)
+	ended, err := sessionService.IsEnded(phoneClaims.Session)
+	assert.NoError(t, err)
+	assert.False(t, ended)
+
+	assert.NoError(t, sessionService.Revoke("alice", sessions[0].ID))
+	ended, err = sessionService.IsEnded(phoneClaims.Session)
+	assert.NoError(t, err)
+	assert.True(t, ended)
+	_, err = authService.Refresh(phone.RefreshToken)
+	assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+
+	sessions, _ = sessionService.List("alice", "")
+	assert.Len(t, sessions, 1)
+	ended, _ = sessionService.IsEnded(laptopClaims.Session)
+	assert.False(t, ended)
+
+	// JWT без сеанса (выданный до появления сеансов) не проверяется
+	ended, _ = sessionService.IsEnded("")
+	assert.False(t, ended)
+}
@@ -68,7 +68,7 @@ func TestTransactionService_TransferCoins(t *testing.T) {
 	logger.SetOutput(io.Discard) // Отключаем вывод логов в консоль
 
 	// Создаем TransactionService с заглушками и логгером
-	transactionService := services.NewTransactionService(stubTransactionRepo, stubUserRepo, logger)
+	transactionService := services.NewTransactionService(stubTransactionRepo, stubUserRepo, nil, logger)
 
 	// Тест на успешный перевод монет
 	err := transactionService.TransferCoins("sender", "receiver", 100)
@@ -102,6 +102,7 @@ func TestTransactionService_GetReceivedTransactions(t *testing.T) {
 				return []models.Transaction{
 					{
 						FromUser: "sender",
+						FromName: "Anna Petrova (Backend)",
 						Amount:   100,
 						Time:     time.Now(),
 					},
@@ -116,13 +117,14 @@ func TestTransactionService_GetReceivedTransactions(t *testing.T) {
 	logger.SetOutput(io.Discard) // Отключаем вывод логов в консоль
 
 	// Создаем TransactionService с заглушкой и логгером
-	transactionService := services.NewTransactionService(stubTransactionRepo, nil, logger)
+	transactionService := services.NewTransactionService(stubTransactionRepo, nil, nil, logger)
 
 	// Тест на успешное получение списка полученных транзакций
 	transactions, err := transactionService.GetReceivedTransactions("receiver")
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(transactions))
 	assert.Equal(t, "sender", transactions[0].FromUser)
+	assert.Equal(t, "Anna Petrova (Backend)", transactions[0].FromName)
 	assert.Equal(t, 100, transactions[0].Amount)
 
 	// Тест на несуществующего пользователя
@@ -139,6 +141,7 @@ func TestTransactionService_GetSentTransactions(t *testing.T) {
 				return []models.Transaction{
 					{
 						ToUser: "receiver",
+						ToName: "receiver",
 						Amount: 100,
 						Time:   time.Now(),
 					},
@@ -153,13 +156,14 @@ func TestTransactionService_GetSentTransactions(t *testing.T) {
 	logger.SetOutput(io.Discard) // Отключаем вывод логов в консоль
 
 	// Создаем TransactionService с заглушкой и логгером
-	transactionService := services.NewTransactionService(stubTransactionRepo, nil, logger)
+	transactionService := services.NewTransactionService(stubTransactionRepo, nil, nil, logger)
 
 	// Тест на успешное получение списка отправленных транзакций
 	transactions, err := transactionService.GetSentTransactions("sender")
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(transactions))
 	assert.Equal(t, "receiver", transactions[0].ToUser)
+	assert.Equal(t, "receiver", transactions[0].ToName)
 	assert.Equal(t, 100, transactions[0].Amount)
 
 	// Тест на несуществующего пользователя
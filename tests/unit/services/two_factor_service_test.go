@@ -0,0 +1,224 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/repository"
+	"ShopAvito/internal/services"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// MemoryTwoFactorRepository хранит секреты TOTP и коды восстановления в памяти
+type MemoryTwoFactorRepository struct {
+	Secrets       map[string]*models.TOTPSecret
+	RecoveryCodes map[string]map[string]bool
+}
+
+func NewMemoryTwoFactorRepository() *MemoryTwoFactorRepository {
+	return &MemoryTwoFactorRepository{Secrets: map[string]*models.TOTPSecret{}, RecoveryCodes: map[string]map[string]bool{}}
+}
+
+func (m *MemoryTwoFactorRepository) SaveTOTPSecret(username, secret string) error {
+	if current, ok := m.Secrets[username]; ok && current.Enabled {
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+	m.Secrets[username] = &models.TOTPSecret{Secret: secret}
+	return nil
+}
+
+func (m *MemoryTwoFactorRepository) GetTOTPSecret(username string) (*models.TOTPSecret, error) {
+	secret, ok := m.Secrets[username]
+	if !ok {
+		return nil, nil
+	}
+	copied := *secret
+	return &copied, nil
+}
+
+func (m *MemoryTwoFactorRepository) EnableTOTP(username string, step int64, recoveryCodeHashes []string) error {
+	secret, ok := m.Secrets[username]
+	if !ok || secret.Enabled {
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+	secret.Enabled, secret.LastUsedStep = true, step
+	m.RecoveryCodes[username] = map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		m.RecoveryCodes[username][hash] = true
+	}
+	return nil
+}
+
+func (m *MemoryTwoFactorRepository) UseTOTPStep(username string, step int64) (bool, error) {
+	secret, ok := m.Secrets[username]
+	if !ok || !secret.Enabled || secret.LastUsedStep >= step {
+		return false, nil
+	}
+	secret.LastUsedStep = step
+	return true, nil
+}
+
+func (m *MemoryTwoFactorRepository) UseRecoveryCode(username, codeHash string) (bool, error) {
+	if !m.RecoveryCodes[username][codeHash] {
+		return false, nil
+	}
+	delete(m.RecoveryCodes[username], codeHash)
+	return true, nil
+}
+
+func (m *MemoryTwoFactorRepository) DisableTOTP(username string) error {
+	delete(m.Secrets, username)
+	delete(m.RecoveryCodes, username)
+	return nil
+}
+
+// Код TOTP по RFC 6238, посчитанный независимо от сервиса
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	assert.NoError(t, err)
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+func newTestTwoFactorService(policy services.TwoFactorPolicy) (*services.TwoFactorService, *MemoryTwoFactorRepository) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo := NewMemoryTwoFactorRepository()
+	return services.NewTwoFactorService(repo, policy, logger), repo
+}
+
+// Подключение двухфакторной аутентификации: возвращает секрет и коды восстановления
+func enrollTwoFactor(t *testing.T, twoFactorService *services.TwoFactorService, username string) (string, []string) {
+	enrollment, err := twoFactorService.Enroll(username)
+	assert.NoError(t, err)
+	codes, err := twoFactorService.Confirm(username, totpAt(t, enrollment.Secret, time.Now().Add(-30*time.Second)))
+	assert.NoError(t, err)
+	return enrollment.Secret, codes
+}
+
+func TestTwoFactorService_Enroll(t *testing.T) {
+	twoFactorService, _ := newTestTwoFactorService(services.TwoFactorPolicy{Issuer: "Avito Shop"})
+
+	enrollment, err := twoFactorService.Enroll("alice")
+	assert.NoError(t, err)
+	uri, err := url.Parse(enrollment.URI)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Avito Shop:alice", uri.Path)
+	assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+
+	// До подтверждения двухфакторная аутентификация не включена
+	enabled, err := twoFactorService.IsEnabled("alice")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	_, err = twoFactorService.Confirm("alice", "000000")
+	if totpAt(t, enrollment.Secret, time.Now()) != "000000" {
+		assert.ErrorIs(t, err, models.ErrInvalidTwoFactorCode)
+	}
+
+	codes, err := twoFactorService.Confirm("alice", totpAt(t, enrollment.Secret, time.Now()))
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	enabled, _ = twoFactorService.IsEnabled("alice")
+	assert.True(t, enabled)
+
+	_, err = twoFactorService.Enroll("alice")
+	assert.ErrorIs(t, err, models.ErrTwoFactorAlreadyEnabled)
+}
+
+func TestTwoFactorService_Verify(t *testing.T) {
+	twoFactorService, _ := newTestTwoFactorService(services.TwoFactorPolicy{})
+	secret, codes := enrollTwoFactor(t, twoFactorService, "alice")
+
+	code := totpAt(t, secret, time.Now())
+	assert.NoError(t, twoFactorService.Verify("alice", code))
+	// Повторно тот же код не принимается
+	assert.ErrorIs(t, twoFactorService.Verify("alice", code), models.ErrInvalidTwoFactorCode)
+
+	// Код восстановления одноразовый и вводится без учёта регистра
+	assert.NoError(t, twoFactorService.Verify("alice", " "+codes[0]+" "))
+	assert.ErrorIs(t, twoFactorService.Verify("alice", codes[0]), models.ErrInvalidTwoFactorCode)
+
+	assert.ErrorIs(t, twoFactorService.Verify("bob", code), models.ErrTwoFactorNotEnabled)
+
+	assert.NoError(t, twoFactorService.Disable("alice", codes[1]))
+	enabled, _ := twoFactorService.IsEnabled("alice")
+	assert.False(t, enabled)
+}
+
+func TestTwoFactorService_Policy(t *testing.T) {
+	twoFactorService, _ := newTestTwoFactorService(services.TwoFactorPolicy{RequireForAdmins: true, TransferThreshold: 500})
+
+	assert.ErrorIs(t, twoFactorService.CheckAdmin("admin"), models.ErrTwoFactorRequired)
+	assert.NoError(t, twoFactorService.AuthorizeTransfer("alice", 500, ""))
+	assert.ErrorIs(t, twoFactorService.AuthorizeTransfer("alice", 501, ""), models.ErrTwoFactorRequired)
+	assert.ErrorIs(t, twoFactorService.AuthorizeTransfer("alice", 501, "123456"), models.ErrTwoFactorNotEnabled)
+
+	secret, _ := enrollTwoFactor(t, twoFactorService, "admin")
+	assert.NoError(t, twoFactorService.CheckAdmin("admin"))
+	assert.NoError(t, twoFactorService.AuthorizeTransfer("admin", 1000, totpAt(t, secret, time.Now())))
+}
+
+func TestAuthService_TwoFactorLogin(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	stubUserRepo := &StubUserRepository{
+		GetUserByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{Username: "alice", Password: string(hashedPassword)}, nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	twoFactorService, _ := newTestTwoFactorService(services.TwoFactorPolicy{})
+	throttle := services.NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(), services.LoginThrottlePolicy{MaxAttempts: 3})
+	authService := services.NewAuthService(stubUserRepo, NewMemoryRefreshTokenRepository(), nil, newTestKeySet("secret"),
+		services.PasswordPolicy{}, throttle, twoFactorService, logger)
+	secret, _ := enrollTwoFactor(t, twoFactorService, "alice")
+
+	// После пароля выдаётся только токен второго шага, как JWT доступа он не принимается
+	challenge, err := authService.Login("alice", "password", models.ClientInfo{IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.True(t, challenge.TwoFactorRequired)
+	assert.Empty(t, challenge.Token)
+	_, err = authService.ValidateToken(challenge.ChallengeToken)
+	assert.Error(t, err)
+
+	// И наоборот, JWT доступа не подходит как токен второго шага
+	access, _ := authService.GenerateToken("alice")
+	_, err = authService.VerifyTwoFactor(access, totpAt(t, secret, time.Now()), models.ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, models.ErrInvalidChallenge)
+
+	_, err = authService.VerifyTwoFactor(challenge.ChallengeToken, "bad-code", models.ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, models.ErrInvalidTwoFactorCode)
+
+	tokens, err := authService.VerifyTwoFactor(challenge.ChallengeToken, totpAt(t, secret, time.Now()), models.ClientInfo{IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	claims, err := authService.ValidateToken(tokens.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// Перебор кодов блокируется так же, как перебор паролей
+	for i := 0; i < 3; i++ {
+		_, err = authService.VerifyTwoFactor(challenge.ChallengeToken, "bad-code", models.ClientInfo{IP: "10.0.0.1"})
+	}
+	var lerr *models.LoginLockedError
+	_, err = authService.VerifyTwoFactor(challenge.ChallengeToken, "bad-code", models.ClientInfo{IP: "10.0.0.1"})
+	assert.True(t, errors.As(err, &lerr))
+}
@@ -16,6 +16,9 @@ type StubUserRepositoryForUser struct {
 	GetUserByUsernameFunc func(username string) (*models.User, error)
 	UpdateUserBalanceFunc func(username string, newBalance int) error
 	UserExistsFunc        func(username string) (bool, error)
+	GetUserRoleFunc       func(username string) (string, error)
+	SetUserRoleFunc       func(username, role string) error
+	UpdatePasswordFunc    func(username, passwordHash string) error
 }
 
 func (s *StubUserRepositoryForUser) CreateUser(user models.User) error {
@@ -38,6 +41,25 @@ func (s *StubUserRepositoryForUser) UserExists(username string) (bool, error) {
 	return s.UserExistsFunc(username)
 }
 
+// Без GetUserRoleFunc у всех пользователей роль user
+func (s *StubUserRepositoryForUser) GetUserRole(username string) (string, error) {
+	if s.GetUserRoleFunc == nil {
+		return services.RoleUser, nil
+	}
+	return s.GetUserRoleFunc(username)
+}
+
+func (s *StubUserRepositoryForUser) SetUserRole(username, role string) error {
+	return s.SetUserRoleFunc(username, role)
+}
+
+func (s *StubUserRepositoryForUser) UpdatePassword(username, passwordHash string) error {
+	if s.UpdatePasswordFunc == nil {
+		return nil
+	}
+	return s.UpdatePasswordFunc(username, passwordHash)
+}
+
 func TestUserService_UserExists(t *testing.T) {
 	// Создаем заглушку для UserRepository
 	stubUserRepo := &StubUserRepositoryForUser{
@@ -103,3 +125,50 @@ func TestUserService_GetBalance(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "user not found", err.Error())
 }
+
+func TestUserService_SetRole(t *testing.T) {
+	roles := map[string]string{"admin": services.RoleAdmin, "alice": services.RoleUser}
+	stubUserRepo := &StubUserRepositoryForUser{
+		GetUserRoleFunc: func(username string) (string, error) {
+			return roles[username], nil
+		},
+		SetUserRoleFunc: func(username, role string) error {
+			if _, ok := roles[username]; !ok {
+				return models.ErrUserNotFound
+			}
+			roles[username] = role
+			return nil
+		},
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	userService := services.NewUserService(stubUserRepo, logger)
+
+	assert.NoError(t, userService.SetRole("admin", "alice", services.RoleModerator))
+	role, err := userService.GetRole("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, services.RoleModerator, role)
+
+	var verr *models.ValidationError
+	assert.True(t, errors.As(userService.SetRole("admin", "alice", "root"), &verr))
+	assert.Equal(t, "unknown_role", verr.Fields[0].Code)
+
+	// Своя роль не меняется, чтобы не остаться без администраторов
+	assert.ErrorIs(t, userService.SetRole("admin", "admin", services.RoleUser), models.ErrCannotChangeOwnRole)
+	assert.ErrorIs(t, userService.SetRole("admin", "ghost", services.RoleUser), models.ErrUserNotFound)
+}
+
+func TestRoleScopes(t *testing.T) {
+	user := services.RoleScopes(services.RoleUser)
+	assert.Contains(t, user, services.ScopeTransferSend)
+	assert.NotContains(t, user, services.ScopeAdminReviews)
+
+	moderator := services.RoleScopes(services.RoleModerator)
+	assert.Contains(t, moderator, services.ScopeAdminReviews)
+	assert.NotContains(t, moderator, services.ScopeAdminUsers)
+
+	assert.Contains(t, services.RoleScopes(services.RoleAdmin), services.ScopeAdminUsers)
+	assert.Empty(t, services.RoleScopes("root"))
+	assert.True(t, services.IsStaffRole(services.RoleModerator))
+	assert.False(t, services.IsStaffRole(services.RoleUser))
+}
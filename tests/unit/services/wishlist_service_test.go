@@ -0,0 +1,180 @@
+package services
+
+import (
+	"ShopAvito/internal/models"
+	"ShopAvito/internal/services"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type StubWishlistRepository struct {
+	Items []models.WishlistItem
+}
+
+func (s *StubWishlistRepository) GetWishlist(username string) ([]models.WishlistItem, error) {
+	var items []models.WishlistItem
+	for _, item := range s.Items {
+		if item.Username == username {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *StubWishlistRepository) GetWishlistsByItems(itemNames []string) ([]models.WishlistItem, error) {
+	var items []models.WishlistItem
+	for _, item := range s.Items {
+		for _, name := range itemNames {
+			if item.ItemName == name {
+				items = append(items, item)
+			}
+		}
+		if len(itemNames) == 0 {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *StubWishlistRepository) AddItem(username, itemName string) error {
+	s.Items = append(s.Items, models.WishlistItem{Username: username, ItemName: itemName})
+	return nil
+}
+
+func (s *StubWishlistRepository) RemoveItem(username, itemName string) error {
+	return nil
+}
+
+type StubNotificationRepository struct {
+	Created []models.Notification
+}
+
+func (s *StubNotificationRepository) CreateNotifications(notifications []models.Notification) error {
+	s.Created = append(s.Created, notifications...)
+	return nil
+}
+
+func (s *StubNotificationRepository) GetNotifications(username string, unreadOnly bool) ([]models.Notification, error) {
+	return s.Created, nil
+}
+
+func (s *StubNotificationRepository) MarkAllRead(username string) error {
+	return nil
+}
+
+// StubBalanceService - баланс пользователей по имени
+type StubBalanceService struct {
+	Balances map[string]int
+}
+
+func (s *StubBalanceService) UserExists(username string) (bool, error) {
+	_, ok := s.Balances[username]
+	return ok, nil
+}
+
+func (s *StubBalanceService) GetBalance(username string) (int, error) {
+	return s.Balances[username], nil
+}
+
+func newWishlistService() (*services.WishlistService, *StubNotificationRepository, *StubBalanceService) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	wishlistRepo := &StubWishlistRepository{Items: []models.WishlistItem{
+		{Username: "alice", ItemName: "hoody", ListPrice: 300},
+		{Username: "alice", ItemName: "cup", ListPrice: 20},
+		{Username: "bob", ItemName: "hoody", ListPrice: 300},
+	}}
+	notificationRepo := &StubNotificationRepository{}
+	discountRepo := &StubDiscountRepository{
+		GetActiveCampaignsFunc: func(itemName string, at time.Time) ([]models.Campaign, error) {
+			if itemName == "hoody" {
+				return []models.Campaign{{ID: 1, DiscountType: models.DiscountFixed, DiscountValue: 50}}, nil
+			}
+			return nil, nil
+		},
+	}
+	catalogRepo := &StubCatalogRepository{
+		GetItemFunc: func(name string) (*models.CatalogItem, error) {
+			if name == "pen" {
+				return &models.CatalogItem{Name: "pen", Price: 10}, nil
+			}
+			return nil, models.ErrItemNotFound
+		},
+	}
+	balances := &StubBalanceService{Balances: map[string]int{"alice": 100, "bob": 1000}}
+
+	wishlistService := services.NewWishlistService(wishlistRepo, notificationRepo, catalogRepo, discountRepo, balances, logger)
+	return wishlistService, notificationRepo, balances
+}
+
+func TestWishlistService_GetWishlist(t *testing.T) {
+	wishlistService, _, _ := newWishlistService()
+
+	items, balance, err := wishlistService.GetWishlist("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, balance)
+	assert.Len(t, items, 2)
+
+	// Недостающая сумма считается от цены со скидкой
+	assert.Equal(t, 250, items[0].Price)
+	assert.Equal(t, 150, items[0].CoinsNeeded)
+	assert.Equal(t, 0, items[1].CoinsNeeded)
+
+	assert.NoError(t, wishlistService.AddItem("alice", "pen"))
+	assert.ErrorIs(t, wishlistService.AddItem("alice", "unknown"), models.ErrItemNotFound)
+}
+
+func TestWishlistService_CoinsReceived(t *testing.T) {
+	wishlistService, notificationRepo, balances := newWishlistService()
+
+	// 100 -> 260: худи со скидкой за 250 стало доступно, кружка была доступна и раньше
+	balances.Balances["alice"] = 260
+	wishlistService.CoinsReceived("alice", 160)
+
+	assert.Len(t, notificationRepo.Created, 1)
+	assert.Equal(t, models.NotificationAffordable, notificationRepo.Created[0].Type)
+	assert.Equal(t, "hoody", notificationRepo.Created[0].ItemName)
+
+	// Повторный перевод не порождает уведомление, товар был доступен и до него
+	wishlistService.CoinsReceived("alice", 10)
+	assert.Len(t, notificationRepo.Created, 1)
+}
+
+func TestWishlistService_ItemDiscounted(t *testing.T) {
+	wishlistService, notificationRepo, _ := newWishlistService()
+
+	// Скидка 10% (30 монет) меньше действующей скидки в 50 монет, цена не падает
+	wishlistService.ItemDiscounted(models.Campaign{
+		ID:            2,
+		ItemNames:     []string{"hoody"},
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 10,
+		StartsAt:      time.Now(),
+		EndsAt:        time.Now().Add(time.Hour),
+	})
+	assert.Empty(t, notificationRepo.Created)
+
+	// Скидка 20% (60 монет) снижает цену со скидкой 250 до 240
+	wishlistService.ItemDiscounted(models.Campaign{
+		ID:            3,
+		ItemNames:     []string{"hoody"},
+		DiscountType:  models.DiscountPercent,
+		DiscountValue: 20,
+		StartsAt:      time.Now(),
+		EndsAt:        time.Now().Add(time.Hour),
+	})
+	assert.Len(t, notificationRepo.Created, 2)
+	for _, n := range notificationRepo.Created {
+		assert.Equal(t, models.NotificationPriceDrop, n.Type)
+		assert.Contains(t, n.Message, "240 coins instead of 250")
+	}
+
+	wishlistService.ItemRestocked("cup", models.DefaultVariant)
+	assert.Len(t, notificationRepo.Created, 3)
+	assert.Equal(t, "alice", notificationRepo.Created[2].Username)
+	assert.Equal(t, "cup is back in stock", notificationRepo.Created[2].Message)
+}
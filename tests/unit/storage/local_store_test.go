@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"ShopAvito/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Put("items/abc.png", []byte("png data"), "image/png"))
+
+	data, contentType, err := store.Get("items/abc.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "png data", string(data))
+	assert.Equal(t, "image/png", contentType)
+
+	assert.NoError(t, store.Delete("items/abc.png"))
+	_, _, err = store.Get("items/abc.png")
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+
+	// Удаление отсутствующего файла не считается ошибкой
+	assert.NoError(t, store.Delete("items/abc.png"))
+
+	// Ключ не может указывать за пределы каталога
+	assert.Error(t, store.Put("../escape.png", []byte("x"), "image/png"))
+	_, _, err = store.Get("../../etc/passwd")
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+}
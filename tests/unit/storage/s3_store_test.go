@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"ShopAvito/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 - минимальная замена S3: хранит объекты в памяти и проверяет заголовки подписи
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := storage.NewS3Store(server.URL, "us-east-1", "shop", "access", "secret", server.Client())
+
+	assert.NoError(t, store.Put("items/abc.jpg", []byte("jpeg data"), "image/jpeg"))
+	assert.Contains(t, fake.objects, "/shop/items/abc.jpg")
+
+	data, contentType, err := store.Get("items/abc.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg data", string(data))
+	assert.Equal(t, "image/jpeg", contentType)
+
+	assert.NoError(t, store.Delete("items/abc.jpg"))
+	_, _, err = store.Get("items/abc.jpg")
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+
+	// Неверные учётные данные
+	denied := storage.NewS3Store(server.URL, "eu-west-1", "shop", "other", "secret", server.Client())
+	assert.Error(t, denied.Put("items/abc.jpg", []byte("x"), "image/jpeg"))
+}